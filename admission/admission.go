@@ -0,0 +1,146 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DEFAULT_SAMPLE_INTERVAL is how often the controller measures load
+const DEFAULT_SAMPLE_INTERVAL = time.Second
+
+// Config sets the thresholds above which new work is shed. Zero values
+// disable a threshold.
+type Config struct {
+	// MaxPendingTasks is the number of tasks that are not completed, failed
+	// or aborted
+	MaxPendingTasks int
+	// MaxDiskUsage is the used fraction of the file system holding DiskPath,
+	// between 0 and 1
+	MaxDiskUsage float64
+	DiskPath     string
+	// Methods are the full method names that are shed
+	Methods []string
+	// SampleInterval defaults to DEFAULT_SAMPLE_INTERVAL
+	SampleInterval time.Duration
+}
+
+// Controller sheds calls that add work while the server is overloaded. Load
+// is sampled in the background, so admission itself is cheap.
+type Controller struct {
+	config         Config
+	pending        func() int
+	reason         string
+	controllerLock *sync.RWMutex
+}
+
+// NewController creates a Controller. pending counts the pending tasks.
+func NewController(config Config, pending func() int) *Controller {
+	if config.SampleInterval <= 0 {
+		config.SampleInterval = DEFAULT_SAMPLE_INTERVAL
+	}
+	c := &Controller{config: config, pending: pending, controllerLock: &sync.RWMutex{}}
+	c.Sample()
+	return c
+}
+
+// SetThresholds replaces MaxPendingTasks and MaxDiskUsage. They apply from
+// the next sample.
+func (c *Controller) SetThresholds(maxPendingTasks int, maxDiskUsage float64) {
+	c.controllerLock.Lock()
+	defer c.controllerLock.Unlock()
+	c.config.MaxPendingTasks = maxPendingTasks
+	c.config.MaxDiskUsage = maxDiskUsage
+}
+
+// Sample measures the load and decides whether to shed
+func (c *Controller) Sample() {
+	c.controllerLock.RLock()
+	config := c.config
+	c.controllerLock.RUnlock()
+
+	reason := ""
+	if config.MaxPendingTasks > 0 {
+		if pending := c.pending(); pending >= config.MaxPendingTasks {
+			reason = fmt.Sprintf("%d pending tasks", pending)
+		}
+	}
+	if reason == "" && config.MaxDiskUsage > 0 {
+		used, err := diskUsage(config.DiskPath)
+		if err != nil {
+			slog.Error("failed to measure disk usage", "path", config.DiskPath, "error", err)
+		} else if used >= config.MaxDiskUsage {
+			reason = fmt.Sprintf("disk %.0f%% full", used*100)
+		}
+	}
+
+	c.controllerLock.Lock()
+	defer c.controllerLock.Unlock()
+	if reason != c.reason {
+		if reason != "" {
+			slog.Warn("shedding load", "reason", reason)
+		} else {
+			slog.Info("stopped shedding load")
+		}
+	}
+	c.reason = reason
+}
+
+// PeriodicallySample samples the load every SampleInterval
+func (c *Controller) PeriodicallySample() {
+	ticker := time.NewTicker(c.config.SampleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.Sample()
+	}
+}
+
+// Admit returns a ResourceExhausted error while load is being shed
+func (c *Controller) Admit() error {
+	c.controllerLock.RLock()
+	defer c.controllerLock.RUnlock()
+	if c.reason != "" {
+		return status.Errorf(codes.ResourceExhausted, "server is overloaded: %s", c.reason)
+	}
+	return nil
+}
+
+func (c *Controller) sheds(fullMethod string) bool {
+	for _, method := range c.config.Methods {
+		if method == fullMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryServerInterceptor sheds the configured methods
+func (c *Controller) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if c.sheds(info.FullMethod) {
+			if err := c.Admit(); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor sheds the configured streaming methods
+func (c *Controller) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if c.sheds(info.FullMethod) {
+			if err := c.Admit(); err != nil {
+				return err
+			}
+		}
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package admission
+
+import "fmt"
+
+// diskUsage is not supported on this platform
+func diskUsage(path string) (float64, error) {
+	return 0, fmt.Errorf("disk usage is not supported on this platform")
+}
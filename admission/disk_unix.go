@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd
+
+package admission
+
+import "syscall"
+
+// diskUsage returns the used fraction of the file system holding path, as
+// seen by unprivileged users
+func diskUsage(path string) (float64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	total := uint64(stat.Blocks)
+	if total == 0 {
+		return 0, nil
+	}
+	available := uint64(stat.Bavail)
+	used := total - uint64(stat.Bfree)
+	return float64(used) / float64(used+available), nil
+}
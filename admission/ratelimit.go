@@ -0,0 +1,160 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/auth"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ANY is the key of the limit applied to methods, callers or queues
+// without their own entry
+const ANY = "*"
+
+// Limit is a token bucket: Rate calls per second on average, in bursts of
+// up to Burst calls
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Limits is the layout of the rate limit file. Each map is keyed by a full
+// method name (such as "/task.TaskService/CreateTask"), a caller or a queue
+// name, or ANY. Every method, caller and queue gets its own bucket.
+type Limits struct {
+	Methods map[string]Limit `json:"methods,omitempty"`
+	// Callers are authenticated principals, or client IP addresses when
+	// authentication is disabled
+	Callers map[string]Limit `json:"callers,omitempty"`
+	// Queues limit the calls whose request names a queue
+	Queues map[string]Limit `json:"queues,omitempty"`
+}
+
+// LoadLimits reads a rate limit file
+func LoadLimits(path string) (*Limits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var limits Limits
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("failed to parse rate limits: %v", err)
+	}
+	return &limits, nil
+}
+
+// lookup returns the limit for a key, falling back to ANY
+func lookup(limits map[string]Limit, key string) (Limit, bool) {
+	if limit, exists := limits[key]; exists {
+		return limit, true
+	}
+	limit, exists := limits[ANY]
+	return limit, exists
+}
+
+// queueRequest is implemented by the generated request messages that have a
+// queue field
+type queueRequest interface {
+	GetQueue() string
+}
+
+// RateLimiter rejects calls beyond their method, caller or queue limits
+// with ResourceExhausted
+type RateLimiter struct {
+	limits      *Limits
+	limiters    *ratelimit.Limiters
+	limiterLock *sync.RWMutex
+}
+
+// NewRateLimiter creates a RateLimiter
+func NewRateLimiter(limits *Limits) *RateLimiter {
+	return &RateLimiter{limits: limits, limiters: ratelimit.NewLimiters(), limiterLock: &sync.RWMutex{}}
+}
+
+// SetLimits replaces the limits. Buckets whose rate or burst changed start
+// over full.
+func (r *RateLimiter) SetLimits(limits *Limits) {
+	r.limiterLock.Lock()
+	defer r.limiterLock.Unlock()
+	r.limits = limits
+}
+
+// allow takes a token from every bucket a call is subject to. A call that
+// is rejected by any bucket takes no token from the others.
+func (r *RateLimiter) allow(ctx context.Context, fullMethod string, req interface{}) error {
+	r.limiterLock.RLock()
+	limits := r.limits
+	r.limiterLock.RUnlock()
+
+	var buckets []ratelimit.Limit
+	var rejections []string
+	if limit, exists := lookup(limits.Methods, fullMethod); exists {
+		buckets = append(buckets, ratelimit.Limit{Key: "method:" + fullMethod, Rate: limit.Rate, Burst: limit.Burst})
+		rejections = append(rejections, fmt.Sprintf("rate limit exceeded for %s", fullMethod))
+	}
+	if caller := callerOf(ctx); caller != "" {
+		if limit, exists := lookup(limits.Callers, caller); exists {
+			buckets = append(buckets, ratelimit.Limit{Key: "caller:" + caller, Rate: limit.Rate, Burst: limit.Burst})
+			rejections = append(rejections, fmt.Sprintf("rate limit exceeded for caller %s", caller))
+		}
+	}
+	if request, ok := req.(queueRequest); ok {
+		queue := queues.Normalize(request.GetQueue())
+		if limit, exists := lookup(limits.Queues, queue); exists {
+			buckets = append(buckets, ratelimit.Limit{Key: "queue:" + queue, Rate: limit.Rate, Burst: limit.Burst})
+			rejections = append(rejections, fmt.Sprintf("rate limit exceeded for queue %q", queue))
+		}
+	}
+	if rejected := r.limiters.AllowAll(buckets, time.Now()); rejected >= 0 {
+		return status.Error(codes.ResourceExhausted, rejections[rejected])
+	}
+	return nil
+}
+
+// callerOf identifies the caller of a request by its principal, or by its
+// IP address without authentication
+func callerOf(ctx context.Context) string {
+	if principal := auth.FromContext(ctx); principal != nil {
+		return principal.Name
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// UnaryServerInterceptor enforces the limits. It must run after the
+// authentication interceptor so callers are known by their principal.
+func (r *RateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := r.allow(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor enforces the method and caller limits when a
+// stream is opened
+func (r *RateLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := r.allow(stream.Context(), info.FullMethod, nil); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// Archive stores expired tasks as gzip-compressed JSON lines, one file per day
+type Archive struct {
+	archiveDir  string
+	archiveLock *sync.Mutex
+}
+
+// Filter selects archived tasks. Empty fields match everything.
+type Filter struct {
+	TaskID        string
+	Queue         string
+	State         string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int
+}
+
+// NewArchive creates a new Archive
+func NewArchive(archiveDir string) (*Archive, error) {
+	// Create the archive directory if it doesn't exist
+	if _, err := os.Stat(archiveDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(archiveDir, 0755); err != nil {
+			slog.Error("failed to create archive directory", "path", archiveDir, "error", err)
+			return nil, err
+		}
+	}
+
+	return &Archive{
+		archiveDir:  archiveDir,
+		archiveLock: &sync.Mutex{},
+	}, nil
+}
+
+// Append writes tasks to today's archive file
+func (a *Archive) Append(tasks []*task.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	a.archiveLock.Lock()
+	defer a.archiveLock.Unlock()
+
+	archiveFile := filepath.Join(a.archiveDir, fmt.Sprintf("%s.jsonl.gz", time.Now().UTC().Format("2006-01-02")))
+	file, err := os.OpenFile(archiveFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Each append adds a new gzip member, which readers see as one stream
+	writer := gzip.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, t := range tasks {
+		if err := encoder.Encode(t); err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	metrics.PersistenceFsyncs.WithLabelValues("archive").Inc()
+	return file.Sync()
+}
+
+// Search returns archived tasks matching the filter, oldest archive first
+func (a *Archive) Search(filter Filter) ([]*task.Task, error) {
+	a.archiveLock.Lock()
+	defer a.archiveLock.Unlock()
+
+	files, err := os.ReadDir(a.archiveDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".jsonl.gz") {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+
+	var results []*task.Task
+	for _, name := range names {
+		matches, err := searchFile(filepath.Join(a.archiveDir, name), filter, filter.Limit-len(results))
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s: %v", name, err)
+		}
+		results = append(results, matches...)
+		if filter.Limit > 0 && len(results) >= filter.Limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+func searchFile(path string, filter Filter, limit int) ([]*task.Task, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var results []*task.Task
+	decoder := json.NewDecoder(bufio.NewReader(reader))
+	for {
+		var t task.Task
+		if err := decoder.Decode(&t); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if !filter.matches(&t) {
+			continue
+		}
+		results = append(results, &t)
+		if filter.Limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (f Filter) matches(t *task.Task) bool {
+	if f.TaskID != "" && t.ID != f.TaskID {
+		return false
+	}
+	if f.Queue != "" && queues.Normalize(t.Queue) != f.Queue {
+		return false
+	}
+	if f.State != "" && t.State != f.State {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() || !f.CreatedBefore.IsZero() {
+		createdAt, err := time.Parse(time.RFC3339, t.CreatedAt)
+		if err != nil {
+			return false
+		}
+		if !f.CreatedAfter.IsZero() && createdAt.Before(f.CreatedAfter) {
+			return false
+		}
+		if !f.CreatedBefore.IsZero() && !createdAt.Before(f.CreatedBefore) {
+			return false
+		}
+	}
+	return true
+}
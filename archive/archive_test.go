@@ -0,0 +1,80 @@
+package archive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+func newTestArchive(t *testing.T) *Archive {
+	t.Helper()
+	a, err := NewArchive(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewArchive: %v", err)
+	}
+	return a
+}
+
+func archivedTask(id string, queue string, state string, createdAt time.Time) *task.Task {
+	return &task.Task{ID: id, Queue: queue, State: state, CreatedAt: createdAt.Format(time.RFC3339)}
+}
+
+func ids(tasks []*task.Task) []string {
+	var result []string
+	for _, t := range tasks {
+		result = append(result, t.ID)
+	}
+	return result
+}
+
+func TestAppendAndSearch(t *testing.T) {
+	a := newTestArchive(t)
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Separate appends land in the same file as separate gzip members
+	if err := a.Append([]*task.Task{
+		archivedTask("t1", "emails", "completed", day),
+		archivedTask("t2", "emails", "failed", day.Add(time.Hour)),
+	}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := a.Append([]*task.Task{archivedTask("t3", "", "completed", day.Add(2*time.Hour))}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := a.Append(nil); err != nil {
+		t.Fatalf("Append of nothing: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "everything", filter: Filter{}, want: []string{"t1", "t2", "t3"}},
+		{name: "by ID", filter: Filter{TaskID: "t2"}, want: []string{"t2"}},
+		{name: "by queue", filter: Filter{Queue: "emails"}, want: []string{"t1", "t2"}},
+		{name: "default queue", filter: Filter{Queue: "default"}, want: []string{"t3"}},
+		{name: "by state", filter: Filter{State: "completed"}, want: []string{"t1", "t3"}},
+		{name: "created after", filter: Filter{CreatedAfter: day.Add(time.Hour)}, want: []string{"t2", "t3"}},
+		{name: "created before", filter: Filter{CreatedBefore: day.Add(time.Hour)}, want: []string{"t1"}},
+		{name: "limit", filter: Filter{Limit: 2}, want: []string{"t1", "t2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := a.Search(tt.filter)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			got := ids(results)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Search = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
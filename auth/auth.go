@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/tenants"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DEFAULT_RELOAD_INTERVAL is how often the key set and policy files are read again
+const DEFAULT_RELOAD_INTERVAL = 30 * time.Second
+
+// Ways a principal can be authenticated
+const (
+	METHOD_TOKEN = "token"
+	METHOD_MTLS  = "mtls"
+)
+
+// Principal is an authenticated caller
+type Principal struct {
+	Name   string
+	Method string
+	// Tenant is empty for principals outside every tenant
+	Tenant string
+}
+
+type principalKey struct{}
+
+// NewContext returns a context carrying a principal
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of a request, or nil when
+// authentication is disabled
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// Authenticator identifies callers from a bearer token in the
+// "authorization" metadata or, failing that, from their verified TLS client
+// certificate, and decides what they may do from a policy file. The key set
+// and policy are reloaded from their files periodically.
+type Authenticator struct {
+	keysPath   string
+	policyPath string
+	keys       *KeySet
+	policy     *Policy
+	// publicServices are full service names callers reach unauthenticated
+	publicServices []string
+	authLock       *sync.RWMutex
+}
+
+// NewAuthenticator loads a policy and, unless keysPath is empty, a token key
+// set. Without a key set only TLS client certificates authenticate callers.
+func NewAuthenticator(keysPath string, policyPath string) (*Authenticator, error) {
+	a := &Authenticator{keysPath: keysPath, policyPath: policyPath, authLock: &sync.RWMutex{}}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload reads the key set and policy files again. Both are left untouched
+// if either file is invalid.
+func (a *Authenticator) Reload() error {
+	policy, err := LoadPolicy(a.policyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load policy: %v", err)
+	}
+	var keys *KeySet
+	if a.keysPath != "" {
+		if keys, err = LoadKeySet(a.keysPath); err != nil {
+			return fmt.Errorf("failed to load token keys: %v", err)
+		}
+	}
+
+	a.authLock.Lock()
+	defer a.authLock.Unlock()
+	a.keys = keys
+	a.policy = policy
+	return nil
+}
+
+// PeriodicallyReload reloads the files every interval
+func (a *Authenticator) PeriodicallyReload(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.Reload(); err != nil {
+			slog.Error("failed to reload auth configuration", "error", err)
+		}
+	}
+}
+
+// Authenticate identifies the caller of a request
+func (a *Authenticator) Authenticate(ctx context.Context) (*Principal, error) {
+	a.authLock.RLock()
+	keys := a.keys
+	a.authLock.RUnlock()
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, found := strings.CutPrefix(values[0], "Bearer ")
+			if !found {
+				return nil, status.Error(codes.Unauthenticated, "authorization must be a bearer token")
+			}
+			if keys == nil {
+				return nil, status.Error(codes.Unauthenticated, "bearer tokens are not accepted")
+			}
+			claims, err := keys.Verify(token, time.Now())
+			if err != nil {
+				return nil, status.Errorf(codes.Unauthenticated, "invalid bearer token: %v", err)
+			}
+			return a.principal(claims.Subject, METHOD_TOKEN), nil
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			cert := tlsInfo.State.VerifiedChains[0][0]
+			name := cert.Subject.CommonName
+			if name == "" && len(cert.DNSNames) > 0 {
+				name = cert.DNSNames[0]
+			}
+			if name != "" {
+				return a.principal(name, METHOD_MTLS), nil
+			}
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "no credentials")
+}
+
+func (a *Authenticator) principal(name string, method string) *Principal {
+	principal := &Principal{Name: name, Method: method}
+	if tenant := a.Tenant(name); tenant != nil {
+		principal.Tenant = tenant.Name
+	}
+	return principal
+}
+
+// Tenant returns the tenant a principal belongs to, or nil
+func (a *Authenticator) Tenant(principal string) *tenants.Tenant {
+	a.authLock.RLock()
+	defer a.authLock.RUnlock()
+	return a.policy.TenantOf(principal)
+}
+
+// Authorize checks that a principal holds a role on a queue. A nil
+// principal means authentication is disabled and is always allowed.
+func (a *Authenticator) Authorize(principal *Principal, queue string, role string) error {
+	if principal == nil {
+		return nil
+	}
+	a.authLock.RLock()
+	defer a.authLock.RUnlock()
+	if !a.policy.HasRole(principal.Name, queue, role) {
+		return status.Errorf(codes.PermissionDenied, "%s does not hold the %s role on queue %q", principal.Name, role, queue)
+	}
+	return nil
+}
+
+// AuthorizeRead checks that a principal holds any role on a queue
+func (a *Authenticator) AuthorizeRead(principal *Principal, queue string) error {
+	if principal == nil {
+		return nil
+	}
+	a.authLock.RLock()
+	defer a.authLock.RUnlock()
+	if !a.policy.HasAnyRole(principal.Name, queue) {
+		return status.Errorf(codes.PermissionDenied, "%s has no access to queue %q", principal.Name, queue)
+	}
+	return nil
+}
+
+// AuthorizeAny checks that a principal holds some role on some queue
+func (a *Authenticator) AuthorizeAny(principal *Principal) error {
+	if principal == nil {
+		return nil
+	}
+	a.authLock.RLock()
+	defer a.authLock.RUnlock()
+	if !a.policy.Granted(principal.Name) {
+		return status.Errorf(codes.PermissionDenied, "%s has no access", principal.Name)
+	}
+	return nil
+}
+
+// AuthorizeOperator checks that a principal may run operations that span
+// tenants, such as snapshots, shard moves and replication: it needs the
+// admin role on every queue and must not be a member of a tenant
+func (a *Authenticator) AuthorizeOperator(principal *Principal) error {
+	if principal == nil {
+		return nil
+	}
+	if principal.Tenant != "" {
+		return status.Errorf(codes.PermissionDenied, "%s is a member of tenant %s", principal.Name, principal.Tenant)
+	}
+	return a.Authorize(principal, ANY, ROLE_ADMIN)
+}
+
+// UnaryServerInterceptor authenticates every call and adds the principal to
+// its context. Calls to adminServices (full service names such as
+// "task.SnapshotService") are only allowed to operators, see
+// AuthorizeOperator; other services authorize their own calls.
+func (a *Authenticator) UnaryServerInterceptor(adminServices ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authenticateCall(ctx, info.FullMethod, adminServices)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func (a *Authenticator) StreamServerInterceptor(adminServices ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticateCall(stream.Context(), info.FullMethod, adminServices)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &principalStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// SetPublicServices lets callers reach services (full service names such as
+// "grpc.health.v1.Health") without credentials, so probes need none
+func (a *Authenticator) SetPublicServices(services ...string) {
+	a.authLock.Lock()
+	defer a.authLock.Unlock()
+	a.publicServices = services
+}
+
+func (a *Authenticator) public(fullMethod string) bool {
+	a.authLock.RLock()
+	defer a.authLock.RUnlock()
+	for _, service := range a.publicServices {
+		if strings.HasPrefix(fullMethod, "/"+service+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Authenticator) authenticateCall(ctx context.Context, fullMethod string, adminServices []string) (context.Context, error) {
+	if a.public(fullMethod) {
+		return ctx, nil
+	}
+	principal, err := a.Authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range adminServices {
+		if strings.HasPrefix(fullMethod, "/"+service+"/") {
+			if err := a.AuthorizeOperator(principal); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewContext(ctx, principal), nil
+}
+
+// principalStream replaces the context of a server stream
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
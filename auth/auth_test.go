@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/keyring"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const TEST_POLICY = `{
+	"grants": [
+		{"principals": ["ops"], "queues": ["*"], "roles": ["admin"]},
+		{"principals": ["producer"], "queues": ["emails"], "roles": ["producer"]},
+		{"principals": ["tenant-admin"], "queues": ["*"], "roles": ["admin"]}
+	],
+	"tenants": [{"name": "acme", "principals": ["tenant-admin"]}]
+}`
+
+// newTestAuthenticator returns an authenticator using TEST_POLICY and a
+// fresh token key set
+func newTestAuthenticator(t *testing.T) (*Authenticator, *KeySet) {
+	t.Helper()
+	dir := t.TempDir()
+	keysPath := filepath.Join(dir, "tokens.json")
+	if _, err := keyring.AddKey(keysPath, "k1"); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	policyPath := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(policyPath, []byte(TEST_POLICY), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	a, err := NewAuthenticator(keysPath, policyPath)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	return a, a.keys
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	a, keys := newTestAuthenticator(t)
+	token, err := keys.Sign("tenant-admin", time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	principal, err := a.Authenticate(withToken(token))
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.Name != "tenant-admin" || principal.Method != METHOD_TOKEN || principal.Tenant != "acme" {
+		t.Errorf("principal = %+v, want tenant-admin of acme by token", principal)
+	}
+
+	expired, err := keys.Sign("ops", time.Hour, time.Now().Add(-2*time.Hour))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	for name, ctx := range map[string]context.Context{
+		"expired token":  withToken(expired),
+		"no credentials": context.Background(),
+		"basic auth":     metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic b3Bz")),
+	} {
+		if _, err := a.Authenticate(ctx); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Authenticate with %s error = %v, want Unauthenticated", name, err)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	a, _ := newTestAuthenticator(t)
+	ops := &Principal{Name: "ops"}
+	producer := &Principal{Name: "producer"}
+	tenantAdmin := &Principal{Name: "tenant-admin", Tenant: "acme"}
+
+	if err := a.Authorize(producer, "emails", ROLE_PRODUCER); err != nil {
+		t.Errorf("producer on its queue: %v", err)
+	}
+	if err := a.Authorize(producer, "emails", ROLE_WORKER); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("producer leasing error = %v, want PermissionDenied", err)
+	}
+	if err := a.AuthorizeRead(producer, "reports"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("producer reading another queue error = %v, want PermissionDenied", err)
+	}
+	// Admins hold every role
+	if err := a.Authorize(ops, "reports", ROLE_WORKER); err != nil {
+		t.Errorf("admin as a worker: %v", err)
+	}
+
+	if err := a.AuthorizeOperator(ops); err != nil {
+		t.Errorf("AuthorizeOperator(ops): %v", err)
+	}
+	for _, principal := range []*Principal{producer, tenantAdmin} {
+		if err := a.AuthorizeOperator(principal); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("AuthorizeOperator(%s) error = %v, want PermissionDenied", principal.Name, err)
+		}
+	}
+	// A nil principal means authentication is disabled
+	if err := a.AuthorizeOperator(nil); err != nil {
+		t.Errorf("AuthorizeOperator(nil): %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/indkumar8999/ps-tasks/tenants"
+)
+
+// Roles a principal can hold on a queue
+const (
+	// ROLE_PRODUCER creates, reads and cancels tasks
+	ROLE_PRODUCER = "producer"
+	// ROLE_WORKER leases, reads and finishes tasks
+	ROLE_WORKER = "worker"
+	// ROLE_ADMIN holds every role and manages queues, shards and snapshots
+	ROLE_ADMIN = "admin"
+)
+
+// ANY matches every principal or every queue in a grant
+const ANY = "*"
+
+// Grant gives principals roles on queues
+type Grant struct {
+	Principals []string `json:"principals"`
+	Queues     []string `json:"queues"`
+	Roles      []string `json:"roles"`
+}
+
+// Policy is the layout of the policy file
+type Policy struct {
+	Grants []Grant `json:"grants"`
+	// Tenants assigns principals to tenants. Principals outside every
+	// tenant, such as operators and other nodes, see all tenants.
+	Tenants []tenants.Tenant `json:"tenants,omitempty"`
+}
+
+// LoadPolicy reads a policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %v", err)
+	}
+	for _, grant := range policy.Grants {
+		for _, role := range grant.Roles {
+			switch role {
+			case ROLE_PRODUCER, ROLE_WORKER, ROLE_ADMIN:
+			default:
+				return nil, fmt.Errorf("unknown role %q", role)
+			}
+		}
+	}
+	if err := tenants.Validate(policy.Tenants); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// TenantOf returns the tenant a principal belongs to, or nil
+func (p *Policy) TenantOf(principal string) *tenants.Tenant {
+	for i, tenant := range p.Tenants {
+		for _, member := range tenant.Principals {
+			if member == principal {
+				return &p.Tenants[i]
+			}
+		}
+	}
+	return nil
+}
+
+// HasRole reports whether a principal holds a role on a queue. Admins hold
+// every role.
+func (p *Policy) HasRole(principal string, queue string, role string) bool {
+	for _, grant := range p.Grants {
+		if !matches(grant.Principals, principal) || !matches(grant.Queues, queue) {
+			continue
+		}
+		for _, granted := range grant.Roles {
+			if granted == role || granted == ROLE_ADMIN {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasAnyRole reports whether a principal holds any role on a queue
+func (p *Policy) HasAnyRole(principal string, queue string) bool {
+	for _, grant := range p.Grants {
+		if matches(grant.Principals, principal) && matches(grant.Queues, queue) && len(grant.Roles) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(values []string, value string) bool {
+	for _, v := range values {
+		if v == ANY || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Granted reports whether a principal holds any role on any queue
+func (p *Policy) Granted(principal string) bool {
+	for _, grant := range p.Grants {
+		if matches(grant.Principals, principal) && len(grant.Roles) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/keyring"
+)
+
+// TOKEN_ALGORITHM is the only signature algorithm tokens may use
+const TOKEN_ALGORITHM = "HS256"
+
+// Claims are the fields of a bearer token
+type Claims struct {
+	// Subject is the principal the token authenticates
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// KeySet holds the keys tokens are signed with. It is stored in the same
+// layout as a keyring file, so cmd/keyring manages it: new tokens are
+// signed with the primary key and any key in the file verifies them.
+type KeySet struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// LoadKeySet reads a key set file
+func LoadKeySet(path string) (*KeySet, error) {
+	file, err := keyring.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keySet := &KeySet{primary: file.Primary, keys: make(map[string][]byte)}
+	for _, key := range file.Keys {
+		if key.ID == "" || len(key.Secret) == 0 {
+			return nil, fmt.Errorf("key set has an invalid key")
+		}
+		keySet.keys[key.ID] = key.Secret
+	}
+	if _, exists := keySet.keys[file.Primary]; !exists {
+		return nil, fmt.Errorf("primary key %q is not in the key set", file.Primary)
+	}
+	return keySet, nil
+}
+
+// Sign issues a token for subject that expires after ttl
+func (ks *KeySet) Sign(subject string, ttl time.Duration, now time.Time) (string, error) {
+	if subject == "" {
+		return "", fmt.Errorf("a subject is required")
+	}
+	header, err := json.Marshal(tokenHeader{Algorithm: TOKEN_ALGORITHM, Type: "JWT", KeyID: ks.primary})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(Claims{Subject: subject, IssuedAt: now.Unix(), ExpiresAt: now.Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+	signed := encodeSegment(header) + "." + encodeSegment(claims)
+	return signed + "." + encodeSegment(sign(ks.keys[ks.primary], signed)), nil
+}
+
+// Verify checks a token's signature and expiry and returns its claims
+func (ks *KeySet) Verify(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %v", err)
+	}
+	if header.Algorithm != TOKEN_ALGORITHM {
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Algorithm)
+	}
+	secret, exists := ks.keys[header.KeyID]
+	if !exists {
+		return nil, fmt.Errorf("unknown token key %q", header.KeyID)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, sign(secret, parts[0]+"."+parts[1])) {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %v", err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+	if claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("token expired")
+	}
+	return &claims, nil
+}
+
+func sign(secret []byte, signed string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/keyring"
+)
+
+// newTestKeySet returns a key set holding a freshly generated key
+func newTestKeySet(t *testing.T, id string) *KeySet {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if _, err := keyring.AddKey(path, id); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	keySet, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	return keySet
+}
+
+func TestSignAndVerify(t *testing.T) {
+	keySet := newTestKeySet(t, "k1")
+	now := time.Now()
+	token, err := keySet.Sign("alice", time.Hour, now)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	claims, err := keySet.Verify(token, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Subject != "alice" || claims.ExpiresAt != now.Add(time.Hour).Unix() {
+		t.Errorf("claims = %+v, want alice expiring in an hour", claims)
+	}
+	if _, err := keySet.Sign("", time.Hour, now); err == nil {
+		t.Errorf("signing a token without a subject succeeded")
+	}
+}
+
+func TestVerifyRejectsExpiredTokens(t *testing.T) {
+	keySet := newTestKeySet(t, "k1")
+	now := time.Now()
+	token, err := keySet.Sign("alice", time.Hour, now)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	for _, at := range []time.Time{now.Add(time.Hour), now.Add(2 * time.Hour)} {
+		if _, err := keySet.Verify(token, at); err == nil || !strings.Contains(err.Error(), "expired") {
+			t.Errorf("Verify at %v error = %v, want expired", at.Sub(now), err)
+		}
+	}
+}
+
+func TestVerifyRejectsOtherKeys(t *testing.T) {
+	now := time.Now()
+	token, err := newTestKeySet(t, "k1").Sign("alice", time.Hour, now)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// A key with the same ID but another secret
+	if _, err := newTestKeySet(t, "k1").Verify(token, now); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("Verify with another secret error = %v, want an invalid signature", err)
+	}
+	// A key set without the token's key
+	if _, err := newTestKeySet(t, "k2").Verify(token, now); err == nil || !strings.Contains(err.Error(), "unknown token key") {
+		t.Errorf("Verify without the key error = %v, want an unknown key", err)
+	}
+
+	// After a rotation, tokens signed with the old key still verify
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if _, err := keyring.AddKey(path, "old"); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	keySet, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	old, err := keySet.Sign("alice", time.Hour, now)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := keyring.AddKey(path, "new"); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if keySet, err = LoadKeySet(path); err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	if _, err := keySet.Verify(old, now); err != nil {
+		t.Errorf("Verify with a rotated key: %v", err)
+	}
+}
+
+func TestVerifyRejectsOtherAlgorithms(t *testing.T) {
+	keySet := newTestKeySet(t, "k1")
+	now := time.Now()
+	token, err := keySet.Sign("alice", time.Hour, now)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	for _, alg := range []string{"none", "HS512", "RS256"} {
+		header, err := json.Marshal(tokenHeader{Algorithm: alg, Type: "JWT", KeyID: "k1"})
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		signed := encodeSegment(header) + "." + parts[1]
+		forged := signed + "." + encodeSegment(sign(keySet.keys["k1"], signed))
+		if _, err := keySet.Verify(forged, now); err == nil || !strings.Contains(err.Error(), "algorithm") {
+			t.Errorf("Verify of an %s token error = %v, want an unsupported algorithm", alg, err)
+		}
+	}
+	// Without a signature
+	if _, err := keySet.Verify(parts[0]+"."+parts[1]+".", now); err == nil {
+		t.Errorf("Verify of an unsigned token succeeded")
+	}
+	if _, err := keySet.Verify("not-a-token", now); err == nil {
+		t.Errorf("Verify of a malformed token succeeded")
+	}
+}
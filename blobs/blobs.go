@@ -0,0 +1,545 @@
+package blobs
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/keyring"
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/indkumar8999/ps-tasks/store"
+)
+
+// DEFAULT_THRESHOLD is the payload size above which payloads are stored as blobs
+const DEFAULT_THRESHOLD = 1 << 20
+
+// CHUNK_SIZE is how much of a payload is encrypted, or streamed, at a time
+const CHUNK_SIZE = 1 << 20
+
+// DEFAULT_GC_INTERVAL is how often unreferenced blobs are collected
+const DEFAULT_GC_INTERVAL = 10 * time.Minute
+
+// DEFAULT_GC_GRACE is how long an unreferenced blob is kept, so a payload
+// uploaded before its task is created is not collected in between
+const DEFAULT_GC_GRACE = 1 * time.Hour
+
+// FETCH_TIMEOUT bounds fetching a blob from another node
+const FETCH_TIMEOUT = 10 * time.Minute
+
+// TMP_PREFIX marks blobs that are still being written
+const TMP_PREFIX = "upload-"
+
+// ErrNotFound is returned for blobs the store does not hold
+var ErrNotFound = errors.New("blob not found")
+
+// Meta describes a stored blob. It is saved next to the blob as
+// <digest>.json; a blob without its meta is an unfinished write.
+type Meta struct {
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"created_at"`
+	// KeyID and WrappedKey are set when the blob is encrypted. The blob is
+	// then a sequence of chunks, each a 4 byte length and an AES-GCM
+	// ciphertext sealed with the data key.
+	KeyID      string `json:"key_id,omitempty"`
+	WrappedKey []byte `json:"wrapped_key,omitempty"`
+}
+
+// Store keeps payloads in <blobsDir>/<first two digits>/<sha256>, so equal
+// payloads are stored once. Blobs are reference counted by the tasks that
+// use them; Collect deletes blobs nothing refers to.
+type Store struct {
+	blobsDir string
+	keyring  *keyring.Keyring
+	refs     map[string]int
+	blobLock *sync.Mutex
+}
+
+// NewStore creates a new blob Store. Blobs are encrypted when keys is set.
+func NewStore(blobsDir string, keys *keyring.Keyring) (*Store, error) {
+	if err := os.MkdirAll(blobsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blobs directory: %v", err)
+	}
+	return &Store{
+		blobsDir: blobsDir,
+		keyring:  keys,
+		refs:     make(map[string]int),
+		blobLock: &sync.Mutex{},
+	}, nil
+}
+
+// ValidDigest reports whether digest is a hex encoded SHA-256
+func ValidDigest(digest string) bool {
+	if len(digest) != sha256.Size*2 || strings.ToLower(digest) != digest {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
+
+func (s *Store) dir(digest string) string {
+	return filepath.Join(s.blobsDir, digest[:2])
+}
+
+func (s *Store) dataPath(digest string) string {
+	return filepath.Join(s.dir(digest), digest)
+}
+
+// Put stores a payload and returns its meta. Storing a payload that is
+// already present only refreshes its creation time.
+func (s *Store) Put(r io.Reader) (*Meta, error) {
+	suffix := make([]byte, 8)
+	if _, err := io.ReadFull(rand.Reader, suffix); err != nil {
+		return nil, err
+	}
+	tmpFile := filepath.Join(s.blobsDir, TMP_PREFIX+hex.EncodeToString(suffix))
+	meta, err := s.write(tmpFile, r)
+	if err != nil {
+		os.Remove(tmpFile)
+		return nil, err
+	}
+
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+
+	if existing, err := s.stat(meta.Digest); err == nil {
+		os.Remove(tmpFile)
+		existing.CreatedAt = meta.CreatedAt
+		if err := store.Save(s.dir(existing.Digest), existing.Digest, existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+	if err := os.MkdirAll(s.dir(meta.Digest), 0755); err != nil {
+		os.Remove(tmpFile)
+		return nil, err
+	}
+	if err := os.Rename(tmpFile, s.dataPath(meta.Digest)); err != nil {
+		os.Remove(tmpFile)
+		return nil, err
+	}
+	if err := store.Save(s.dir(meta.Digest), meta.Digest, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// write copies r into path, encrypting it when the store has a keyring,
+// and returns the meta of the plaintext
+func (s *Store) write(path string, r io.Reader) (*Meta, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	meta := &Meta{CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	hasher := sha256.New()
+	r = io.TeeReader(r, hasher)
+
+	if s.keyring == nil {
+		if meta.Size, err = io.Copy(file, r); err != nil {
+			return nil, err
+		}
+	} else {
+		dataKey, keyID, wrappedKey, err := s.keyring.NewDataKey()
+		if err != nil {
+			return nil, err
+		}
+		meta.KeyID = keyID
+		meta.WrappedKey = wrappedKey
+
+		writer := bufio.NewWriter(file)
+		buf := make([]byte, CHUNK_SIZE)
+		for index := uint64(0); ; index++ {
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				sealed, err := keyring.SealWithKey(dataKey, buf[:n], chunkAAD(index))
+				if err != nil {
+					return nil, err
+				}
+				if err := binary.Write(writer, binary.BigEndian, uint32(len(sealed))); err != nil {
+					return nil, err
+				}
+				if _, err := writer.Write(sealed); err != nil {
+					return nil, err
+				}
+				meta.Size += int64(n)
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	metrics.PersistenceFsyncs.WithLabelValues("blobs").Inc()
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+	meta.Digest = hex.EncodeToString(hasher.Sum(nil))
+	return meta, nil
+}
+
+// chunkAAD binds a chunk to its position, so chunks cannot be reordered
+func chunkAAD(index uint64) []byte {
+	aad := make([]byte, 8)
+	binary.BigEndian.PutUint64(aad, index)
+	return aad
+}
+
+// Stat returns the meta of a blob
+func (s *Store) Stat(digest string) (*Meta, error) {
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+	return s.stat(digest)
+}
+
+func (s *Store) stat(digest string) (*Meta, error) {
+	if !ValidDigest(digest) {
+		return nil, fmt.Errorf("invalid blob digest %q", digest)
+	}
+	var meta Meta
+	if err := store.Load(s.dir(digest), digest, &meta); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, digest)
+		}
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// Open returns a reader of a blob's payload. The payload is checked against
+// its digest as it is read; a blob that does not match fails at the end.
+func (s *Store) Open(digest string) (io.ReadCloser, *Meta, error) {
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+
+	meta, err := s.stat(digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(s.dataPath(digest))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := &verifyingReader{file: file, meta: meta, hasher: sha256.New()}
+	if meta.KeyID == "" {
+		reader.source = file
+		return reader, meta, nil
+	}
+	if s.keyring == nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("blob %s is encrypted with key %s but no keyring is configured", digest, meta.KeyID)
+	}
+	dataKey, err := s.keyring.UnwrapDataKey(meta.KeyID, meta.WrappedKey)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	reader.source = &chunkReader{reader: bufio.NewReader(file), dataKey: dataKey}
+	return reader, meta, nil
+}
+
+// chunkReader decrypts the chunks of an encrypted blob
+type chunkReader struct {
+	reader  *bufio.Reader
+	dataKey []byte
+	index   uint64
+	pending []byte
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		var size uint32
+		if err := binary.Read(c.reader, binary.BigEndian, &size); err != nil {
+			if err == io.EOF {
+				return 0, io.EOF
+			}
+			return 0, fmt.Errorf("failed to read chunk: %v", err)
+		}
+		if size > CHUNK_SIZE+1024 {
+			return 0, fmt.Errorf("chunk %d is too large", c.index)
+		}
+		sealed := make([]byte, size)
+		if _, err := io.ReadFull(c.reader, sealed); err != nil {
+			return 0, fmt.Errorf("failed to read chunk: %v", err)
+		}
+		plaintext, err := keyring.OpenWithKey(c.dataKey, sealed, chunkAAD(c.index))
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt chunk %d: %v", c.index, err)
+		}
+		c.index++
+		c.pending = plaintext
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// verifyingReader checks the size and digest of a payload once it is read
+type verifyingReader struct {
+	file   *os.File
+	source io.Reader
+	meta   *Meta
+	hasher hash.Hash
+	size   int64
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.source.Read(p)
+	v.hasher.Write(p[:n])
+	v.size += int64(n)
+	if err == io.EOF {
+		if v.size != v.meta.Size || hex.EncodeToString(v.hasher.Sum(nil)) != v.meta.Digest {
+			return n, fmt.Errorf("blob %s does not match its digest", v.meta.Digest)
+		}
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error {
+	return v.file.Close()
+}
+
+// Read returns a whole payload
+func (s *Store) Read(digest string) ([]byte, error) {
+	reader, _, err := s.Open(digest)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Ref records one more reference to a blob
+func (s *Store) Ref(digest string) {
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+	s.refs[digest]++
+}
+
+// Unref drops a reference to a blob. The blob is deleted by the next
+// collection after the grace period.
+func (s *Store) Unref(digest string) {
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+	if s.refs[digest] <= 1 {
+		delete(s.refs, digest)
+		return
+	}
+	s.refs[digest]--
+}
+
+// ResetRefs replaces all reference counts, after the tasks were reloaded
+func (s *Store) ResetRefs(refs map[string]int) {
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+	s.refs = refs
+}
+
+// metas returns the meta of every stored blob
+func (s *Store) metas() ([]*Meta, error) {
+	var metas []*Meta
+	err := filepath.WalkDir(s.blobsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		digest, ok := store.RecordID(entry.Name())
+		if entry.IsDir() || !ok || !ValidDigest(digest) {
+			return nil
+		}
+		meta, err := s.stat(digest)
+		if err != nil {
+			return err
+		}
+		metas = append(metas, meta)
+		return nil
+	})
+	return metas, err
+}
+
+// Collect deletes blobs without references that are older than grace, as
+// well as unfinished writes older than grace. It returns the number of
+// blobs deleted.
+func (s *Store) Collect(grace time.Duration) (int, error) {
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+
+	cutoff := time.Now().Add(-grace)
+	metas, err := s.metas()
+	if err != nil {
+		return 0, err
+	}
+	collected := 0
+	for _, meta := range metas {
+		createdAt, err := time.Parse(time.RFC3339, meta.CreatedAt)
+		if s.refs[meta.Digest] > 0 || (err == nil && createdAt.After(cutoff)) {
+			continue
+		}
+		if err := os.Remove(s.dataPath(meta.Digest)); err != nil && !os.IsNotExist(err) {
+			return collected, err
+		}
+		if err := store.Remove(s.dir(meta.Digest), meta.Digest); err != nil {
+			return collected, err
+		}
+		collected++
+	}
+
+	// Blobs without meta and uploads cut short
+	err = filepath.WalkDir(s.blobsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		name := entry.Name()
+		if _, ok := store.RecordID(name); ok {
+			return nil
+		}
+		if !strings.HasPrefix(name, TMP_PREFIX) && !strings.HasSuffix(name, store.TMP_EXT) {
+			if _, err := os.Stat(store.Path(filepath.Dir(path), name)); err == nil {
+				return nil
+			}
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	return collected, err
+}
+
+// Reencrypt makes every blob use the keyring's primary key. Encrypted blobs
+// only have their data key wrapped again; blobs stored in plain text are
+// encrypted. It returns the number of blobs changed.
+func (s *Store) Reencrypt() (int, error) {
+	if s.keyring == nil {
+		return 0, nil
+	}
+	s.blobLock.Lock()
+	metas, err := s.metas()
+	s.blobLock.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, meta := range metas {
+		if meta.KeyID == s.keyring.Primary() {
+			continue
+		}
+		var err error
+		if meta.KeyID == "" {
+			err = s.encrypt(meta)
+		} else {
+			err = s.rewrap(meta)
+		}
+		if err != nil {
+			return changed, fmt.Errorf("failed to re-encrypt blob %s: %v", meta.Digest, err)
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+func (s *Store) rewrap(meta *Meta) error {
+	dataKey, err := s.keyring.UnwrapDataKey(meta.KeyID, meta.WrappedKey)
+	if err != nil {
+		return err
+	}
+	keyID, wrappedKey, err := s.keyring.WrapDataKey(dataKey)
+	if err != nil {
+		return err
+	}
+
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+	current, err := s.stat(meta.Digest)
+	if err != nil {
+		// Collected in the meantime
+		return nil
+	}
+	current.KeyID = keyID
+	current.WrappedKey = wrappedKey
+	return store.Save(s.dir(meta.Digest), meta.Digest, current)
+}
+
+func (s *Store) encrypt(meta *Meta) error {
+	reader, _, err := s.Open(meta.Digest)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	tmpFile := s.dataPath(meta.Digest) + store.TMP_EXT
+	encrypted, err := s.write(tmpFile, reader)
+	if err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+
+	s.blobLock.Lock()
+	defer s.blobLock.Unlock()
+	current, err := s.stat(meta.Digest)
+	if err != nil {
+		os.Remove(tmpFile)
+		return nil
+	}
+	if err := os.Rename(tmpFile, s.dataPath(meta.Digest)); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	current.KeyID = encrypted.KeyID
+	current.WrappedKey = encrypted.WrappedKey
+	return store.Save(s.dir(meta.Digest), meta.Digest, current)
+}
+
+// ChunkReader reads a payload received in chunks, such as a stream of
+// PayloadChunk messages. recv returns the next chunk, or io.EOF after the
+// last one; first is a chunk that was already received.
+type ChunkReader struct {
+	pending []byte
+	recv    func() ([]byte, error)
+	close   func()
+}
+
+// NewChunkReader creates a new ChunkReader. close is called by Close.
+func NewChunkReader(first []byte, recv func() ([]byte, error), close func()) *ChunkReader {
+	return &ChunkReader{pending: first, recv: recv, close: close}
+}
+
+func (c *ChunkReader) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		chunk, err := c.recv()
+		if err != nil {
+			return 0, err
+		}
+		c.pending = chunk
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *ChunkReader) Close() error {
+	c.close()
+	return nil
+}
@@ -0,0 +1,194 @@
+package blobs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/keyring"
+)
+
+func newTestStore(t *testing.T, keys *keyring.Keyring) (*Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := NewStore(dir, keys)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	return s, dir
+}
+
+func digestOf(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPutAndRead(t *testing.T) {
+	s, _ := newTestStore(t, nil)
+	payload := bytes.Repeat([]byte("payload"), 1000)
+
+	meta, err := s.Put(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if meta.Digest != digestOf(payload) || meta.Size != int64(len(payload)) {
+		t.Errorf("meta = %+v, want digest %s and size %d", meta, digestOf(payload), len(payload))
+	}
+	got, err := s.Read(meta.Digest)
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("Read = %d bytes, %v, want the payload", len(got), err)
+	}
+	if _, err := s.Stat(digestOf([]byte("other"))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat of a missing blob error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Stat("../../etc/passwd"); err == nil {
+		t.Errorf("Stat of an invalid digest succeeded")
+	}
+}
+
+func TestPutDeduplicates(t *testing.T) {
+	s, dir := newTestStore(t, nil)
+	payload := []byte("the same payload")
+
+	first, err := s.Put(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("first Put: %v", err)
+	}
+	second, err := s.Put(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("second Put: %v", err)
+	}
+	if first.Digest != second.Digest {
+		t.Errorf("digests = %s and %s, want the same", first.Digest, second.Digest)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, first.Digest[:2]))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	// The blob and its meta
+	if len(entries) != 2 {
+		t.Errorf("blob directory holds %d files, want 2", len(entries))
+	}
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	if _, err := keyring.AddKey(path, "k1"); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	keys, err := keyring.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	s, dir := newTestStore(t, keys)
+	// More than one chunk
+	payload := bytes.Repeat([]byte("secret"), CHUNK_SIZE/3)
+
+	meta, err := s.Put(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if meta.KeyID != "k1" || len(meta.WrappedKey) == 0 {
+		t.Errorf("meta = key %q, %d byte wrapped key, want encryption with k1", meta.KeyID, len(meta.WrappedKey))
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, meta.Digest[:2], meta.Digest))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if bytes.Contains(stored, []byte("secretsecret")) {
+		t.Errorf("blob is stored in plain text")
+	}
+	got, err := s.Read(meta.Digest)
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("Read = %d bytes, %v, want the payload", len(got), err)
+	}
+
+	// Without the keyring the blob cannot be read
+	plain, err := NewStore(dir, nil)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if _, err := plain.Read(meta.Digest); err == nil {
+		t.Errorf("reading an encrypted blob without the keyring succeeded")
+	}
+}
+
+func TestReadDetectsCorruption(t *testing.T) {
+	s, dir := newTestStore(t, nil)
+	payload := []byte("a payload that will be corrupted")
+	meta, err := s.Put(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	path := filepath.Join(dir, meta.Digest[:2], meta.Digest)
+	corrupted := append([]byte{}, payload...)
+	corrupted[0] ^= 0xff
+	if err := os.WriteFile(path, corrupted, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := s.Read(meta.Digest); err == nil {
+		t.Errorf("reading a corrupted blob succeeded")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	s, _ := newTestStore(t, nil)
+	referenced, err := s.Put(bytes.NewReader([]byte("referenced")))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	unreferenced, err := s.Put(bytes.NewReader([]byte("unreferenced")))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	s.Ref(referenced.Digest)
+
+	// Unreferenced blobs are kept for the grace period
+	if collected, err := s.Collect(time.Hour); err != nil || collected != 0 {
+		t.Errorf("Collect within the grace period = %d, %v, want 0", collected, err)
+	}
+	if collected, err := s.Collect(0); err != nil || collected != 1 {
+		t.Errorf("Collect = %d, %v, want 1", collected, err)
+	}
+	if _, err := s.Stat(unreferenced.Digest); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unreferenced blob was kept: %v", err)
+	}
+	if _, err := s.Stat(referenced.Digest); err != nil {
+		t.Errorf("referenced blob was collected: %v", err)
+	}
+
+	s.Unref(referenced.Digest)
+	if collected, err := s.Collect(0); err != nil || collected != 1 {
+		t.Errorf("Collect after the last reference was dropped = %d, %v, want 1", collected, err)
+	}
+}
+
+func TestChunkReader(t *testing.T) {
+	chunks := [][]byte{[]byte("second "), []byte("third")}
+	closed := false
+	reader := NewChunkReader([]byte("first "), func() ([]byte, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+		chunk := chunks[0]
+		chunks = chunks[1:]
+		return chunk, nil
+	}, func() { closed = true })
+
+	got, err := io.ReadAll(reader)
+	if err != nil || string(got) != "first second third" {
+		t.Errorf("ReadAll = %q, %v, want %q", got, err, "first second third")
+	}
+	reader.Close()
+	if !closed {
+		t.Errorf("Close did not close the stream")
+	}
+}
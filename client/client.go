@@ -2,10 +2,19 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"github.com/indkumar8999/ps-tasks/codec"
+	"github.com/indkumar8999/ps-tasks/logging"
+	"github.com/indkumar8999/ps-tasks/tlsconfig"
+	"github.com/indkumar8999/ps-tasks/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"github.com/indkumar8999/ps-tasks/service/taskpb"
 )
 
@@ -13,17 +22,169 @@ import (
 type Client struct {
 	conn   *grpc.ClientConn
 	client taskpb.TaskServiceClient
+	// ctx is the parent of every call's context, see WithContext
+	ctx context.Context
+	// leases holds the leases taken with LeaseTask, see leaseCache
+	leases *leaseCache
+}
+
+// leaseCache remembers the lease the client holds on each task it leased,
+// so CompleteTask and FailTask send its lease ID and fencing token. The
+// server rejects finishing a leased task without them.
+type leaseCache struct {
+	leaseLock sync.Mutex
+	leases    map[string]*taskpb.LeaseTaskResponse
+}
+
+func (lc *leaseCache) put(lease *taskpb.LeaseTaskResponse) {
+	lc.leaseLock.Lock()
+	defer lc.leaseLock.Unlock()
+	lc.leases[lease.TaskId] = lease
+}
+
+// get returns the lease ID and fencing token held on a task, or zero values
+func (lc *leaseCache) get(taskID string) (string, uint64) {
+	lc.leaseLock.Lock()
+	defer lc.leaseLock.Unlock()
+	lease, ok := lc.leases[taskID]
+	if !ok {
+		return "", 0
+	}
+	return lease.Id, lease.FencingToken
+}
+
+func (lc *leaseCache) forget(taskID string) {
+	lc.leaseLock.Lock()
+	defer lc.leaseLock.Unlock()
+	delete(lc.leases, taskID)
+}
+
+// DEFAULT_COMPRESSION is the codec requests are compressed with unless
+// WithCompression says otherwise
+const DEFAULT_COMPRESSION = codec.GZIP
+
+// options holds the settings of a connection
+type options struct {
+	compression string
+	tls         *tlsconfig.ClientOptions
+	tlsConfig   *tls.Config
+	token       string
+}
+
+// Option changes how a Client connects
+type Option func(*options)
+
+// WithCompression compresses requests with a codec registered by the codec
+// package (gzip, zstd or snappy), or disables compression when empty. The
+// server answers with the same codec.
+func WithCompression(compression string) Option {
+	return func(o *options) {
+		o.compression = compression
+	}
+}
+
+// WithTLS connects over TLS. Setting a client certificate in the options
+// authenticates the client to servers that require mutual TLS; it is
+// reloaded when its files change.
+func WithTLS(tlsOpts tlsconfig.ClientOptions) Option {
+	return func(o *options) {
+		o.tls = &tlsOpts
+	}
+}
+
+// WithTLSConfig connects over TLS with a prepared configuration
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithToken authenticates every call with a bearer token issued by
+// cmd/token. Tokens are only sent over TLS.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// bearerToken sends a token in the authorization metadata
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return true
 }
 
 // NewClient initializes a connection to the gRPC server
-func NewClient(serverAddr string) (*Client, error) {
-	conn, err := grpc.Dial(serverAddr, grpc.WithInsecure(), grpc.WithBlock())
+func NewClient(serverAddr string, opts ...Option) (*Client, error) {
+	o := &options{compression: DEFAULT_COMPRESSION}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := codec.Validate(o.compression); err != nil {
+		return nil, err
+	}
+	creds := insecure.NewCredentials()
+	if o.tls != nil {
+		config, _, err := tlsconfig.ClientConfig(*o.tls)
+		if err != nil {
+			return nil, fmt.Errorf("error loading TLS configuration: %w", err)
+		}
+		creds = credentials.NewTLS(config)
+	} else if o.tlsConfig != nil {
+		creds = credentials.NewTLS(o.tlsConfig)
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+		// Propagates the trace of the caller's context to the server
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithPropagators(tracing.Propagator))),
+		// Sends the request ID set with logging.WithRequestID on the caller's context
+		grpc.WithChainUnaryInterceptor(logging.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(logging.StreamClientInterceptor()),
+	}
+	if o.token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(bearerToken(o.token)))
+	}
+	if o.compression != codec.NONE {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor(o.compression)))
+	}
+
+	conn, err := grpc.Dial(serverAddr, dialOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 
 	client := taskpb.NewTaskServiceClient(conn)
-	return &Client{conn: conn, client: client}, nil
+	return &Client{conn: conn, client: client, ctx: context.Background(), leases: &leaseCache{leases: make(map[string]*taskpb.LeaseTaskResponse)}}, nil
+}
+
+// WithContext returns a client sharing the connection whose calls are made
+// in ctx, so they carry its trace context and are cancelled with it. Each
+// call still has its own timeout.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	scoped := *c
+	scoped.ctx = ctx
+	return &scoped
+}
+
+func (c *Client) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// TaskContext returns ctx carrying the trace a task was created in, so a
+// worker's spans and calls for the task continue the producer's trace:
+//
+//	ctx := client.TaskContext(ctx, t)
+//	c.WithContext(ctx).CompleteTask(t.Id, result)
+func TaskContext(ctx context.Context, t *taskpb.Task) context.Context {
+	return tracing.Extract(ctx, t.GetTraceContext())
 }
 
 // Close closes the gRPC connection
@@ -31,15 +192,34 @@ func (c *Client) Close() {
 	c.conn.Close()
 }
 
-// CreateTask creates a new task and returns the task details
-func (c *Client) CreateTask(name string) (*taskpb.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+// CreateTask creates a new task in the given queue and returns the task details
+func (c *Client) CreateTask(name string, queue string, input []byte) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
 	defer cancel()
 
 	resp, err := c.client.CreateTask(ctx, &taskpb.CreateTaskRequest{
 		Name: name,
 		Description: "task description",
-		Data: []byte("task data"),
+		Data: input,
+		Queue: queue,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating task: %w", err)
+	}
+	return resp.Task, nil
+}
+
+// CreateTaskWithID creates a new task with a caller chosen ID
+func (c *Client) CreateTaskWithID(taskID string, name string, queue string, input []byte) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.CreateTask(ctx, &taskpb.CreateTaskRequest{
+		Id: taskID,
+		Name: name,
+		Description: "task description",
+		Data: input,
+		Queue: queue,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating task: %w", err)
@@ -49,7 +229,7 @@ func (c *Client) CreateTask(name string) (*taskpb.Task, error) {
 
 // GetTask fetches task details by ID
 func (c *Client) GetTask(taskID string) (*taskpb.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
 	defer cancel()
 
 	resp, err := c.client.GetTask(ctx, &taskpb.GetTaskRequest{Id: taskID})
@@ -61,7 +241,7 @@ func (c *Client) GetTask(taskID string) (*taskpb.Task, error) {
 
 // // UpdateTask updates the state of a task
 // func (c *Client) UpdateTask(taskID, state string) (*taskpb.Task, error) {
-// 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+// 	ctx, cancel := context.WithTimeout(c.context(), time.Second)
 // 	defer cancel()
 
 // 	resp, err := c.client.UpdateTask(ctx, &taskpb.UpdateTaskRequest{Id: taskID, State: state})
@@ -71,37 +251,412 @@ func (c *Client) GetTask(taskID string) (*taskpb.Task, error) {
 // 	return resp.Task, nil
 // }
 
-// CompleteTask marks a task as completed
-func (c *Client) CompleteTask(taskID string) (*taskpb.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+// CompleteTask marks a task as completed with the given result. A task
+// leased with LeaseTask is completed through its lease, as with
+// CompleteLeasedTask.
+func (c *Client) CompleteTask(taskID string, result []byte) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
 	defer cancel()
 
-	resp, err := c.client.CompleteTask(ctx, &taskpb.CompleteTaskRequest{Id: taskID})
+	leaseID, fencingToken := c.leases.get(taskID)
+	resp, err := c.client.CompleteTask(ctx, &taskpb.CompleteTaskRequest{
+		Id: taskID,
+		Result: result,
+		LeaseId: leaseID,
+		FencingToken: fencingToken,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error completing task: %w", err)
 	}
+	c.leases.forget(taskID)
 	return resp.Task, nil
 }
 
-// LeaseTask leases a task for processing
+// CompleteLeasedTask completes the task of a lease with the given result.
+// It fails with FailedPrecondition if the lease was lost, for example
+// because it expired and the task was leased to another worker.
+func (c *Client) CompleteLeasedTask(lease *taskpb.LeaseTaskResponse, result []byte) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.CompleteTask(ctx, &taskpb.CompleteTaskRequest{
+		Id: lease.TaskId,
+		Result: result,
+		LeaseId: lease.Id,
+		FencingToken: lease.FencingToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error completing task: %w", err)
+	}
+	c.leases.forget(lease.TaskId)
+	return resp.Task, nil
+}
+
+// FailTask marks a task as failed with the given error. Like CompleteTask
+// it goes through the lease taken with LeaseTask.
+func (c *Client) FailTask(taskID string, code string, message string, details []string) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	leaseID, fencingToken := c.leases.get(taskID)
+	resp, err := c.client.FailTask(ctx, &taskpb.FailTaskRequest{
+		Id: taskID,
+		Error: &taskpb.TaskError{
+			Code: code,
+			Message: message,
+			Details: details,
+		},
+		LeaseId: leaseID,
+		FencingToken: fencingToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error failing task: %w", err)
+	}
+	c.leases.forget(taskID)
+	return resp.Task, nil
+}
+
+// FailLeasedTask fails the task of a lease with the given error. Like
+// CompleteLeasedTask it fails if the lease was lost.
+func (c *Client) FailLeasedTask(lease *taskpb.LeaseTaskResponse, code string, message string, details []string) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.FailTask(ctx, &taskpb.FailTaskRequest{
+		Id: lease.TaskId,
+		Error: &taskpb.TaskError{
+			Code: code,
+			Message: message,
+			Details: details,
+		},
+		LeaseId: lease.Id,
+		FencingToken: lease.FencingToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error failing task: %w", err)
+	}
+	c.leases.forget(lease.TaskId)
+	return resp.Task, nil
+}
+
+// LeaseTask leases a task for processing for leaseDuration seconds, or the
+// server default when 0. The client finishes the task through the lease.
 func (c *Client) LeaseTask(taskID string, leaseDuration int32) (*taskpb.LeaseTaskResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
 	defer cancel()
 
-	resp, err := c.client.LeaseTask(ctx, &taskpb.LeaseTaskRequest{TaskId: taskID, Owner: "owner1"})
+	resp, err := c.client.LeaseTask(ctx, &taskpb.LeaseTaskRequest{TaskId: taskID, Owner: "owner1", LeaseDurationSeconds: leaseDuration})
 	if err != nil {
 		return nil, fmt.Errorf("error leasing task: %w", err)
 	}
+	c.leases.put(resp)
 	return resp, nil
 }
 
-func (c *Client) GetUnLeasdTask() (*taskpb.TaskResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+// GetUnLeasdTask fetches a task ready to be leased from the given queue,
+// or from any queue if queue is empty
+func (c *Client) GetUnLeasdTask(queue string) (*taskpb.TaskResponse, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
 	defer cancel()
 
-	resp, err := c.client.GetUnLeasdTask(ctx, &taskpb.UnLeasedTaskRequest{})
+	resp, err := c.client.GetUnLeasdTask(ctx, &taskpb.UnLeasedTaskRequest{Queue: queue})
 	if err != nil {
 		return nil, fmt.Errorf("error getting unleased task: %w", err)
 	}
 	return resp, nil
-}
\ No newline at end of file
+}
+
+// ReportProgress reports the progress of a leased task and keeps its lease alive
+func (c *Client) ReportProgress(leaseID string, owner string, percent int32, currentStep int64, totalSteps int64, message string) (*taskpb.ReportProgressResponse, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.ReportProgress(ctx, &taskpb.ReportProgressRequest{
+		LeaseId: leaseID,
+		Owner: owner,
+		Percent: percent,
+		CurrentStep: currentStep,
+		TotalSteps: totalSteps,
+		Message: message,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reporting progress: %w", err)
+	}
+	return resp, nil
+}
+
+// CancelTask requests cancellation of a task
+func (c *Client) CancelTask(taskID string) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.CancelTask(ctx, &taskpb.CancelTaskRequest{Id: taskID})
+	if err != nil {
+		return nil, fmt.Errorf("error cancelling task: %w", err)
+	}
+	return resp.Task, nil
+}
+
+// AcknowledgeCancel tells the server the worker has stopped a cancelled task
+func (c *Client) AcknowledgeCancel(leaseID string, owner string) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.AcknowledgeCancel(ctx, &taskpb.AcknowledgeCancelRequest{LeaseId: leaseID, Owner: owner})
+	if err != nil {
+		return nil, fmt.Errorf("error acknowledging cancel: %w", err)
+	}
+	return resp.Task, nil
+}
+
+// PauseTask stops a task from being handed out
+func (c *Client) PauseTask(taskID string) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.PauseTask(ctx, &taskpb.PauseTaskRequest{Id: taskID})
+	if err != nil {
+		return nil, fmt.Errorf("error pausing task: %w", err)
+	}
+	return resp.Task, nil
+}
+
+// ResumeTask makes a paused task available again
+func (c *Client) ResumeTask(taskID string) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.ResumeTask(ctx, &taskpb.ResumeTaskRequest{Id: taskID})
+	if err != nil {
+		return nil, fmt.Errorf("error resuming task: %w", err)
+	}
+	return resp.Task, nil
+}
+
+// PauseQueue stops all tasks in a queue from being handed out
+func (c *Client) PauseQueue(queue string) (*taskpb.Queue, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.PauseQueue(ctx, &taskpb.PauseQueueRequest{Queue: queue})
+	if err != nil {
+		return nil, fmt.Errorf("error pausing queue: %w", err)
+	}
+	return resp.Queue, nil
+}
+
+// ResumeQueue lets tasks in a paused queue be handed out again
+func (c *Client) ResumeQueue(queue string) (*taskpb.Queue, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.ResumeQueue(ctx, &taskpb.ResumeQueueRequest{Queue: queue})
+	if err != nil {
+		return nil, fmt.Errorf("error resuming queue: %w", err)
+	}
+	return resp.Queue, nil
+}
+
+// SetRetentionPolicy sets how long tasks in a state are kept in a queue.
+// A maxAge of zero keeps them forever.
+func (c *Client) SetRetentionPolicy(queue string, state string, maxAge time.Duration, archive bool) (*taskpb.Queue, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.SetRetentionPolicy(ctx, &taskpb.SetRetentionPolicyRequest{
+		Queue: queue,
+		Policy: &taskpb.RetentionPolicy{
+			State: state,
+			MaxAgeSeconds: int64(maxAge.Seconds()),
+			Archive: archive,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error setting retention policy: %w", err)
+	}
+	return resp.Queue, nil
+}
+
+// SetQueueCompression sets the codec task payloads in a queue are stored
+// with: gzip, zstd, snappy, or "" for none
+func (c *Client) SetQueueCompression(queue string, compression string) (*taskpb.Queue, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.SetQueueCompression(ctx, &taskpb.SetQueueCompressionRequest{Queue: queue, Compression: compression})
+	if err != nil {
+		return nil, fmt.Errorf("error setting queue compression: %w", err)
+	}
+	return resp.Queue, nil
+}
+
+// SearchArchive searches archived tasks
+func (c *Client) SearchArchive(req *taskpb.SearchArchiveRequest) ([]*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := c.client.SearchArchive(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("error searching archive: %w", err)
+	}
+	return resp.Tasks, nil
+}
+
+// GetShardMap fetches the shard map published to the server
+func (c *Client) GetShardMap() (*taskpb.ShardMap, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.GetShardMap(ctx, &taskpb.GetShardMapRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting shard map: %w", err)
+	}
+	return resp.ShardMap, nil
+}
+
+// SetShardMap publishes a shard map to the server
+func (c *Client) SetShardMap(shardMap *taskpb.ShardMap) (*taskpb.ShardMap, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.SetShardMap(ctx, &taskpb.SetShardMapRequest{ShardMap: shardMap})
+	if err != nil {
+		return nil, fmt.Errorf("error setting shard map: %w", err)
+	}
+	return resp.ShardMap, nil
+}
+
+// ExportSlot copies the tasks in a hash slot out of the server
+func (c *Client) ExportSlot(slot int32, slots int32) ([]*taskpb.ExportedTask, error) {
+	ctx, cancel := context.WithTimeout(c.context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := c.client.ExportSlot(ctx, &taskpb.ExportSlotRequest{Slot: slot, Slots: slots})
+	if err != nil {
+		return nil, fmt.Errorf("error exporting slot: %w", err)
+	}
+	return resp.Tasks, nil
+}
+
+// ImportTasks copies exported tasks into the server
+func (c *Client) ImportTasks(tasks []*taskpb.ExportedTask) (int32, error) {
+	ctx, cancel := context.WithTimeout(c.context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := c.client.ImportTasks(ctx, &taskpb.ImportTasksRequest{Tasks: tasks})
+	if err != nil {
+		return 0, fmt.Errorf("error importing tasks: %w", err)
+	}
+	return resp.Imported, nil
+}
+
+// DropTasks deletes exported tasks that have not changed since their export,
+// returning the IDs of the tasks that were dropped
+func (c *Client) DropTasks(tasks []*taskpb.TaskVersion) ([]string, error) {
+	ctx, cancel := context.WithTimeout(c.context(), 30*time.Second)
+	defer cancel()
+
+	resp, err := c.client.DropTasks(ctx, &taskpb.DropTasksRequest{Tasks: tasks})
+	if err != nil {
+		return nil, fmt.Errorf("error dropping tasks: %w", err)
+	}
+	return resp.Dropped, nil
+}
+
+// ListTasks lists tasks in creation order, optionally filtered by queue and
+// state. Standbys serve it from their copy of the primary's state.
+func (c *Client) ListTasks(queue string, state string, limit int32) ([]*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := c.client.ListTasks(ctx, &taskpb.ListTasksRequest{Queue: queue, TaskState: state, Limit: limit})
+	if err != nil {
+		return nil, fmt.Errorf("error listing tasks: %w", err)
+	}
+	return resp.Tasks, nil
+}
+
+// Promote turns the standby the client is connected to into the primary
+func (c *Client) Promote() (*taskpb.ReplicationStatus, error) {
+	ctx, cancel := context.WithTimeout(c.context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := taskpb.NewStandbyServiceClient(c.conn).Promote(ctx, &taskpb.PromoteRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error promoting standby: %w", err)
+	}
+	return resp, nil
+}
+
+// GetReplicationStatus returns the log shipping role, epoch and position of the server
+func (c *Client) GetReplicationStatus() (*taskpb.ReplicationStatus, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := taskpb.NewStandbyServiceClient(c.conn).GetReplicationStatus(ctx, &taskpb.GetReplicationStatusRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting replication status: %w", err)
+	}
+	return resp, nil
+}
+
+// CreateSnapshot takes a point-in-time snapshot of the server's database
+func (c *Client) CreateSnapshot() (*taskpb.SnapshotInfo, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Minute)
+	defer cancel()
+
+	resp, err := taskpb.NewSnapshotServiceClient(c.conn).CreateSnapshot(ctx, &taskpb.CreateSnapshotRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshot: %w", err)
+	}
+	return resp, nil
+}
+
+// ListSnapshots lists the snapshots kept by the server, oldest first
+func (c *Client) ListSnapshots() ([]*taskpb.SnapshotInfo, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Minute)
+	defer cancel()
+
+	resp, err := taskpb.NewSnapshotServiceClient(c.conn).ListSnapshots(ctx, &taskpb.ListSnapshotsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing snapshots: %w", err)
+	}
+	return resp.Snapshots, nil
+}
+
+// RestoreSnapshot replaces the server's database with a snapshot
+func (c *Client) RestoreSnapshot(id string) (*taskpb.SnapshotInfo, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Minute)
+	defer cancel()
+
+	resp, err := taskpb.NewSnapshotServiceClient(c.conn).RestoreSnapshot(ctx, &taskpb.RestoreSnapshotRequest{Id: id})
+	if err != nil {
+		return nil, fmt.Errorf("error restoring snapshot: %w", err)
+	}
+	return resp, nil
+}
+
+// Drain stops the server from granting leases, or resumes granting them.
+// Leases it granted can still report progress, complete and fail.
+func (c *Client) Drain(resume bool) (*taskpb.DrainStatus, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := taskpb.NewAdminServiceClient(c.conn).Drain(ctx, &taskpb.DrainRequest{Resume: resume})
+	if err != nil {
+		return nil, fmt.Errorf("error draining server: %w", err)
+	}
+	return resp, nil
+}
+
+// GetDrainStatus reports whether the server is draining and how many leases are active
+func (c *Client) GetDrainStatus() (*taskpb.DrainStatus, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := taskpb.NewAdminServiceClient(c.conn).GetDrainStatus(ctx, &taskpb.GetDrainStatusRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting drain status: %w", err)
+	}
+	return resp, nil
+}
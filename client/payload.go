@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+)
+
+// PAYLOAD_CHUNK_SIZE is how much of a payload is sent per message
+const PAYLOAD_CHUNK_SIZE = 1 << 20
+
+// PAYLOAD_TIMEOUT bounds a whole upload or download
+const PAYLOAD_TIMEOUT = 10 * time.Minute
+
+// UploadPayload streams a payload into the server's blob store and returns
+// its reference, to be passed to CreateTaskWithInputBlob or
+// CompleteTaskWithResultBlob
+func (c *Client) UploadPayload(r io.Reader) (*taskpb.PayloadRef, error) {
+	ctx, cancel := context.WithTimeout(c.context(), PAYLOAD_TIMEOUT)
+	defer cancel()
+
+	stream, err := c.client.UploadPayload(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error uploading payload: %w", err)
+	}
+	buf := make([]byte, PAYLOAD_CHUNK_SIZE)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := stream.Send(&taskpb.PayloadChunk{Data: buf[:n]}); err != nil {
+				// The server's error is returned by CloseAndRecv
+				break
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading payload: %w", err)
+		}
+	}
+	ref, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, fmt.Errorf("error uploading payload: %w", err)
+	}
+	return ref, nil
+}
+
+// DownloadPayload streams a payload from the blob store into w and returns
+// the number of bytes written
+func (c *Client) DownloadPayload(digest string, w io.Writer) (int64, error) {
+	ctx, cancel := context.WithTimeout(c.context(), PAYLOAD_TIMEOUT)
+	defer cancel()
+
+	stream, err := c.client.DownloadPayload(ctx, &taskpb.DownloadPayloadRequest{Digest: digest})
+	if err != nil {
+		return 0, fmt.Errorf("error downloading payload: %w", err)
+	}
+	var written int64
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, fmt.Errorf("error downloading payload: %w", err)
+		}
+		n, err := w.Write(chunk.Data)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("error writing payload: %w", err)
+		}
+	}
+}
+
+// CreateTaskWithInputBlob creates a task whose input was uploaded with
+// UploadPayload. taskID may be empty to let the server choose it.
+func (c *Client) CreateTaskWithInputBlob(taskID string, name string, queue string, digest string) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	resp, err := c.client.CreateTask(ctx, &taskpb.CreateTaskRequest{
+		Id:          taskID,
+		Name:        name,
+		Description: "task description",
+		Queue:       queue,
+		InputBlob:   digest,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating task: %w", err)
+	}
+	return resp.Task, nil
+}
+
+// CompleteTaskWithResultBlob completes a task with a result uploaded with UploadPayload
+func (c *Client) CompleteTaskWithResultBlob(taskID string, digest string) (*taskpb.Task, error) {
+	ctx, cancel := context.WithTimeout(c.context(), time.Second)
+	defer cancel()
+
+	leaseID, fencingToken := c.leases.get(taskID)
+	resp, err := c.client.CompleteTask(ctx, &taskpb.CompleteTaskRequest{
+		Id:           taskID,
+		ResultBlob:   digest,
+		LeaseId:      leaseID,
+		FencingToken: fencingToken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error completing task: %w", err)
+	}
+	c.leases.forget(taskID)
+	return resp.Task, nil
+}
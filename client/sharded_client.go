@@ -0,0 +1,628 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"github.com/indkumar8999/ps-tasks/shards"
+)
+
+// MAX_MIGRATION_ROUNDS bounds how many times a slot is re-copied while its
+// tasks keep changing on the old shard
+const MAX_MIGRATION_ROUNDS = 10
+
+// ShardedClient routes calls to the TaskService instance owning each task.
+// Tasks are placed by the hash of their ID, so the client picks the ID when
+// creating a task. Calls that are not tied to a task go to every shard.
+type ShardedClient struct {
+	shardMap *shards.ShardMap
+	clients  map[string]*Client
+	// leaseShards remembers which shard granted each lease
+	leaseShards map[string]string
+	next        uint32
+	// opts are used for the connection to every shard
+	opts      []Option
+	shardLock *sync.Mutex
+}
+
+// NewShardedClient connects to a shard and fetches the shard map from it
+func NewShardedClient(seedAddr string, opts ...Option) (*ShardedClient, error) {
+	seed, err := NewClient(seedAddr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer seed.Close()
+
+	shardMapProto, err := seed.GetShardMap()
+	if err != nil {
+		return nil, err
+	}
+	sc := newShardedClient(opts)
+	sc.shardMap = shards.FromProto(shardMapProto)
+	return sc, nil
+}
+
+// InitShards spreads the slots over the given shards, publishes the new shard
+// map to each of them and returns a client using it
+func InitShards(shardList []shards.Shard, slots int, opts ...Option) (*ShardedClient, error) {
+	shardMap, err := shards.NewShardMap(shardList, slots)
+	if err != nil {
+		return nil, err
+	}
+	sc := newShardedClient(opts)
+	sc.shardMap = shardMap
+	if err := sc.publish(shardMap); err != nil {
+		sc.Close()
+		return nil, err
+	}
+	return sc, nil
+}
+
+func newShardedClient(opts []Option) *ShardedClient {
+	return &ShardedClient{
+		clients:     make(map[string]*Client),
+		leaseShards: make(map[string]string),
+		opts:        opts,
+		shardLock:   &sync.Mutex{},
+	}
+}
+
+// Close closes the connections to all shards
+func (sc *ShardedClient) Close() {
+	sc.shardLock.Lock()
+	defer sc.shardLock.Unlock()
+
+	for id, c := range sc.clients {
+		c.Close()
+		delete(sc.clients, id)
+	}
+}
+
+// ShardMap returns the shard map the client is routing with
+func (sc *ShardedClient) ShardMap() *shards.ShardMap {
+	sc.shardLock.Lock()
+	defer sc.shardLock.Unlock()
+	return sc.shardMap
+}
+
+// RefreshShardMap fetches the newest shard map known to any shard
+func (sc *ShardedClient) RefreshShardMap() error {
+	var latest *shards.ShardMap
+	var lastErr error
+	for _, shard := range sc.ShardMap().Shards {
+		c, err := sc.clientFor(shard)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		shardMapProto, err := c.GetShardMap()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if latest == nil || shardMapProto.Version > latest.Version {
+			latest = shards.FromProto(shardMapProto)
+		}
+	}
+	if latest == nil {
+		return fmt.Errorf("error refreshing shard map: %w", lastErr)
+	}
+
+	sc.shardLock.Lock()
+	defer sc.shardLock.Unlock()
+	if latest.Version >= sc.shardMap.Version {
+		sc.shardMap = latest
+	}
+	return nil
+}
+
+// clientFor returns the connection to a shard, dialing it on first use
+func (sc *ShardedClient) clientFor(shard shards.Shard) (*Client, error) {
+	sc.shardLock.Lock()
+	defer sc.shardLock.Unlock()
+
+	if c, ok := sc.clients[shard.ID]; ok {
+		return c, nil
+	}
+	c, err := NewClient(shard.Addr, sc.opts...)
+	if err != nil {
+		return nil, fmt.Errorf("shard %s: %w", shard.ID, err)
+	}
+	sc.clients[shard.ID] = c
+	return c, nil
+}
+
+// withTask calls fn on the shard owning a task. While the task's slot is
+// being migrated the task may still be on the previous owner, which is
+// tried when the new owner fails.
+func (sc *ShardedClient) withTask(taskID string, fn func(shardID string, c *Client) error) error {
+	shardMap := sc.ShardMap()
+	owner, err := shardMap.Owner(taskID)
+	if err != nil {
+		return err
+	}
+	c, err := sc.clientFor(owner)
+	if err == nil {
+		if err = fn(owner.ID, c); err == nil {
+			return nil
+		}
+	}
+	previous, migrating := shardMap.PreviousOwner(taskID)
+	if !migrating {
+		return err
+	}
+	pc, perr := sc.clientFor(previous)
+	if perr != nil {
+		return err
+	}
+	if perr := fn(previous.ID, pc); perr != nil {
+		return err
+	}
+	return nil
+}
+
+// withLease calls fn on the shard that granted a lease. Leases granted by
+// another client are looked for on every shard.
+func (sc *ShardedClient) withLease(leaseID string, fn func(c *Client) error) error {
+	sc.shardLock.Lock()
+	shardID, known := sc.leaseShards[leaseID]
+	sc.shardLock.Unlock()
+
+	shardMap := sc.ShardMap()
+	if known {
+		if shard, err := shardMap.Shard(shardID); err == nil {
+			c, err := sc.clientFor(shard)
+			if err == nil {
+				if err = fn(c); err == nil {
+					return nil
+				}
+			}
+		}
+	}
+
+	var lastErr error = fmt.Errorf("lease %s not found", leaseID)
+	for _, shard := range shardMap.Shards {
+		c, err := sc.clientFor(shard)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := fn(c); err != nil {
+			lastErr = err
+			continue
+		}
+		sc.rememberLease(leaseID, shard.ID)
+		return nil
+	}
+	return lastErr
+}
+
+func (sc *ShardedClient) rememberLease(leaseID string, shardID string) {
+	sc.shardLock.Lock()
+	defer sc.shardLock.Unlock()
+	sc.leaseShards[leaseID] = shardID
+}
+
+func (sc *ShardedClient) forgetLease(leaseID string) {
+	sc.shardLock.Lock()
+	defer sc.shardLock.Unlock()
+	delete(sc.leaseShards, leaseID)
+}
+
+// broadcast calls fn on every shard and returns the first error
+func (sc *ShardedClient) broadcast(fn func(c *Client) error) error {
+	for _, shard := range sc.ShardMap().Shards {
+		c, err := sc.clientFor(shard)
+		if err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return fmt.Errorf("shard %s: %w", shard.ID, err)
+		}
+	}
+	return nil
+}
+
+// CreateTask creates a new task on the shard owning its ID
+func (sc *ShardedClient) CreateTask(name string, queue string, input []byte) (*taskpb.Task, error) {
+	taskID := uuid.New().String()
+	owner, err := sc.ShardMap().Owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := sc.clientFor(owner)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateTaskWithID(taskID, name, queue, input)
+}
+
+// CreateTaskWithPayload uploads a large input to the shard owning the new
+// task and creates the task there
+func (sc *ShardedClient) CreateTaskWithPayload(name string, queue string, input io.Reader) (*taskpb.Task, error) {
+	taskID := uuid.New().String()
+	owner, err := sc.ShardMap().Owner(taskID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := sc.clientFor(owner)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := c.UploadPayload(input)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateTaskWithInputBlob(taskID, name, queue, ref.Digest)
+}
+
+// DownloadPayload streams a payload of a task from the shard holding it.
+// The previous owner is only tried if the new one fails before writing.
+func (sc *ShardedClient) DownloadPayload(taskID string, digest string, w io.Writer) (int64, error) {
+	var written int64
+	err := sc.withTask(taskID, func(shardID string, c *Client) error {
+		if written > 0 {
+			return fmt.Errorf("download interrupted after %d bytes", written)
+		}
+		n, err := c.DownloadPayload(digest, w)
+		written += n
+		return err
+	})
+	return written, err
+}
+
+// GetTask fetches task details by ID
+func (sc *ShardedClient) GetTask(taskID string) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withTask(taskID, func(shardID string, c *Client) (err error) {
+		t, err = c.GetTask(taskID)
+		return err
+	})
+	return t, err
+}
+
+// CompleteTask marks a task as completed with the given result
+func (sc *ShardedClient) CompleteTask(taskID string, result []byte) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withTask(taskID, func(shardID string, c *Client) (err error) {
+		t, err = c.CompleteTask(taskID, result)
+		return err
+	})
+	return t, err
+}
+
+// FailTask marks a task as failed with the given error
+func (sc *ShardedClient) FailTask(taskID string, code string, message string, details []string) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withTask(taskID, func(shardID string, c *Client) (err error) {
+		t, err = c.FailTask(taskID, code, message, details)
+		return err
+	})
+	return t, err
+}
+
+// CompleteLeasedTask completes the task of a lease with the given result
+func (sc *ShardedClient) CompleteLeasedTask(lease *taskpb.LeaseTaskResponse, result []byte) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withTask(lease.TaskId, func(shardID string, c *Client) (err error) {
+		t, err = c.CompleteLeasedTask(lease, result)
+		return err
+	})
+	return t, err
+}
+
+// FailLeasedTask fails the task of a lease with the given error
+func (sc *ShardedClient) FailLeasedTask(lease *taskpb.LeaseTaskResponse, code string, message string, details []string) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withTask(lease.TaskId, func(shardID string, c *Client) (err error) {
+		t, err = c.FailLeasedTask(lease, code, message, details)
+		return err
+	})
+	return t, err
+}
+
+// LeaseTask leases a task for processing
+func (sc *ShardedClient) LeaseTask(taskID string, leaseDuration int32) (*taskpb.LeaseTaskResponse, error) {
+	var resp *taskpb.LeaseTaskResponse
+	err := sc.withTask(taskID, func(shardID string, c *Client) (err error) {
+		resp, err = c.LeaseTask(taskID, leaseDuration)
+		if err == nil {
+			sc.rememberLease(resp.Id, shardID)
+		}
+		return err
+	})
+	return resp, err
+}
+
+// GetUnLeasdTask fetches a task ready to be leased from the given queue, or
+// from any queue if queue is empty. Shards are tried in turn, starting from
+// a different shard on every call so that no shard is drained first.
+func (sc *ShardedClient) GetUnLeasdTask(queue string) (*taskpb.TaskResponse, error) {
+	shardList := sc.ShardMap().Shards
+	start := int(atomic.AddUint32(&sc.next, 1))
+
+	var lastErr error = fmt.Errorf("no shards")
+	for i := range shardList {
+		shard := shardList[(start+i)%len(shardList)]
+		c, err := sc.clientFor(shard)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp, err := c.GetUnLeasdTask(queue)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// ReportProgress reports the progress of a leased task and keeps its lease alive
+func (sc *ShardedClient) ReportProgress(leaseID string, owner string, percent int32, currentStep int64, totalSteps int64, message string) (*taskpb.ReportProgressResponse, error) {
+	var resp *taskpb.ReportProgressResponse
+	err := sc.withLease(leaseID, func(c *Client) (err error) {
+		resp, err = c.ReportProgress(leaseID, owner, percent, currentStep, totalSteps, message)
+		return err
+	})
+	return resp, err
+}
+
+// CancelTask requests cancellation of a task
+func (sc *ShardedClient) CancelTask(taskID string) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withTask(taskID, func(shardID string, c *Client) (err error) {
+		t, err = c.CancelTask(taskID)
+		return err
+	})
+	return t, err
+}
+
+// AcknowledgeCancel tells the server the worker has stopped a cancelled task
+func (sc *ShardedClient) AcknowledgeCancel(leaseID string, owner string) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withLease(leaseID, func(c *Client) (err error) {
+		t, err = c.AcknowledgeCancel(leaseID, owner)
+		return err
+	})
+	if err == nil {
+		sc.forgetLease(leaseID)
+	}
+	return t, err
+}
+
+// PauseTask stops a task from being handed out
+func (sc *ShardedClient) PauseTask(taskID string) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withTask(taskID, func(shardID string, c *Client) (err error) {
+		t, err = c.PauseTask(taskID)
+		return err
+	})
+	return t, err
+}
+
+// ResumeTask makes a paused task available again
+func (sc *ShardedClient) ResumeTask(taskID string) (*taskpb.Task, error) {
+	var t *taskpb.Task
+	err := sc.withTask(taskID, func(shardID string, c *Client) (err error) {
+		t, err = c.ResumeTask(taskID)
+		return err
+	})
+	return t, err
+}
+
+// PauseQueue pauses a queue on every shard
+func (sc *ShardedClient) PauseQueue(queue string) (*taskpb.Queue, error) {
+	var q *taskpb.Queue
+	err := sc.broadcast(func(c *Client) (err error) {
+		q, err = c.PauseQueue(queue)
+		return err
+	})
+	return q, err
+}
+
+// ResumeQueue resumes a queue on every shard
+func (sc *ShardedClient) ResumeQueue(queue string) (*taskpb.Queue, error) {
+	var q *taskpb.Queue
+	err := sc.broadcast(func(c *Client) (err error) {
+		q, err = c.ResumeQueue(queue)
+		return err
+	})
+	return q, err
+}
+
+// SetRetentionPolicy sets a queue's retention policy on every shard
+func (sc *ShardedClient) SetRetentionPolicy(queue string, state string, maxAge time.Duration, archive bool) (*taskpb.Queue, error) {
+	var q *taskpb.Queue
+	err := sc.broadcast(func(c *Client) (err error) {
+		q, err = c.SetRetentionPolicy(queue, state, maxAge, archive)
+		return err
+	})
+	return q, err
+}
+
+// SetQueueCompression sets the payload codec of a queue on every shard
+func (sc *ShardedClient) SetQueueCompression(queue string, compression string) (*taskpb.Queue, error) {
+	var q *taskpb.Queue
+	err := sc.broadcast(func(c *Client) (err error) {
+		q, err = c.SetQueueCompression(queue, compression)
+		return err
+	})
+	return q, err
+}
+
+// SearchArchive searches the archives of every shard
+func (sc *ShardedClient) SearchArchive(req *taskpb.SearchArchiveRequest) ([]*taskpb.Task, error) {
+	var tasks []*taskpb.Task
+	err := sc.broadcast(func(c *Client) error {
+		found, err := c.SearchArchive(req)
+		tasks = append(tasks, found...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if req.Limit > 0 && len(tasks) > int(req.Limit) {
+		tasks = tasks[:req.Limit]
+	}
+	return tasks, nil
+}
+
+// publish sends a shard map to every shard it names
+func (sc *ShardedClient) publish(shardMap *shards.ShardMap) error {
+	for _, shard := range shardMap.Shards {
+		c, err := sc.clientFor(shard)
+		if err != nil {
+			return err
+		}
+		if _, err := c.SetShardMap(shardMap.ToProto()); err != nil {
+			return fmt.Errorf("shard %s: %w", shard.ID, err)
+		}
+	}
+	return nil
+}
+
+// Rebalance spreads the slots over a new set of shards while they keep
+// serving. The target map is published first with the moving slots marked
+// as migrating, so clients send new tasks to the new owners and fall back to
+// the old owners for tasks not yet copied. Each slot is then copied over and
+// removed from its old owner, and the final map is published.
+func (sc *ShardedClient) Rebalance(target []shards.Shard) error {
+	if err := sc.RefreshShardMap(); err != nil {
+		return err
+	}
+	current := sc.ShardMap()
+	next, moves, err := current.Rebalance(target)
+	if err != nil {
+		return err
+	}
+
+	migrating := next.Migrating(current, moves)
+	if err := sc.publish(migrating); err != nil {
+		return fmt.Errorf("error publishing migrating shard map: %w", err)
+	}
+	sc.shardLock.Lock()
+	sc.shardMap = migrating
+	sc.shardLock.Unlock()
+
+	for _, move := range moves {
+		if err := sc.moveSlot(migrating, move); err != nil {
+			return fmt.Errorf("error moving slot %d: %w", move.Slot, err)
+		}
+	}
+
+	final := *next
+	final.Version = migrating.Version + 1
+	// Removed shards also get the final map so stale clients can refresh from them
+	final.Shards = migrating.Shards
+	if err := sc.publish(&final); err != nil {
+		return fmt.Errorf("error publishing shard map: %w", err)
+	}
+	final.Shards = next.Shards
+
+	sc.shardLock.Lock()
+	sc.shardMap = &final
+	sc.shardLock.Unlock()
+	return nil
+}
+
+// moveSlot copies a slot's tasks to its new owner and drops them from the old
+// one, repeating for tasks that changed while they were being copied
+func (sc *ShardedClient) moveSlot(shardMap *shards.ShardMap, move shards.Migration) error {
+	fromShard, err := shardMap.Shard(move.From)
+	if err != nil {
+		return err
+	}
+	toShard, err := shardMap.Shard(move.To)
+	if err != nil {
+		return err
+	}
+	from, err := sc.clientFor(fromShard)
+	if err != nil {
+		return err
+	}
+	to, err := sc.clientFor(toShard)
+	if err != nil {
+		return err
+	}
+
+	for round := 0; round < MAX_MIGRATION_ROUNDS; round++ {
+		exported, err := from.ExportSlot(int32(move.Slot), int32(shardMap.Slots))
+		if err != nil {
+			return err
+		}
+		if len(exported) == 0 {
+			return nil
+		}
+		if err := copyBlobs(from, to, exported); err != nil {
+			return err
+		}
+		if _, err := to.ImportTasks(exported); err != nil {
+			return err
+		}
+
+		versions := make([]*taskpb.TaskVersion, 0, len(exported))
+		for _, e := range exported {
+			id, err := exportedTaskID(e)
+			if err != nil {
+				return err
+			}
+			versions = append(versions, &taskpb.TaskVersion{Id: id, Version: e.Version})
+		}
+		if _, err := from.DropTasks(versions); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("tasks still changing after %d rounds", MAX_MIGRATION_ROUNDS)
+}
+
+// copyBlobs streams the payloads exported tasks keep in the blob store to
+// the shard importing them
+func copyBlobs(from *Client, to *Client, exported []*taskpb.ExportedTask) error {
+	for _, e := range exported {
+		var record struct {
+			InputBlob  *struct{ Digest string } `json:"input_blob"`
+			ResultBlob *struct{ Digest string } `json:"result_blob"`
+		}
+		if err := json.Unmarshal(e.Task, &record); err != nil {
+			return fmt.Errorf("error decoding exported task: %w", err)
+		}
+		for _, ref := range []*struct{ Digest string }{record.InputBlob, record.ResultBlob} {
+			if ref == nil {
+				continue
+			}
+			reader, writer := io.Pipe()
+			go func() {
+				_, err := from.DownloadPayload(ref.Digest, writer)
+				writer.CloseWithError(err)
+			}()
+			copied, err := to.UploadPayload(reader)
+			reader.Close()
+			if err != nil {
+				return err
+			}
+			if copied.Digest != ref.Digest {
+				return fmt.Errorf("blob %s was copied as %s", ref.Digest, copied.Digest)
+			}
+		}
+	}
+	return nil
+}
+
+// exportedTaskID reads the task ID out of an exported task record
+func exportedTaskID(e *taskpb.ExportedTask) (string, error) {
+	var record struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(e.Task, &record); err != nil {
+		return "", fmt.Errorf("error decoding exported task: %w", err)
+	}
+	return record.ID, nil
+}
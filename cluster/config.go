@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// DEFAULT_APPLY_TIMEOUT bounds how long a proposal waits to be committed
+const DEFAULT_APPLY_TIMEOUT = 10 * time.Second
+
+// Peer is a member of the cluster
+type Peer struct {
+	// ID is the raft server ID of the node
+	ID string
+	// RaftAddr is where the node's raft transport listens
+	RaftAddr string
+	// RPCAddr is where the node serves gRPC, used to forward writes to the leader
+	RPCAddr string
+}
+
+// Config configures a cluster node
+type Config struct {
+	NodeID   string
+	RaftAddr string
+	// DataDir holds the raft log and snapshots
+	DataDir string
+	// Peers lists every voting member of the cluster, including this node
+	Peers []Peer
+	// Bootstrap forms the cluster from Peers if no raft state exists yet.
+	// It only needs to be set on one node.
+	Bootstrap bool
+	// ApplyTimeout defaults to DEFAULT_APPLY_TIMEOUT
+	ApplyTimeout time.Duration
+	// ServerTLS and ClientTLS secure the default raft transport and write
+	// forwarding. ServerTLS accepts connections from peers and ClientTLS
+	// makes them; both are needed for the raft transport to use TLS.
+	ServerTLS *tls.Config
+	ClientTLS *tls.Config
+
+	// The fields below default to a TCP transport, a bolt log store, file
+	// snapshots and gRPC forwarding. In-process clusters can replace them
+	// with raft's in-memory implementations and an InmemForwarder.
+	Transport     raft.Transport
+	LogStore      raft.LogStore
+	StableStore   raft.StableStore
+	SnapshotStore raft.SnapshotStore
+	Forwarder     Forwarder
+}
+
+// ParsePeers parses a comma separated list of id=raftAddr=rpcAddr entries
+func ParsePeers(value string) ([]Peer, error) {
+	var peers []Peer
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "=")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid peer %q, expected id=raftAddr=rpcAddr", entry)
+		}
+		peers = append(peers, Peer{ID: parts[0], RaftAddr: parts[1], RPCAddr: parts[2]})
+	}
+	return peers, nil
+}
@@ -0,0 +1,163 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Forwarder sends a command proposed on a follower to the leader, and
+// fetches blobs stored on other nodes
+type Forwarder interface {
+	Forward(leader Peer, cmd *managers.Command) (*managers.CommandResult, error)
+	FetchBlob(peer Peer, digest string) (io.ReadCloser, error)
+}
+
+// GrpcForwarder forwards commands to the leader's ClusterService
+type GrpcForwarder struct {
+	creds     credentials.TransportCredentials
+	conns     map[string]*grpc.ClientConn
+	connsLock *sync.Mutex
+}
+
+// NewGrpcForwarder creates a new GrpcForwarder. Connections use TLS when
+// tlsConfig is not nil.
+func NewGrpcForwarder(tlsConfig *tls.Config) *GrpcForwarder {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	return &GrpcForwarder{
+		creds:     creds,
+		conns:     make(map[string]*grpc.ClientConn),
+		connsLock: &sync.Mutex{},
+	}
+}
+
+// Forward implements Forwarder
+func (f *GrpcForwarder) Forward(leader Peer, cmd *managers.Command) (*managers.CommandResult, error) {
+	conn, err := f.conn(leader.RPCAddr)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode command: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_APPLY_TIMEOUT)
+	defer cancel()
+	resp, err := taskpb.NewClusterServiceClient(conn).Propose(ctx, &taskpb.ProposeRequest{Command: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to forward command to %s: %v", leader.ID, err)
+	}
+
+	var result managers.CommandResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %v", err)
+	}
+	return &result, nil
+}
+
+// FetchBlob implements Forwarder
+func (f *GrpcForwarder) FetchBlob(peer Peer, digest string) (io.ReadCloser, error) {
+	conn, err := f.conn(peer.RPCAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), blobs.FETCH_TIMEOUT)
+	stream, err := taskpb.NewClusterServiceClient(conn).FetchBlob(ctx, &taskpb.FetchBlobRequest{Digest: digest})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to fetch blob from %s: %v", peer.ID, err)
+	}
+	// The first chunk tells whether the peer holds the blob
+	first, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		cancel()
+		return nil, fmt.Errorf("failed to fetch blob from %s: %v", peer.ID, err)
+	}
+	recv := func() ([]byte, error) {
+		chunk, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		return chunk.Data, nil
+	}
+	return blobs.NewChunkReader(first.GetData(), recv, cancel), nil
+}
+
+func (f *GrpcForwarder) conn(addr string) (*grpc.ClientConn, error) {
+	f.connsLock.Lock()
+	defer f.connsLock.Unlock()
+
+	if conn, exists := f.conns[addr]; exists {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(f.creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+	f.conns[addr] = conn
+	return conn, nil
+}
+
+// InmemForwarder forwards commands directly to nodes in the same process,
+// for clusters built on raft's in-memory transport
+type InmemForwarder struct {
+	nodes     map[string]*Node
+	nodesLock *sync.Mutex
+}
+
+// NewInmemForwarder creates a new InmemForwarder
+func NewInmemForwarder() *InmemForwarder {
+	return &InmemForwarder{
+		nodes:     make(map[string]*Node),
+		nodesLock: &sync.Mutex{},
+	}
+}
+
+// Register makes a node reachable through the forwarder
+func (f *InmemForwarder) Register(node *Node) {
+	f.nodesLock.Lock()
+	defer f.nodesLock.Unlock()
+	f.nodes[node.ID()] = node
+}
+
+// Forward implements Forwarder
+func (f *InmemForwarder) Forward(leader Peer, cmd *managers.Command) (*managers.CommandResult, error) {
+	f.nodesLock.Lock()
+	node, exists := f.nodes[leader.ID]
+	f.nodesLock.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown node %s", leader.ID)
+	}
+	return node.ProposeAsLeader(cmd)
+}
+
+// FetchBlob implements Forwarder
+func (f *InmemForwarder) FetchBlob(peer Peer, digest string) (io.ReadCloser, error) {
+	f.nodesLock.Lock()
+	node, exists := f.nodes[peer.ID]
+	f.nodesLock.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown node %s", peer.ID)
+	}
+	blobStore := node.fsm.taskManager.Blobs()
+	if blobStore == nil {
+		return nil, fmt.Errorf("blob storage is not enabled on node %s", peer.ID)
+	}
+	reader, _, err := blobStore.Open(digest)
+	return reader, err
+}
@@ -0,0 +1,140 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/raft"
+	"github.com/indkumar8999/ps-tasks/managers"
+)
+
+// fsm applies committed commands to the TaskManager. The task, lease and
+// queue files on disk are the state machine, so the index of the last applied
+// entry is persisted alongside them and entries at or below it are skipped
+// when raft replays its log on restart.
+type fsm struct {
+	taskManager *managers.TaskManager
+	appliedFile string
+	lastApplied uint64
+	fsmLock     *sync.Mutex
+}
+
+type appliedIndex struct {
+	Index uint64 `json:"index"`
+}
+
+type snapshotData struct {
+	Index uint64          `json:"index"`
+	State json.RawMessage `json:"state"`
+}
+
+func newFSM(taskManager *managers.TaskManager, dataDir string) (*fsm, error) {
+	f := &fsm{
+		taskManager: taskManager,
+		appliedFile: filepath.Join(dataDir, "applied.json"),
+		fsmLock:     &sync.Mutex{},
+	}
+	data, err := os.ReadFile(f.appliedFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var applied appliedIndex
+		if err := json.Unmarshal(data, &applied); err != nil {
+			return nil, fmt.Errorf("failed to read applied index: %v", err)
+		}
+		f.lastApplied = applied.Index
+	}
+	return f, nil
+}
+
+// Apply implements raft.FSM
+func (f *fsm) Apply(log *raft.Log) interface{} {
+	f.fsmLock.Lock()
+	defer f.fsmLock.Unlock()
+
+	if log.Index <= f.lastApplied {
+		return nil
+	}
+
+	cmd, err := f.taskManager.DecodeCommand(log.Data)
+	if err != nil {
+		return &managers.CommandResult{Error: err.Error()}
+	}
+	cmd.Term = log.Term
+	cmd.Index = log.Index
+
+	result := f.taskManager.Apply(cmd)
+	if err := f.setApplied(log.Index); err != nil {
+		slog.Error("failed to save applied index", "index", log.Index, "error", err)
+	}
+	return result
+}
+
+// Snapshot implements raft.FSM
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	f.fsmLock.Lock()
+	defer f.fsmLock.Unlock()
+
+	state, err := f.taskManager.ExportSealedState()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(snapshotData{Index: f.lastApplied, State: state})
+	if err != nil {
+		return nil, err
+	}
+	return &fsmSnapshot{data: data}, nil
+}
+
+// Restore implements raft.FSM
+func (f *fsm) Restore(reader io.ReadCloser) error {
+	defer reader.Close()
+	f.fsmLock.Lock()
+	defer f.fsmLock.Unlock()
+
+	var snapshot snapshotData
+	if err := json.NewDecoder(reader).Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %v", err)
+	}
+	if err := f.taskManager.RestoreState(snapshot.State); err != nil {
+		return err
+	}
+	return f.setApplied(snapshot.Index)
+}
+
+// setApplied persists the index of the last applied entry.
+// The caller must hold fsmLock.
+func (f *fsm) setApplied(index uint64) error {
+	f.lastApplied = index
+	data, err := json.Marshal(appliedIndex{Index: index})
+	if err != nil {
+		return err
+	}
+	tmpFile := f.appliedFile + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, f.appliedFile)
+}
+
+type fsmSnapshot struct {
+	data []byte
+}
+
+// Persist implements raft.FSMSnapshot
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s.data); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+// Release implements raft.FSMSnapshot
+func (s *fsmSnapshot) Release() {}
@@ -0,0 +1,248 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"github.com/indkumar8999/ps-tasks/managers"
+)
+
+// Node is a member of a raft replicated cluster. It implements
+// managers.Replicator so every TaskManager mutation goes through the raft log.
+// Only the leader appends to the log, so only the leader grants leases; other
+// nodes forward their writes to it.
+type Node struct {
+	config    Config
+	raft      *raft.Raft
+	fsm       *fsm
+	peers     map[string]Peer
+	forwarder Forwarder
+}
+
+// NewNode starts a cluster node replicating the given TaskManager.
+// The TaskManager must already have loaded its state from disk.
+func NewNode(config Config, taskManager *managers.TaskManager) (*Node, error) {
+	if config.NodeID == "" {
+		return nil, fmt.Errorf("invalid node ID")
+	}
+	if config.ApplyTimeout <= 0 {
+		config.ApplyTimeout = DEFAULT_APPLY_TIMEOUT
+	}
+	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create raft directory: %v", err)
+	}
+
+	peers := make(map[string]Peer)
+	for _, peer := range config.Peers {
+		peers[peer.ID] = peer
+	}
+
+	if config.Transport == nil && config.ServerTLS != nil && config.ClientTLS != nil {
+		stream, err := newTLSStreamLayer(config.RaftAddr, config.ServerTLS, config.ClientTLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create raft transport: %v", err)
+		}
+		config.Transport = raft.NewNetworkTransport(stream, 3, 10*time.Second, os.Stderr)
+	}
+	if config.Transport == nil {
+		transport, err := raft.NewTCPTransport(config.RaftAddr, nil, 3, 10*time.Second, os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create raft transport: %v", err)
+		}
+		config.Transport = transport
+	}
+	if config.LogStore == nil || config.StableStore == nil {
+		store, err := raftboltdb.NewBoltStore(filepath.Join(config.DataDir, "raft.db"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create raft log store: %v", err)
+		}
+		if config.LogStore == nil {
+			config.LogStore = store
+		}
+		if config.StableStore == nil {
+			config.StableStore = store
+		}
+	}
+	if config.SnapshotStore == nil {
+		snapshots, err := raft.NewFileSnapshotStore(config.DataDir, 2, os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create raft snapshot store: %v", err)
+		}
+		config.SnapshotStore = snapshots
+	}
+	if config.Forwarder == nil {
+		config.Forwarder = NewGrpcForwarder(config.ClientTLS)
+	}
+
+	fsm, err := newFSM(taskManager, config.DataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(config.NodeID)
+	// The files on disk already reflect every applied entry, see fsm
+	raftConfig.NoSnapshotRestoreOnStart = true
+
+	r, err := raft.NewRaft(raftConfig, fsm, config.LogStore, config.StableStore, config.SnapshotStore, config.Transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start raft: %v", err)
+	}
+
+	if config.Bootstrap {
+		hasState, err := raft.HasExistingState(config.LogStore, config.StableStore, config.SnapshotStore)
+		if err != nil {
+			return nil, err
+		}
+		if !hasState {
+			configuration := raft.Configuration{}
+			for _, peer := range config.Peers {
+				configuration.Servers = append(configuration.Servers, raft.Server{
+					ID:      raft.ServerID(peer.ID),
+					Address: raft.ServerAddress(peer.RaftAddr),
+				})
+			}
+			if err := r.BootstrapCluster(configuration).Error(); err != nil {
+				return nil, fmt.Errorf("failed to bootstrap cluster: %v", err)
+			}
+		}
+	}
+
+	return &Node{
+		config:    config,
+		raft:      r,
+		fsm:       fsm,
+		peers:     peers,
+		forwarder: config.Forwarder,
+	}, nil
+}
+
+// ID returns the node ID
+func (n *Node) ID() string {
+	return n.config.NodeID
+}
+
+// IsLeader reports whether this node is the raft leader
+func (n *Node) IsLeader() bool {
+	return n.raft.State() == raft.Leader
+}
+
+// Term returns the current raft term
+func (n *Node) Term() uint64 {
+	return n.raft.CurrentTerm()
+}
+
+// Leader returns the current leader, if one is known
+func (n *Node) Leader() (Peer, error) {
+	_, leaderID := n.raft.LeaderWithID()
+	if leaderID == "" {
+		return Peer{}, fmt.Errorf("no leader elected")
+	}
+	peer, exists := n.peers[string(leaderID)]
+	if !exists {
+		return Peer{}, fmt.Errorf("unknown leader %s", leaderID)
+	}
+	return peer, nil
+}
+
+// WaitForLeader blocks until a leader is known or the timeout passes
+func (n *Node) WaitForLeader(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := n.Leader(); err == nil {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("no leader elected after %v", timeout)
+}
+
+// Propose implements managers.Replicator. Commands proposed on a follower
+// are forwarded to the leader.
+func (n *Node) Propose(cmd *managers.Command) (*managers.CommandResult, error) {
+	if n.IsLeader() {
+		return n.ProposeAsLeader(cmd)
+	}
+	leader, err := n.Leader()
+	if err != nil {
+		return nil, err
+	}
+	return n.forwarder.Forward(leader, cmd)
+}
+
+// FetchBlob implements managers.BlobFetcher. Blobs are stored on the node
+// that received the payload, which is usually the leader, so it is asked
+// first and then every other peer.
+func (n *Node) FetchBlob(digest string) (io.ReadCloser, error) {
+	var peers []Peer
+	for _, peer := range n.peers {
+		if peer.ID != n.config.NodeID {
+			peers = append(peers, peer)
+		}
+	}
+	leader, _ := n.Leader()
+	sort.Slice(peers, func(i, j int) bool {
+		if (peers[i].ID == leader.ID) != (peers[j].ID == leader.ID) {
+			return peers[i].ID == leader.ID
+		}
+		return peers[i].ID < peers[j].ID
+	})
+
+	err := fmt.Errorf("no other nodes")
+	for _, peer := range peers {
+		var reader io.ReadCloser
+		if reader, err = n.forwarder.FetchBlob(peer, digest); err == nil {
+			return reader, nil
+		}
+	}
+	return nil, err
+}
+
+// ProposeAsLeader appends a command to the raft log and waits for it to be
+// applied. It fails rather than forwarding if this node is not the leader.
+func (n *Node) ProposeAsLeader(cmd *managers.Command) (*managers.CommandResult, error) {
+	if !n.IsLeader() {
+		return nil, fmt.Errorf("node %s is not the leader", n.config.NodeID)
+	}
+	data, err := n.fsm.taskManager.EncodeCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+	future := n.raft.Apply(data, n.config.ApplyTimeout)
+	if err := future.Error(); err != nil {
+		return nil, fmt.Errorf("failed to replicate command: %v", err)
+	}
+	result, ok := future.Response().(*managers.CommandResult)
+	if !ok {
+		return nil, fmt.Errorf("command was not applied")
+	}
+	return result, nil
+}
+
+// MAX_APPLY_LAG is how many committed entries a node can have left to apply
+// before Check reports it as behind
+const MAX_APPLY_LAG = 1000
+
+// Check returns an error if the node knows no leader or is more than
+// MAX_APPLY_LAG entries behind the committed log
+func (n *Node) Check() error {
+	if _, err := n.Leader(); err != nil {
+		return err
+	}
+	commitIndex, appliedIndex := n.raft.CommitIndex(), n.raft.AppliedIndex()
+	if commitIndex > appliedIndex+MAX_APPLY_LAG {
+		return fmt.Errorf("applied index %d is %d entries behind the commit index", appliedIndex, commitIndex-appliedIndex)
+	}
+	return nil
+}
+
+// Shutdown stops the node
+func (n *Node) Shutdown() error {
+	return n.raft.Shutdown().Error()
+}
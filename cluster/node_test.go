@@ -0,0 +1,278 @@
+package cluster
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/indkumar8999/ps-tasks/archive"
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/managers"
+)
+
+// testNode is a member of an in-process cluster
+type testNode struct {
+	node        *Node
+	taskManager *managers.TaskManager
+	transport   *raft.InmemTransport
+}
+
+func newTestTaskManager(t *testing.T, dir string) *managers.TaskManager {
+	t.Helper()
+	for _, sub := range []string{"tasks", "leases", "metadata/queues"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", sub, err)
+		}
+	}
+	leaseManager, err := managers.NewLeaseManager(filepath.Join(dir, "leases"))
+	if err != nil {
+		t.Fatalf("failed to create lease manager: %v", err)
+	}
+	queueManager, err := managers.NewQueueManager(filepath.Join(dir, "metadata", "queues"))
+	if err != nil {
+		t.Fatalf("failed to create queue manager: %v", err)
+	}
+	taskArchive, err := archive.NewArchive(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	shardManager := managers.NewShardManager(filepath.Join(dir, "metadata"))
+	taskManager := managers.NewTaskManager(filepath.Join(dir, "tasks"), leaseManager, queueManager, shardManager, taskArchive)
+	// Short leases let the test wait for one to expire
+	settings := taskManager.Settings()
+	settings.MinLeaseDuration = 10 * time.Millisecond
+	taskManager.SetSettings(settings)
+	return taskManager
+}
+
+// newTestCluster starts size nodes connected through raft's in-memory
+// transport and an InmemForwarder, and waits for a leader
+func newTestCluster(t *testing.T, size int) []*testNode {
+	t.Helper()
+	forwarder := NewInmemForwarder()
+	var peers []Peer
+	var transports []*raft.InmemTransport
+	for i := 0; i < size; i++ {
+		id := fmt.Sprintf("node%d", i)
+		addr, transport := raft.NewInmemTransport(raft.ServerAddress(id))
+		peers = append(peers, Peer{ID: id, RaftAddr: string(addr), RPCAddr: string(addr)})
+		transports = append(transports, transport)
+	}
+	for _, a := range transports {
+		for _, b := range transports {
+			a.Connect(b.LocalAddr(), b)
+		}
+	}
+
+	var nodes []*testNode
+	for i, peer := range peers {
+		dir := t.TempDir()
+		taskManager := newTestTaskManager(t, dir)
+		store := raft.NewInmemStore()
+		node, err := NewNode(Config{
+			NodeID:        peer.ID,
+			RaftAddr:      peer.RaftAddr,
+			DataDir:       filepath.Join(dir, "raft"),
+			Peers:         peers,
+			Bootstrap:     i == 0,
+			Transport:     transports[i],
+			LogStore:      store,
+			StableStore:   store,
+			SnapshotStore: raft.NewInmemSnapshotStore(),
+			Forwarder:     forwarder,
+		}, taskManager)
+		if err != nil {
+			t.Fatalf("failed to start %s: %v", peer.ID, err)
+		}
+		taskManager.SetReplicator(node)
+		forwarder.Register(node)
+		nodes = append(nodes, &testNode{node: node, taskManager: taskManager, transport: transports[i]})
+		t.Cleanup(func() { node.Shutdown() })
+	}
+	for _, n := range nodes {
+		if err := n.node.WaitForLeader(10 * time.Second); err != nil {
+			t.Fatalf("%s: %v", n.node.ID(), err)
+		}
+	}
+	return nodes
+}
+
+// leader returns the node that is the leader, waiting for one to be elected
+// among nodes
+func leader(t *testing.T, nodes []*testNode) *testNode {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, n := range nodes {
+			if n.node.IsLeader() {
+				return n
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("no leader elected")
+	return nil
+}
+
+// eventually fails the test unless check passes within a few seconds
+func eventually(t *testing.T, check func() error) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestLeaderFailover(t *testing.T) {
+	nodes := newTestCluster(t, 3)
+	first := leader(t, nodes)
+	var follower *testNode
+	for _, n := range nodes {
+		if n != first {
+			follower = n
+			break
+		}
+	}
+
+	// Writes made on a follower are forwarded to the leader
+	created, err := follower.taskManager.CreateTask("task", "", "default", []byte("input"), nil)
+	if err != nil {
+		t.Fatalf("CreateTask on a follower: %v", err)
+	}
+	stale, err := first.taskManager.LeaseTask(created.ID, "worker1", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("LeaseTask: %v", err)
+	}
+	if stale.Term == 0 || stale.FencingToken == 0 {
+		t.Fatalf("lease has no term or fencing token: %+v", stale)
+	}
+	for _, n := range nodes {
+		eventually(t, func() error {
+			_, err := n.taskManager.GetTask(created.ID)
+			return err
+		})
+	}
+
+	// The leader goes away and the others elect a new one
+	first.transport.DisconnectAll()
+	for _, n := range nodes {
+		if n != first {
+			n.transport.Disconnect(first.transport.LocalAddr())
+		}
+	}
+	if err := first.node.Shutdown(); err != nil {
+		t.Fatalf("failed to shut down the leader: %v", err)
+	}
+	var rest []*testNode
+	for _, n := range nodes {
+		if n != first {
+			rest = append(rest, n)
+		}
+	}
+	second := leader(t, rest)
+	if second.node.Term() <= stale.Term {
+		t.Errorf("term did not advance: %d <= %d", second.node.Term(), stale.Term)
+	}
+
+	// The first worker's lease expires and the new leader leases the task again
+	time.Sleep(100 * time.Millisecond)
+	current, err := second.taskManager.LeaseTask(created.ID, "worker2", time.Minute)
+	if err != nil {
+		t.Fatalf("LeaseTask after failover: %v", err)
+	}
+	if current.FencingToken <= stale.FencingToken {
+		t.Errorf("fencing token did not increase: %d <= %d", current.FencingToken, stale.FencingToken)
+	}
+
+	// The first worker finishing late through any node is fenced off
+	for _, n := range rest {
+		_, err := n.taskManager.WithLease(stale.ID, stale.FencingToken).CompleteTask(created.ID, []byte("stale"))
+		if !errors.Is(err, managers.ErrLeaseLost) {
+			t.Errorf("stale completion on %s: error = %v, want ErrLeaseLost", n.node.ID(), err)
+		}
+	}
+	for _, n := range rest {
+		if n != second {
+			if _, err := n.taskManager.WithLease(current.ID, current.FencingToken).CompleteTask(created.ID, []byte("result")); err != nil {
+				t.Fatalf("CompleteTask through a follower: %v", err)
+			}
+		}
+	}
+	for _, n := range rest {
+		eventually(t, func() error {
+			got, err := n.taskManager.GetTask(created.ID)
+			if err != nil {
+				return err
+			}
+			if got.State != managers.COMPLETED || string(got.Result) != "result" {
+				return fmt.Errorf("%s: task = %s %q, want %s %q", n.node.ID(), got.State, got.Result, managers.COMPLETED, "result")
+			}
+			return nil
+		})
+	}
+}
+
+func TestFollowersFetchBlobs(t *testing.T) {
+	nodes := newTestCluster(t, 3)
+	blobDirs := make(map[*testNode]string)
+	for _, n := range nodes {
+		blobDirs[n] = t.TempDir()
+		blobStore, err := blobs.NewStore(blobDirs[n], nil)
+		if err != nil {
+			t.Fatalf("NewStore: %v", err)
+		}
+		n.taskManager.SetBlobStore(blobStore, 16)
+	}
+	first := leader(t, nodes)
+	input := bytes.Repeat([]byte("payload "), 64)
+	created, err := first.taskManager.CreateTask("task", "", "default", input, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if created.InputBlob == nil {
+		t.Fatalf("input was not stored as a blob")
+	}
+	digest := created.InputBlob.Digest
+
+	// Followers copy the blob once they apply the task
+	for _, n := range nodes {
+		eventually(t, func() error {
+			_, err := n.taskManager.Blobs().Stat(digest)
+			return err
+		})
+	}
+
+	// A blob a node lost is fetched again when it is read
+	var follower *testNode
+	for _, n := range nodes {
+		if n != first {
+			follower = n
+			break
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(blobDirs[follower], digest[:2])); err != nil {
+		t.Fatalf("failed to delete the blob: %v", err)
+	}
+	reader, _, err := follower.taskManager.OpenBlob(digest)
+	if err != nil {
+		t.Fatalf("OpenBlob: %v", err)
+	}
+	defer reader.Close()
+	got, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(got, input) {
+		t.Errorf("fetched payload = %q, %v, want the input", got, err)
+	}
+}
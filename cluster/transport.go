@@ -0,0 +1,29 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// tlsStreamLayer is a raft.StreamLayer that carries raft traffic over TLS
+type tlsStreamLayer struct {
+	net.Listener
+	clientTLS *tls.Config
+}
+
+func newTLSStreamLayer(bindAddr string, serverTLS *tls.Config, clientTLS *tls.Config) (*tlsStreamLayer, error) {
+	listener, err := tls.Listen("tcp", bindAddr, serverTLS)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsStreamLayer{Listener: listener, clientTLS: clientTLS}, nil
+}
+
+// Dial implements raft.StreamLayer
+func (s *tlsStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	return tls.DialWithDialer(dialer, "tcp", string(address), s.clientTLS)
+}
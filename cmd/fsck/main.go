@@ -0,0 +1,56 @@
+// Command fsck checks the database of a stopped server and optionally repairs it.
+//
+//	go run ./cmd/fsck -db ./database           report problems
+//	go run ./cmd/fsck -db ./database -repair   quarantine, re-queue and drop
+//
+// It exits with status 1 if problems are left unrepaired.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/indkumar8999/ps-tasks/fsck"
+)
+
+func main() {
+	dbPath := flag.String("db", "database", "database directory of the server")
+	repair := flag.Bool("repair", false, "repair the problems found")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening database:", err)
+		os.Exit(2)
+	}
+
+	report, err := fsck.NewChecker(*dbPath, *repair).Run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error checking database:", err)
+		os.Exit(2)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		for _, issue := range report.Issues {
+			status := "found"
+			if issue.Repaired {
+				status = "repaired"
+			} else if issue.RepairError != "" {
+				status = "repair failed: " + issue.RepairError
+			}
+			fmt.Printf("%-16s %-10s %s: %s (%s)\n", issue.Kind, issue.Repair, issue.File, issue.Detail, status)
+		}
+		fmt.Printf("Checked %d tasks and %d leases, %d problems, %d unrepaired\n",
+			report.TasksChecked, report.LeasesChecked, len(report.Issues), report.Unrepaired())
+	}
+
+	if report.Unrepaired() > 0 {
+		os.Exit(1)
+	}
+}
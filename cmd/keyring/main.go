@@ -0,0 +1,46 @@
+// Command keyring manages the keyring file used to encrypt task payloads.
+//
+//	go run ./cmd/keyring -file keyring.json -add         add a primary key
+//	go run ./cmd/keyring -file keyring.json              list the keys
+//
+// A running server picks up a new primary key within -keyring-reload-interval
+// and re-encrypts its tasks in the background.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/indkumar8999/ps-tasks/keyring"
+)
+
+func main() {
+	path := flag.String("file", "keyring.json", "keyring file")
+	add := flag.Bool("add", false, "generate a new key and make it the primary key")
+	id := flag.String("id", "", "ID of the new key; defaults to the current time")
+	flag.Parse()
+
+	if *add {
+		key, err := keyring.AddKey(*path, *id)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error adding key:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Added primary key", key.ID)
+		return
+	}
+
+	file, err := keyring.ReadFile(*path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading keyring:", err)
+		os.Exit(1)
+	}
+	for _, key := range file.Keys {
+		primary := ""
+		if key.ID == file.Primary {
+			primary = " (primary)"
+		}
+		fmt.Printf("%s created %s%s\n", key.ID, key.CreatedAt, primary)
+	}
+}
@@ -0,0 +1,54 @@
+// Command migrate upgrades the records of a stopped server to the current
+// schema versions.
+//
+//	go run ./cmd/migrate -db ./database -dry-run   list the records to migrate
+//	go run ./cmd/migrate -db ./database            migrate, backing up originals
+//
+// The server runs the same migrations at startup unless -no-auto-migrate is set.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/indkumar8999/ps-tasks/migrate"
+)
+
+func main() {
+	dbPath := flag.String("db", "database", "database directory of the server")
+	dryRun := flag.Bool("dry-run", false, "list the records to migrate without changing them")
+	noBackup := flag.Bool("no-backup", false, "do not copy records to database/backups before rewriting them")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening database:", err)
+		os.Exit(2)
+	}
+
+	report, err := migrate.Run(*dbPath, migrate.Options{DryRun: *dryRun, Backup: !*noBackup})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error migrating database:", err)
+		os.Exit(2)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+		return
+	}
+	for _, change := range report.Changes {
+		fmt.Printf("%-7s %s: version %d -> %d\n", change.Kind, change.ID, change.From, change.To)
+	}
+	if report.DryRun {
+		fmt.Printf("%d records need migrating\n", len(report.Changes))
+		return
+	}
+	fmt.Printf("Migrated %d records\n", len(report.Changes))
+	if report.BackupDir != "" {
+		fmt.Println("Originals saved in", report.BackupDir)
+	}
+}
@@ -0,0 +1,36 @@
+// Command token issues bearer tokens for the server's -auth-token-keys key
+// set. The key set is a keyring file, so cmd/keyring creates and rotates it.
+//
+//	go run ./cmd/keyring -file tokens.json -add                     add a signing key
+//	go run ./cmd/token -keys tokens.json -subject worker-1 -ttl 24h  issue a token
+//
+// The subject is the principal the server's policy grants roles to.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/auth"
+)
+
+func main() {
+	keysPath := flag.String("keys", "tokens.json", "key set file")
+	subject := flag.String("subject", "", "principal the token authenticates")
+	ttl := flag.Duration("ttl", 24*time.Hour, "how long the token is valid")
+	flag.Parse()
+
+	keys, err := auth.LoadKeySet(*keysPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading key set:", err)
+		os.Exit(1)
+	}
+	token, err := keys.Sign(*subject, *ttl, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error issuing token:", err)
+		os.Exit(1)
+	}
+	fmt.Println(token)
+}
@@ -0,0 +1,78 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs payloads can be compressed with. NONE leaves payloads as they are.
+const (
+	NONE   = ""
+	GZIP   = "gzip"
+	ZSTD   = "zstd"
+	SNAPPY = "snappy"
+)
+
+// zstd encoders and decoders are safe for concurrent use of EncodeAll and
+// DecodeAll, so one of each is shared
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+// Validate checks that a codec is known
+func Validate(codec string) error {
+	switch codec {
+	case NONE, GZIP, ZSTD, SNAPPY:
+		return nil
+	}
+	return fmt.Errorf("unknown compression codec %q", codec)
+}
+
+// Compress compresses data with a codec
+func Compress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case NONE:
+		return data, nil
+	case GZIP:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case ZSTD:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case SNAPPY:
+		return snappy.Encode(nil, data), nil
+	}
+	return nil, Validate(codec)
+}
+
+// Decompress reverses Compress
+func Decompress(codec string, data []byte) ([]byte, error) {
+	switch codec {
+	case NONE:
+		return data, nil
+	case GZIP:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case ZSTD:
+		return zstdDecoder.DecodeAll(data, nil)
+	case SNAPPY:
+		return snappy.Decode(nil, data)
+	}
+	return nil, Validate(codec)
+}
@@ -0,0 +1,52 @@
+package codec
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// Importing this package registers the gzip, zstd and snappy compressors
+// with gRPC. Servers answer with the compressor a client used, and clients
+// advertise every registered compressor, so both ends only need the import.
+func init() {
+	encoding.RegisterCompressor(&zstdCompressor{})
+	encoding.RegisterCompressor(&snappyCompressor{})
+}
+
+type zstdCompressor struct{}
+
+func (c *zstdCompressor) Name() string {
+	return ZSTD
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	// A single threaded decoder runs synchronously, so it holds no
+	// goroutines once the message is read
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+type snappyCompressor struct{}
+
+func (c *snappyCompressor) Name() string {
+	return SNAPPY
+}
+
+func (c *snappyCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+func (c *snappyCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return snappy.NewReader(r), nil
+}
@@ -0,0 +1,362 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/admission"
+	"github.com/indkumar8999/ps-tasks/auth"
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/health"
+	"github.com/indkumar8999/ps-tasks/logging"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/snapshot"
+	"github.com/indkumar8999/ps-tasks/tlsconfig"
+	"github.com/indkumar8999/ps-tasks/tracing"
+)
+
+// DEFAULT_RPC_ADDR is the address the gRPC server listens on by default
+const DEFAULT_RPC_ADDR = ":50051"
+
+// DEFAULT_SHUTDOWN_TIMEOUT is how long in-flight calls get to finish on
+// shutdown by default
+const DEFAULT_SHUTDOWN_TIMEOUT = 30 * time.Second
+
+// DEFAULT_DATA_DIR is the database directory, relative to the working
+// directory, used by default
+const DEFAULT_DATA_DIR = "database"
+
+// Config is the configuration of the server. Each setting has a key in the
+// configuration file, a flag and an environment variable; see Loader for
+// how they are combined. Settings tagged reload take effect on SIGHUP.
+type Config struct {
+	Listen      Listen      `yaml:"listen" toml:"listen"`
+	Data        Data        `yaml:"data" toml:"data"`
+	Leases      Leases      `yaml:"leases" toml:"leases"`
+	Sweeps      Sweeps      `yaml:"sweeps" toml:"sweeps"`
+	Retention   Retention   `yaml:"retention" toml:"retention"`
+	TLS         TLS         `yaml:"tls" toml:"tls"`
+	Auth        Auth        `yaml:"auth" toml:"auth"`
+	Limits      Limits      `yaml:"limits" toml:"limits"`
+	Cluster     Cluster     `yaml:"cluster" toml:"cluster"`
+	Replication Replication `yaml:"replication" toml:"replication"`
+	Snapshots   Snapshots   `yaml:"snapshots" toml:"snapshots"`
+	Keyring     Keyring     `yaml:"keyring" toml:"keyring"`
+	Blobs       Blobs       `yaml:"blobs" toml:"blobs"`
+	Log         Log         `yaml:"log" toml:"log"`
+	Tracing     Tracing     `yaml:"tracing" toml:"tracing"`
+	Health      Health      `yaml:"health" toml:"health"`
+	Shutdown    Shutdown    `yaml:"shutdown" toml:"shutdown"`
+}
+
+// Listen are the addresses the server listens on
+type Listen struct {
+	RPC     string `yaml:"rpc" toml:"rpc" flag:"rpc-addr" usage:"address the gRPC server listens on"`
+	Metrics string `yaml:"metrics" toml:"metrics" flag:"metrics-addr" usage:"address the Prometheus /metrics endpoint listens on; disabled when empty"`
+}
+
+// Data is where and how records are stored
+type Data struct {
+	Dir           string `yaml:"dir" toml:"dir" flag:"data-dir" usage:"directory tasks, leases and queues are stored in"`
+	StrictLoad    bool   `yaml:"strict_load" toml:"strict_load" flag:"strict-load" usage:"fail startup on unreadable records instead of quarantining them"`
+	NoAutoMigrate bool   `yaml:"no_auto_migrate" toml:"no_auto_migrate" flag:"no-auto-migrate" usage:"refuse to start when records need migrating instead of migrating them"`
+}
+
+// Leases bound how long tasks are leased for
+type Leases struct {
+	Default time.Duration `yaml:"default" toml:"default" flag:"lease-duration" reload:"true" usage:"how long a lease lasts when the worker does not ask for a duration"`
+	Min     time.Duration `yaml:"min" toml:"min" flag:"lease-min" reload:"true" usage:"shortest lease a worker can ask for"`
+	Max     time.Duration `yaml:"max" toml:"max" flag:"lease-max" reload:"true" usage:"longest lease a worker can ask for"`
+}
+
+// Sweeps are the intervals of the background sweeps
+type Sweeps struct {
+	Retention   time.Duration `yaml:"retention" toml:"retention" flag:"retention-sweep-interval" reload:"true" usage:"how often retention policies are applied"`
+	Cancel      time.Duration `yaml:"cancel" toml:"cancel" flag:"cancel-sweep-interval" reload:"true" usage:"how often cancelled tasks whose lease has expired are finalized"`
+	LeaseExpiry time.Duration `yaml:"lease_expiry" toml:"lease_expiry" flag:"lease-expiry-sweep-interval" reload:"true" usage:"how often expired leases are counted, logged and removed"`
+}
+
+// Retention is how long finished tasks are kept in queues without a policy
+// for their state; 0 keeps them forever
+type Retention struct {
+	Completed time.Duration `yaml:"completed" toml:"completed" flag:"retention-completed" reload:"true" usage:"how long completed tasks are kept; 0 keeps them forever"`
+	Failed    time.Duration `yaml:"failed" toml:"failed" flag:"retention-failed" reload:"true" usage:"how long failed tasks are kept; 0 keeps them forever"`
+	Aborted   time.Duration `yaml:"aborted" toml:"aborted" flag:"retention-aborted" reload:"true" usage:"how long aborted tasks are kept; 0 keeps them forever"`
+}
+
+// TLS configures TLS for clients and other nodes
+type TLS struct {
+	Cert               string        `yaml:"cert" toml:"cert" flag:"tls-cert" usage:"server certificate file; serves gRPC over TLS when set"`
+	Key                string        `yaml:"key" toml:"key" flag:"tls-key" usage:"private key file of -tls-cert"`
+	ClientCA           string        `yaml:"client_ca" toml:"client_ca" flag:"tls-client-ca" usage:"CA bundle client certificates are verified against; requires client certificates when set"`
+	ClientCertOptional bool          `yaml:"client_cert_optional" toml:"client_cert_optional" flag:"tls-client-cert-optional" usage:"accept clients without a certificate when -tls-client-ca is set"`
+	PeerCA             string        `yaml:"peer_ca" toml:"peer_ca" flag:"tls-peer-ca" usage:"CA bundle other nodes' certificates are verified against; defaults to -tls-client-ca"`
+	ReloadInterval     time.Duration `yaml:"reload_interval" toml:"reload_interval" flag:"tls-reload-interval" usage:"how often certificate files are checked for changes"`
+}
+
+// Auth configures authentication and authorization
+type Auth struct {
+	Policy         string        `yaml:"policy" toml:"policy" flag:"auth-policy" usage:"policy file granting roles on queues; requires every caller to authenticate when set"`
+	TokenKeys      string        `yaml:"token_keys" toml:"token_keys" flag:"auth-token-keys" usage:"key set file bearer tokens are verified against; only client certificates authenticate when empty"`
+	ReloadInterval time.Duration `yaml:"reload_interval" toml:"reload_interval" flag:"auth-reload-interval" usage:"how often the policy and token key files are read again"`
+}
+
+// Limits configure rate limits and admission control
+type Limits struct {
+	RateLimits        string        `yaml:"rate_limits" toml:"rate_limits" flag:"rate-limits" reload:"true" usage:"file of token bucket rate limits per method, caller and queue"`
+	MaxPendingTasks   int           `yaml:"max_pending_tasks" toml:"max_pending_tasks" flag:"admission-max-pending" reload:"true" usage:"reject new tasks while this many tasks are pending; 0 disables the limit"`
+	MaxDiskUsage      float64       `yaml:"max_disk_usage" toml:"max_disk_usage" flag:"admission-max-disk-usage" reload:"true" usage:"reject new tasks and payloads while the database disk is this percent full; 0 disables the limit"`
+	AdmissionInterval time.Duration `yaml:"admission_interval" toml:"admission_interval" flag:"admission-interval" usage:"how often pending tasks and disk usage are measured"`
+}
+
+// Cluster configures replicated mode
+type Cluster struct {
+	NodeID    string `yaml:"node_id" toml:"node_id" flag:"cluster-node-id" usage:"raft node ID; enables replicated mode"`
+	RaftAddr  string `yaml:"raft_addr" toml:"raft_addr" flag:"cluster-raft-addr" usage:"address the raft transport listens on"`
+	Peers     string `yaml:"peers" toml:"peers" flag:"cluster-peers" usage:"cluster members as id=raftAddr=rpcAddr, comma separated"`
+	Bootstrap bool   `yaml:"bootstrap" toml:"bootstrap" flag:"cluster-bootstrap" usage:"form the cluster from -cluster-peers if it has no state yet"`
+}
+
+// Replication configures log shipping to standbys
+type Replication struct {
+	LogShipping bool   `yaml:"log_shipping" toml:"log_shipping" flag:"log-shipping" usage:"run as a log shipping primary that standbys can follow"`
+	StandbyOf   string `yaml:"standby_of" toml:"standby_of" flag:"standby-of" usage:"gRPC address of the primary to follow as a read-only standby"`
+}
+
+// Snapshots configures scheduled snapshots
+type Snapshots struct {
+	Interval time.Duration `yaml:"interval" toml:"interval" flag:"snapshot-interval" usage:"take a scheduled snapshot this often; 0 disables scheduled snapshots"`
+	Keep     int           `yaml:"keep" toml:"keep" flag:"snapshot-keep" usage:"number of scheduled snapshots to keep"`
+}
+
+// Keyring configures encryption of payloads at rest
+type Keyring struct {
+	Path           string        `yaml:"path" toml:"path" flag:"keyring" usage:"keyring file; encrypts task payloads at rest when set"`
+	ReloadInterval time.Duration `yaml:"reload_interval" toml:"reload_interval" flag:"keyring-reload-interval" usage:"how often the keyring file is checked for a new primary key"`
+}
+
+// Blobs configures the blob store for large payloads
+type Blobs struct {
+	Threshold  int           `yaml:"threshold" toml:"threshold" flag:"blob-threshold" usage:"payloads larger than this many bytes are kept in the blob store"`
+	GCInterval time.Duration `yaml:"gc_interval" toml:"gc_interval" flag:"blob-gc-interval" usage:"how often unreferenced blobs are collected"`
+	GCGrace    time.Duration `yaml:"gc_grace" toml:"gc_grace" flag:"blob-gc-grace" usage:"how long an unreferenced blob is kept before it is collected"`
+}
+
+// Log configures logging
+type Log struct {
+	Level  string `yaml:"level" toml:"level" flag:"log-level" reload:"true" usage:"minimum level logged: debug, info, warn or error"`
+	Format string `yaml:"format" toml:"format" flag:"log-format" usage:"log format: text or json"`
+}
+
+// Tracing configures where spans are exported
+type Tracing struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter" flag:"trace-exporter" usage:"where spans are exported: otlp or stdout; trace context is still propagated when empty"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" flag:"trace-otlp-endpoint" usage:"host:port of the OTLP gRPC collector; defaults to OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure" flag:"trace-otlp-insecure" usage:"send spans to the OTLP collector without TLS"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio" flag:"trace-sample-ratio" usage:"fraction of traces started by the server that are recorded"`
+}
+
+// Health configures the grpc.health.v1 service
+type Health struct {
+	CheckInterval time.Duration `yaml:"check_interval" toml:"check_interval" flag:"health-check-interval" usage:"how often storage, the lease reaper and replication are checked"`
+}
+
+// Shutdown configures how the server stops on SIGTERM or SIGINT
+type Shutdown struct {
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" flag:"shutdown-timeout" usage:"how long in-flight calls get to finish on SIGTERM or SIGINT before they are cancelled"`
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	settings := managers.DefaultSettings()
+	return &Config{
+		Listen: Listen{RPC: DEFAULT_RPC_ADDR},
+		Data:   Data{Dir: DEFAULT_DATA_DIR},
+		Leases: Leases{
+			Default: settings.LeaseDuration,
+			Min:     settings.MinLeaseDuration,
+			Max:     settings.MaxLeaseDuration,
+		},
+		Sweeps: Sweeps{
+			Retention:   settings.RetentionSweepInterval,
+			Cancel:      settings.CancelSweepInterval,
+			LeaseExpiry: metrics.DEFAULT_LEASE_SWEEP_INTERVAL,
+		},
+		Retention: Retention{
+			Completed: managers.DEFAULT_RETENTION[managers.COMPLETED].MaxAge(),
+			Failed:    managers.DEFAULT_RETENTION[managers.FAILED].MaxAge(),
+			Aborted:   managers.DEFAULT_RETENTION[managers.ABORTED].MaxAge(),
+		},
+		TLS:       TLS{ReloadInterval: tlsconfig.DEFAULT_RELOAD_INTERVAL},
+		Auth:      Auth{ReloadInterval: auth.DEFAULT_RELOAD_INTERVAL},
+		Limits:    Limits{AdmissionInterval: admission.DEFAULT_SAMPLE_INTERVAL},
+		Snapshots: Snapshots{Keep: snapshot.DEFAULT_KEEP},
+		Keyring:   Keyring{ReloadInterval: managers.DEFAULT_KEYRING_RELOAD_INTERVAL},
+		Blobs: Blobs{
+			Threshold:  blobs.DEFAULT_THRESHOLD,
+			GCInterval: blobs.DEFAULT_GC_INTERVAL,
+			GCGrace:    blobs.DEFAULT_GC_GRACE,
+		},
+		Log:      Log{Level: "info", Format: logging.FORMAT_TEXT},
+		Tracing:  Tracing{Exporter: tracing.EXPORTER_NONE, SampleRatio: 1},
+		Health:   Health{CheckInterval: health.DEFAULT_CHECK_INTERVAL},
+		Shutdown: Shutdown{Timeout: DEFAULT_SHUTDOWN_TIMEOUT},
+	}
+}
+
+// Settings returns the lease bounds and sweep intervals of the task manager
+func (c *Config) Settings() managers.Settings {
+	return managers.Settings{
+		LeaseDuration:            c.Leases.Default,
+		MinLeaseDuration:         c.Leases.Min,
+		MaxLeaseDuration:         c.Leases.Max,
+		RetentionSweepInterval:   c.Sweeps.Retention,
+		CancelSweepInterval:      c.Sweeps.Cancel,
+		LeaseExpirySweepInterval: c.Sweeps.LeaseExpiry,
+	}
+}
+
+// DefaultRetention returns the retention of queues without a policy
+func (c *Config) DefaultRetention() map[string]*queues.RetentionPolicy {
+	policy := func(maxAge time.Duration) *queues.RetentionPolicy {
+		return &queues.RetentionPolicy{MaxAgeSeconds: int64(maxAge.Seconds())}
+	}
+	return map[string]*queues.RetentionPolicy{
+		managers.COMPLETED: policy(c.Retention.Completed),
+		managers.FAILED:    policy(c.Retention.Failed),
+		managers.ABORTED:   policy(c.Retention.Aborted),
+	}
+}
+
+// Validate checks the settings, returning every problem found
+func (c *Config) Validate() error {
+	v := &validator{flags: flagNames(c)}
+
+	if c.Listen.RPC == "" {
+		v.fail("listen.rpc", "is required")
+	} else {
+		v.address("listen.rpc", c.Listen.RPC)
+	}
+	if c.Listen.Metrics != "" {
+		v.address("listen.metrics", c.Listen.Metrics)
+	}
+	if c.Data.Dir == "" {
+		v.fail("data.dir", "is required")
+	}
+
+	v.positive("leases.min", c.Leases.Min)
+	v.positive("leases.max", c.Leases.Max)
+	v.positive("leases.default", c.Leases.Default)
+	if c.Leases.Max < c.Leases.Min {
+		v.fail("leases.max", "%s is shorter than leases.min (%s)", c.Leases.Max, c.Leases.Min)
+	}
+	if c.Leases.Default < c.Leases.Min || c.Leases.Default > c.Leases.Max {
+		v.fail("leases.default", "%s is outside leases.min and leases.max (%s to %s)", c.Leases.Default, c.Leases.Min, c.Leases.Max)
+	}
+
+	v.positive("sweeps.retention", c.Sweeps.Retention)
+	v.positive("sweeps.cancel", c.Sweeps.Cancel)
+	v.positive("sweeps.lease_expiry", c.Sweeps.LeaseExpiry)
+	v.notNegative("retention.completed", c.Retention.Completed)
+	v.notNegative("retention.failed", c.Retention.Failed)
+	v.notNegative("retention.aborted", c.Retention.Aborted)
+
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		v.fail("tls.key", "tls.cert and tls.key must be set together")
+	}
+	if c.TLS.Cert == "" && (c.TLS.ClientCA != "" || c.TLS.PeerCA != "" || c.TLS.ClientCertOptional) {
+		v.fail("tls.cert", "is required by the other tls settings")
+	}
+	v.positive("tls.reload_interval", c.TLS.ReloadInterval)
+	if c.Auth.TokenKeys != "" && c.Auth.Policy == "" {
+		v.fail("auth.policy", "is required by auth.token_keys")
+	}
+	v.positive("auth.reload_interval", c.Auth.ReloadInterval)
+
+	if c.Limits.MaxPendingTasks < 0 {
+		v.fail("limits.max_pending_tasks", "%d is negative", c.Limits.MaxPendingTasks)
+	}
+	if c.Limits.MaxDiskUsage < 0 || c.Limits.MaxDiskUsage > 100 {
+		v.fail("limits.max_disk_usage", "%v is not a percentage between 0 and 100", c.Limits.MaxDiskUsage)
+	}
+	v.positive("limits.admission_interval", c.Limits.AdmissionInterval)
+
+	if c.Cluster.NodeID != "" {
+		if c.Cluster.RaftAddr == "" {
+			v.fail("cluster.raft_addr", "is required by cluster.node_id")
+		} else {
+			v.address("cluster.raft_addr", c.Cluster.RaftAddr)
+		}
+		if c.Replication.LogShipping || c.Replication.StandbyOf != "" {
+			v.fail("cluster.node_id", "log shipping cannot be combined with cluster mode")
+		}
+	} else if c.Cluster.RaftAddr != "" || c.Cluster.Peers != "" || c.Cluster.Bootstrap {
+		v.fail("cluster.node_id", "is required by the other cluster settings")
+	}
+	if c.Replication.LogShipping && c.Replication.StandbyOf != "" {
+		v.fail("replication.standby_of", "a standby cannot also be a log shipping primary")
+	}
+
+	v.notNegative("snapshots.interval", c.Snapshots.Interval)
+	if c.Snapshots.Keep < 1 {
+		v.fail("snapshots.keep", "%d must be at least 1", c.Snapshots.Keep)
+	}
+	v.positive("keyring.reload_interval", c.Keyring.ReloadInterval)
+	if c.Blobs.Threshold < 0 {
+		v.fail("blobs.threshold", "%d is negative", c.Blobs.Threshold)
+	}
+	v.positive("blobs.gc_interval", c.Blobs.GCInterval)
+	v.notNegative("blobs.gc_grace", c.Blobs.GCGrace)
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		v.fail("log.level", "%q is not debug, info, warn or error", c.Log.Level)
+	}
+	if c.Log.Format != logging.FORMAT_TEXT && c.Log.Format != logging.FORMAT_JSON {
+		v.fail("log.format", "%q is not text or json", c.Log.Format)
+	}
+	switch c.Tracing.Exporter {
+	case tracing.EXPORTER_NONE, tracing.EXPORTER_OTLP, tracing.EXPORTER_STDOUT:
+	default:
+		v.fail("tracing.exporter", "%q is not otlp or stdout", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.fail("tracing.sample_ratio", "%v is not between 0 and 1", c.Tracing.SampleRatio)
+	}
+
+	v.positive("health.check_interval", c.Health.CheckInterval)
+	v.positive("shutdown.timeout", c.Shutdown.Timeout)
+
+	return errors.Join(v.errs...)
+}
+
+// validator collects problems, naming each setting by its key and flag
+type validator struct {
+	flags map[string]string
+	errs  []error
+}
+
+func (v *validator) fail(key string, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("invalid %s (-%s): %s", key, v.flags[key], fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) positive(key string, d time.Duration) {
+	if d <= 0 {
+		v.fail(key, "%s must be positive", d)
+	}
+}
+
+func (v *validator) notNegative(key string, d time.Duration) {
+	if d < 0 {
+		v.fail(key, "%s is negative", d)
+	}
+}
+
+func (v *validator) address(key string, addr string) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		v.fail(key, "%q is not a host:port address", addr)
+	}
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ENV_PREFIX starts the environment variable of every setting, followed by
+// its flag name in upper case with dashes replaced by underscores, such as
+// PS_TASKS_RPC_ADDR for -rpc-addr
+const ENV_PREFIX = "PS_TASKS_"
+
+// CONFIG_ENV names the configuration file when -config is not set
+const CONFIG_ENV = ENV_PREFIX + "CONFIG"
+
+// setting is a leaf of Config
+type setting struct {
+	key    string
+	flag   string
+	usage  string
+	reload bool
+	value  reflect.Value
+}
+
+// settings lists the settings of c in declaration order. Their values point
+// into c.
+func settings(c *Config) []setting {
+	var result []setting
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Type().Field(i)
+		fields := sections.Field(i)
+		for j := 0; j < fields.NumField(); j++ {
+			field := fields.Type().Field(j)
+			result = append(result, setting{
+				key:    section.Tag.Get("yaml") + "." + field.Tag.Get("yaml"),
+				flag:   field.Tag.Get("flag"),
+				usage:  field.Tag.Get("usage"),
+				reload: field.Tag.Get("reload") == "true",
+				value:  fields.Field(j),
+			})
+		}
+	}
+	return result
+}
+
+// flagNames maps setting keys to flag names
+func flagNames(c *Config) map[string]string {
+	names := make(map[string]string)
+	for _, s := range settings(c) {
+		names[s.key] = s.flag
+	}
+	return names
+}
+
+// envName returns the environment variable of a flag
+func envName(flagName string) string {
+	return ENV_PREFIX + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// parse sets a setting from its text form
+func parse(value reflect.Value, text string) error {
+	switch value.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 1h", text)
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", text)
+		}
+		value.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", text)
+		}
+		value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", text)
+		}
+		value.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+	return nil
+}
+
+// Loader reads the configuration. Settings are taken from, in increasing
+// precedence: the defaults, the configuration file, PS_TASKS_*
+// environment variables and flags set on the command line. The loader keeps
+// the command line so Load can be called again to reload.
+type Loader struct {
+	path  string
+	flags map[string]string
+}
+
+// Register adds -config and a flag per setting to fs. Flags are only
+// recorded while parsing and applied by Load.
+func Register(fs *flag.FlagSet) *Loader {
+	loader := &Loader{flags: make(map[string]string)}
+	fs.StringVar(&loader.path, "config", "", "YAML (.yaml, .yml) or TOML (.toml) configuration file; defaults to "+CONFIG_ENV)
+	for _, s := range settings(Default()) {
+		fs.Var(&flagValue{loader: loader, name: s.flag, value: s.value}, s.flag, s.usage)
+	}
+	return loader
+}
+
+// Path returns the configuration file, or "" if there is none
+func (l *Loader) Path() string {
+	if l.path != "" {
+		return l.path
+	}
+	return os.Getenv(CONFIG_ENV)
+}
+
+// Load reads and validates the configuration
+func (l *Loader) Load() (*Config, error) {
+	c := Default()
+	if path := l.Path(); path != "" {
+		if err := readFile(path, c); err != nil {
+			return nil, err
+		}
+	}
+	var errs []error
+	for _, s := range settings(c) {
+		name := envName(s.flag)
+		if text, ok := os.LookupEnv(name); ok {
+			if err := parse(s.value, text); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %v", name, err))
+			}
+		}
+	}
+	for _, s := range settings(c) {
+		if text, ok := l.flags[s.flag]; ok {
+			if err := parse(s.value, text); err != nil {
+				errs = append(errs, fmt.Errorf("invalid -%s: %v", s.flag, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile decodes a YAML or TOML file over c. Unknown keys are errors, so
+// misspelled settings are not silently ignored.
+func readFile(path string, c *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.NewDecoder(file).Decode(c)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("failed to parse %s: unknown setting %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("unsupported config file %s: use .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Changes returns the keys of the settings that differ between two
+// configurations, split into those that take effect on reload and those
+// that need a restart
+func Changes(old *Config, new *Config) (reloaded []string, restart []string) {
+	newSettings := settings(new)
+	for i, s := range settings(old) {
+		if reflect.DeepEqual(s.value.Interface(), newSettings[i].value.Interface()) {
+			continue
+		}
+		if s.reload {
+			reloaded = append(reloaded, s.key)
+		} else {
+			restart = append(restart, s.key)
+		}
+	}
+	return reloaded, restart
+}
+
+// flagValue records a flag set on the command line. value holds the default.
+type flagValue struct {
+	loader *Loader
+	name   string
+	value  reflect.Value
+}
+
+func (f *flagValue) String() string {
+	if !f.value.IsValid() || f.value.IsZero() {
+		return ""
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+func (f *flagValue) Set(text string) error {
+	if err := parse(reflect.New(f.value.Type()).Elem(), text); err != nil {
+		return err
+	}
+	f.loader.flags[f.name] = text
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.value.IsValid() && f.value.Kind() == reflect.Bool
+}
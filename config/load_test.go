@@ -0,0 +1,136 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newLoader registers the settings on a new flag set and parses args
+func newLoader(t *testing.T, args ...string) *Loader {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := Register(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return loader
+}
+
+func writeConfig(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, "server.yaml", `
+listen:
+  rpc: ":1000"
+leases:
+  default: 2m
+data:
+  dir: from-file
+`)
+	t.Setenv(CONFIG_ENV, "")
+	t.Setenv(envName("rpc-addr"), ":2000")
+	t.Setenv(envName("lease-duration"), "3m")
+
+	c, err := newLoader(t, "-config", path, "-rpc-addr", ":3000").Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// The flag beats the environment, which beats the file, which beats
+	// the defaults
+	if c.Listen.RPC != ":3000" {
+		t.Errorf("listen.rpc = %q, want the flag's :3000", c.Listen.RPC)
+	}
+	if c.Leases.Default != 3*time.Minute {
+		t.Errorf("leases.default = %v, want the environment's 3m", c.Leases.Default)
+	}
+	if c.Data.Dir != "from-file" {
+		t.Errorf("data.dir = %q, want the file's from-file", c.Data.Dir)
+	}
+	if c.Snapshots.Keep != Default().Snapshots.Keep {
+		t.Errorf("snapshots.keep = %d, want the default %d", c.Snapshots.Keep, Default().Snapshots.Keep)
+	}
+}
+
+func TestLoadTOMLFromEnvironment(t *testing.T) {
+	path := writeConfig(t, "server.toml", `
+[listen]
+rpc = ":4000"
+
+[leases]
+max = "2h"
+`)
+	t.Setenv(CONFIG_ENV, path)
+
+	c, err := newLoader(t).Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.Listen.RPC != ":4000" || c.Leases.Max != 2*time.Hour {
+		t.Errorf("config = %+v %+v, want rpc :4000 and max lease 2h", c.Listen, c.Leases)
+	}
+}
+
+func TestLoadRejectsInvalidSettings(t *testing.T) {
+	t.Setenv(CONFIG_ENV, "")
+	tests := []struct {
+		name     string
+		env      map[string]string
+		fileName string
+		file     string
+		args     []string
+	}{
+		{name: "unknown file key", fileName: "server.yaml", file: "listen:\n  rcp: \":1\"\n"},
+		{name: "bad environment duration", env: map[string]string{envName("lease-duration"): "soon"}},
+		{name: "unsupported file type", fileName: "server.json", file: "{}"},
+		{name: "min lease above max", args: []string{"-lease-min", "2h", "-lease-max", "1h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			args := tt.args
+			if tt.fileName != "" {
+				args = append(args, "-config", writeConfig(t, tt.fileName, tt.file))
+			}
+			if _, err := newLoader(t, args...).Load(); err == nil {
+				t.Errorf("Load succeeded")
+			}
+		})
+	}
+
+	// Flags are checked while parsing
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	Register(fs)
+	if err := fs.Parse([]string{"-lease-duration", "soon"}); err == nil {
+		t.Errorf("parsing an invalid duration flag succeeded")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	old := Default()
+	changed := Default()
+	changed.Leases.Default = 5 * time.Minute
+	changed.Listen.RPC = ":9999"
+
+	reloaded, restart := Changes(old, changed)
+	if !slices.Equal(reloaded, []string{"leases.default"}) {
+		t.Errorf("reloaded = %v, want [leases.default]", reloaded)
+	}
+	if !slices.Equal(restart, []string{"listen.rpc"}) {
+		t.Errorf("restart = %v, want [listen.rpc]", restart)
+	}
+}
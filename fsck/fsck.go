@@ -0,0 +1,289 @@
+package fsck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/codec"
+	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// Kinds of problems the checker finds
+const (
+	// INVALID_JSON is a record file that is truncated or not valid JSON
+	INVALID_JSON = "invalid_json"
+	// ID_MISMATCH is a record file whose name does not match the ID inside it
+	ID_MISMATCH = "id_mismatch"
+	// ORPHAN_LEASE is a lease on a task that does not exist
+	ORPHAN_LEASE = "orphan_lease"
+	// DUPLICATE_LEASE is one of several unexpired leases on the same task
+	DUPLICATE_LEASE = "duplicate_lease"
+	// STUCK_TASK is a task handed to a worker that nobody holds a lease on
+	STUCK_TASK = "stuck_task"
+)
+
+// Repairs the checker can make
+const (
+	QUARANTINE = "quarantine"
+	RENAME     = "rename"
+	DROP       = "drop"
+	REQUEUE    = "requeue"
+)
+
+// Issue is a single problem found in the database
+type Issue struct {
+	Kind   string `json:"kind"`
+	File   string `json:"file"`
+	ID     string `json:"id,omitempty"`
+	Detail string `json:"detail"`
+	// Repair is the action that fixes the issue
+	Repair string `json:"repair"`
+	// Repaired is set once the repair has been made
+	Repaired bool `json:"repaired"`
+	// RepairError explains why a repair failed
+	RepairError string `json:"repair_error,omitempty"`
+}
+
+// Report lists everything the checker found
+type Report struct {
+	TasksChecked  int      `json:"tasks_checked"`
+	LeasesChecked int      `json:"leases_checked"`
+	Issues        []*Issue `json:"issues"`
+}
+
+// Unrepaired returns the number of issues that are still present
+func (r *Report) Unrepaired() int {
+	count := 0
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			count++
+		}
+	}
+	return count
+}
+
+// Checker walks the tasks and leases of a stopped server's database
+type Checker struct {
+	tasksDir   string
+	leasesDir  string
+	quarantine *store.Quarantine
+	repair     bool
+	now        time.Time
+	report     *Report
+}
+
+// NewChecker creates a checker for the database directory. Repairs are only
+// made when repair is set; otherwise the checker only reports.
+func NewChecker(dbPath string, repair bool) *Checker {
+	return &Checker{
+		tasksDir:   filepath.Join(dbPath, "tasks"),
+		leasesDir:  filepath.Join(dbPath, "leases"),
+		quarantine: store.NewQuarantine(filepath.Join(dbPath, "quarantine")),
+		repair:     repair,
+		now:        time.Now(),
+		report:     &Report{},
+	}
+}
+
+// Run checks the database. The server must not be running while it does.
+func (c *Checker) Run() (*Report, error) {
+	tasks, err := c.loadTasks()
+	if err != nil {
+		return nil, err
+	}
+	taskLeases, err := c.loadLeases()
+	if err != nil {
+		return nil, err
+	}
+
+	active := c.checkLeases(tasks, taskLeases)
+	c.checkStuckTasks(tasks, taskLeases, active)
+	return c.report, nil
+}
+
+// scan decodes every JSON file in a directory, quarantining the ones that
+// cannot be read and renaming the ones saved under the wrong name. decode
+// returns the record's ID and a function that keeps the decoded record.
+func (c *Checker) scan(dir string, kind string, decode func(data []byte) (string, func(), error)) (int, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	checked := 0
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if _, ok := store.RecordID(file.Name()); !ok {
+			continue
+		}
+		checked++
+		path := filepath.Join(dir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return checked, err
+		}
+
+		id, keep, err := decode(data)
+		if err != nil || id == "" {
+			detail := "record has no ID"
+			if err != nil {
+				detail = err.Error()
+			}
+			issue := c.add(&Issue{Kind: INVALID_JSON, File: path, Detail: detail, Repair: QUARANTINE})
+			c.fix(issue, func() error { return c.quarantine.Add(kind, path, fmt.Errorf("%s", detail)) })
+			continue
+		}
+
+		expected := store.FileName(id)
+		if file.Name() != expected {
+			target := filepath.Join(dir, expected)
+			issue := c.add(&Issue{Kind: ID_MISMATCH, File: path, ID: id, Detail: fmt.Sprintf("file should be named %s", expected), Repair: RENAME})
+			if _, err := os.Stat(target); err == nil {
+				// The correctly named file wins; this copy is set aside
+				issue.Detail = fmt.Sprintf("%s already exists", expected)
+				issue.Repair = QUARANTINE
+				c.fix(issue, func() error { return c.quarantine.Add(kind, path, fmt.Errorf("%s", issue.Detail)) })
+				continue
+			}
+			c.fix(issue, func() error { return os.Rename(path, target) })
+		}
+		keep()
+	}
+	return checked, nil
+}
+
+func (c *Checker) loadTasks() (map[string]*task.Task, error) {
+	tasks := make(map[string]*task.Task)
+	checked, err := c.scan(c.tasksDir, "tasks", func(data []byte) (string, func(), error) {
+		var t task.Task
+		err := json.Unmarshal(data, &t)
+		return t.ID, func() { tasks[t.ID] = &t }, err
+	})
+	c.report.TasksChecked = checked
+	return tasks, err
+}
+
+func (c *Checker) loadLeases() (map[string][]*leases.Lease, error) {
+	taskLeases := make(map[string][]*leases.Lease)
+	checked, err := c.scan(c.leasesDir, "leases", func(data []byte) (string, func(), error) {
+		var lease leases.Lease
+		err := json.Unmarshal(data, &lease)
+		return lease.ID, func() { taskLeases[lease.TaskID] = append(taskLeases[lease.TaskID], &lease) }, err
+	})
+	c.report.LeasesChecked = checked
+	return taskLeases, err
+}
+
+// checkLeases drops leases on missing tasks and all but the longest of
+// several unexpired leases on a task. It returns the tasks left with an
+// unexpired lease.
+func (c *Checker) checkLeases(tasks map[string]*task.Task, taskLeases map[string][]*leases.Lease) map[string]bool {
+	active := make(map[string]bool)
+	for taskID, held := range taskLeases {
+		if _, exists := tasks[taskID]; !exists {
+			for _, lease := range held {
+				c.dropLease(&Issue{Kind: ORPHAN_LEASE, ID: lease.ID, Detail: fmt.Sprintf("task %s does not exist", taskID), Repair: DROP})
+			}
+			continue
+		}
+
+		var unexpired []*leases.Lease
+		for _, lease := range held {
+			if !lease.IsExpiredAt(c.now) {
+				unexpired = append(unexpired, lease)
+			}
+		}
+		if len(unexpired) == 0 {
+			continue
+		}
+		active[taskID] = true
+		sort.Slice(unexpired, func(i, j int) bool { return unexpired[i].ExpiresAt.After(unexpired[j].ExpiresAt) })
+		for _, lease := range unexpired[1:] {
+			c.dropLease(&Issue{Kind: DUPLICATE_LEASE, ID: lease.ID, Detail: fmt.Sprintf("task %s is also leased by %s", taskID, unexpired[0].ID), Repair: DROP})
+		}
+	}
+	return active
+}
+
+func (c *Checker) dropLease(issue *Issue) {
+	issue.File = store.Path(c.leasesDir, issue.ID)
+	c.add(issue)
+	c.fix(issue, func() error { return os.Remove(issue.File) })
+}
+
+// checkStuckTasks puts tasks back in the queue that are waiting on a worker
+// nobody has a lease for. The server leaves a leased task CREATED or
+// RESUMED, so those are stuck when all their leases have expired; tasks
+// left RUNNING, STARTED or STOPPED by UpdateTask are never leased again and
+// are stuck unless a lease is still held. Tasks being cancelled are left to
+// the server's cancel sweep.
+func (c *Checker) checkStuckTasks(tasks map[string]*task.Task, taskLeases map[string][]*leases.Lease, active map[string]bool) {
+	for id, t := range tasks {
+		if active[id] || t.CancelRequested {
+			continue
+		}
+		expired := taskLeases[id]
+		var detail string
+		switch t.State {
+		case managers.CREATED, managers.RESUMED:
+			if len(expired) == 0 {
+				continue
+			}
+			detail = fmt.Sprintf("task is %s and its %d lease(s) have expired", t.State, len(expired))
+		case managers.RUNNING, managers.STARTED, managers.STOPPED:
+			detail = fmt.Sprintf("task is %s without a lease", t.State)
+		default:
+			continue
+		}
+		issue := c.add(&Issue{
+			Kind:   STUCK_TASK,
+			File:   store.Path(c.tasksDir, id),
+			ID:     id,
+			Detail: detail,
+			Repair: REQUEUE,
+		})
+		c.fix(issue, func() error {
+			for _, lease := range expired {
+				if err := os.Remove(store.Path(c.leasesDir, lease.ID)); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			if t.State == managers.RESUMED {
+				return nil
+			}
+			t.State = managers.CREATED
+			t.UpdatedAt = c.now.Format(time.RFC3339)
+			// Packed payloads are written back untouched, so no keyring is needed
+			return t.Save(c.tasksDir, nil, codec.NONE)
+		})
+	}
+}
+
+func (c *Checker) add(issue *Issue) *Issue {
+	c.report.Issues = append(c.report.Issues, issue)
+	return issue
+}
+
+// fix makes a repair if the checker is allowed to
+func (c *Checker) fix(issue *Issue, repair func() error) {
+	if !c.repair {
+		return
+	}
+	if err := repair(); err != nil {
+		issue.RepairError = err.Error()
+		return
+	}
+	issue.Repaired = true
+}
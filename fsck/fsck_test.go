@@ -0,0 +1,137 @@
+package fsck
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// FIXTURE_NOW is the time the checker runs at against testdata/db
+var FIXTURE_NOW = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newFixtureChecker returns a checker running at FIXTURE_NOW
+func newFixtureChecker(t *testing.T, dbPath string, repair bool) *Checker {
+	t.Helper()
+	c := NewChecker(dbPath, repair)
+	c.now = FIXTURE_NOW
+	return c
+}
+
+// copyFixture copies testdata/db to a temporary directory, since repairs
+// change it
+func copyFixture(t *testing.T) string {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "db")
+	if err := os.CopyFS(dbPath, os.DirFS(filepath.Join("testdata", "db"))); err != nil {
+		t.Fatalf("failed to copy fixture: %v", err)
+	}
+	return dbPath
+}
+
+// issuesByKind maps each issue kind to the IDs, or file names for records
+// without a readable ID, it was reported for
+func issuesByKind(report *Report) map[string][]string {
+	kinds := make(map[string][]string)
+	for _, issue := range report.Issues {
+		id := issue.ID
+		if id == "" {
+			id = filepath.Base(issue.File)
+		}
+		kinds[issue.Kind] = append(kinds[issue.Kind], id)
+	}
+	return kinds
+}
+
+func TestCheckReportsEveryKind(t *testing.T) {
+	dbPath := copyFixture(t)
+	report, err := newFixtureChecker(t, dbPath, false).Run()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.TasksChecked != 7 || report.LeasesChecked != 5 {
+		t.Errorf("checked %d tasks and %d leases, want 7 and 5", report.TasksChecked, report.LeasesChecked)
+	}
+
+	kinds := issuesByKind(report)
+	want := map[string][]string{
+		INVALID_JSON:    {"broken.json"},
+		ID_MISMATCH:     {"renamed"},
+		ORPHAN_LEASE:    {"l-orphan"},
+		DUPLICATE_LEASE: {"l-duplicate"},
+	}
+	for kind, ids := range want {
+		if len(kinds[kind]) != 1 || kinds[kind][0] != ids[0] {
+			t.Errorf("%s issues = %v, want %v", kind, kinds[kind], ids)
+		}
+	}
+	// The leased, waiting and cancelling tasks are not stuck
+	stuck := map[string]bool{}
+	for _, id := range kinds[STUCK_TASK] {
+		stuck[id] = true
+	}
+	if len(stuck) != 2 || !stuck["expired"] || !stuck["running"] {
+		t.Errorf("stuck tasks = %v, want expired and running", kinds[STUCK_TASK])
+	}
+	if report.Unrepaired() != len(report.Issues) {
+		t.Errorf("%d of %d issues repaired without -repair", len(report.Issues)-report.Unrepaired(), len(report.Issues))
+	}
+
+	// Without -repair nothing changes
+	for _, path := range []string{"tasks/broken.json", "tasks/misnamed.json", "leases/l-orphan.json", "leases/l-expired.json"} {
+		if _, err := os.Stat(filepath.Join(dbPath, path)); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dbPath, "quarantine")); !os.IsNotExist(err) {
+		t.Errorf("quarantine was created without -repair")
+	}
+}
+
+func TestRepair(t *testing.T) {
+	dbPath := copyFixture(t)
+	report, err := newFixtureChecker(t, dbPath, true).Run()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Unrepaired() != 0 {
+		for _, issue := range report.Issues {
+			t.Errorf("issue %s %s: repaired %v, %s", issue.Kind, issue.File, issue.Repaired, issue.RepairError)
+		}
+	}
+
+	exists := map[string]bool{
+		"tasks/broken.json":            false,
+		"quarantine/tasks/broken.json": true,
+		"tasks/misnamed.json":          false,
+		"tasks/renamed.json":           true,
+		"leases/l-orphan.json":         false,
+		"leases/l-duplicate.json":      false,
+		"leases/l-leased.json":         true,
+		"leases/l-expired.json":        false,
+		"leases/l-cancelling.json":     true,
+	}
+	for path, want := range exists {
+		_, err := os.Stat(filepath.Join(dbPath, path))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", path, got, want)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dbPath, "tasks", "running.json"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if got := string(data); !strings.Contains(got, `"state":"created"`) {
+		t.Errorf("requeued task = %s, want state created", got)
+	}
+
+	// A second run finds nothing left to fix
+	report, err = newFixtureChecker(t, dbPath, true).Run()
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("second run found %v", issuesByKind(report))
+	}
+}
@@ -0,0 +1,133 @@
+package health
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// DEFAULT_CHECK_INTERVAL is how often subsystems are checked
+const DEFAULT_CHECK_INTERVAL = 5 * time.Second
+
+// Subsystems reported as services of the health server
+const (
+	// STORAGE is serving while the data directory is writable
+	STORAGE = "storage"
+	// LEASE_REAPER is serving while the sweeps that act on expired leases run
+	LEASE_REAPER = "lease-reaper"
+	// REPLICATION is serving while the node follows the cluster or primary
+	REPLICATION = "replication"
+	// DRAIN is serving while the node grants leases
+	DRAIN = "drain"
+)
+
+// Check returns an error when a subsystem is unhealthy
+type Check func() error
+
+// Checker runs checks and publishes their results on a grpc.health.v1
+// server, one service per subsystem. The overall status, under "" and the
+// names given to NewChecker, is serving only while every subsystem is.
+type Checker struct {
+	server   *grpchealth.Server
+	services []string
+	names    []string
+	checks   map[string]Check
+	// failures are the last errors of unhealthy subsystems, by name
+	failures    map[string]string
+	checkerLock *sync.Mutex
+}
+
+// NewChecker creates a Checker. services, such as "task.TaskService", report
+// the overall status.
+func NewChecker(services ...string) *Checker {
+	c := &Checker{
+		server:      grpchealth.NewServer(),
+		services:    append([]string{""}, services...),
+		checks:      make(map[string]Check),
+		failures:    make(map[string]string),
+		checkerLock: &sync.Mutex{},
+	}
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	return c
+}
+
+// Server returns the health server to register on the gRPC server
+func (c *Checker) Server() *grpchealth.Server {
+	return c.server
+}
+
+// Add checks a subsystem from the next Check on
+func (c *Checker) Add(name string, check Check) {
+	c.checkerLock.Lock()
+	defer c.checkerLock.Unlock()
+	c.names = append(c.names, name)
+	c.checks[name] = check
+}
+
+// Check runs every check and publishes the results. Changes are logged.
+func (c *Checker) Check() {
+	c.checkerLock.Lock()
+	defer c.checkerLock.Unlock()
+
+	overall := healthpb.HealthCheckResponse_SERVING
+	for _, name := range c.names {
+		status := healthpb.HealthCheckResponse_SERVING
+		if err := c.checks[name](); err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			overall = status
+			if c.failures[name] != err.Error() {
+				slog.Warn("subsystem is unhealthy", "subsystem", name, "error", err)
+			}
+			c.failures[name] = err.Error()
+		} else if _, failed := c.failures[name]; failed {
+			slog.Info("subsystem is healthy again", "subsystem", name)
+			delete(c.failures, name)
+		}
+		c.server.SetServingStatus(name, status)
+	}
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, overall)
+	}
+}
+
+// PeriodicallyCheck runs the checks every interval
+func (c *Checker) PeriodicallyCheck(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.Check()
+	}
+}
+
+// Shutdown reports every service as not serving from now on
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+}
+
+// Writable checks that a file can be written and synced in dir
+func Writable(dir string) Check {
+	return func() error {
+		file, err := os.CreateTemp(dir, ".health-*.tmp")
+		if err != nil {
+			return fmt.Errorf("failed to create file: %v", err)
+		}
+		defer os.Remove(file.Name())
+		if _, err := file.WriteString("ok\n"); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write file: %v", err)
+		}
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to sync file: %v", err)
+		}
+		return file.Close()
+	}
+}
@@ -0,0 +1,249 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// KEY_SIZE is the size of key encryption keys and data keys (AES-256)
+const KEY_SIZE = 32
+
+// Key is a key encryption key. Keys are never deleted by the server; a key
+// can be removed from the file once no record is sealed with it.
+type Key struct {
+	ID        string `json:"id"`
+	Secret    []byte `json:"secret"`
+	CreatedAt string `json:"created_at"`
+}
+
+// File is the layout of the keyring file
+type File struct {
+	Primary string `json:"primary"`
+	Keys    []*Key `json:"keys"`
+}
+
+// Envelope is a payload sealed with a random data key, which is itself
+// sealed with a key from the keyring. Both ciphertexts start with their nonce.
+type Envelope struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Keyring holds the keys loaded from a keyring file. New records are sealed
+// with the primary key; any key in the file can open a record.
+type Keyring struct {
+	path        string
+	primary     string
+	keys        map[string]*Key
+	keyringLock *sync.RWMutex
+}
+
+// Load reads a keyring file
+func Load(path string) (*Keyring, error) {
+	k := &Keyring{path: path, keyringLock: &sync.RWMutex{}}
+	if _, err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload reads the keyring file again. It reports whether the primary key
+// changed. The keyring is left untouched if the file is invalid.
+func (k *Keyring) Reload() (bool, error) {
+	file, err := ReadFile(k.path)
+	if err != nil {
+		return false, err
+	}
+	keys := make(map[string]*Key)
+	for _, key := range file.Keys {
+		if key.ID == "" {
+			return false, fmt.Errorf("keyring has a key without an ID")
+		}
+		if len(key.Secret) != KEY_SIZE {
+			return false, fmt.Errorf("key %s must be %d bytes", key.ID, KEY_SIZE)
+		}
+		if _, exists := keys[key.ID]; exists {
+			return false, fmt.Errorf("key %s is listed twice", key.ID)
+		}
+		keys[key.ID] = key
+	}
+	if _, exists := keys[file.Primary]; !exists {
+		return false, fmt.Errorf("primary key %q is not in the keyring", file.Primary)
+	}
+
+	k.keyringLock.Lock()
+	defer k.keyringLock.Unlock()
+	changed := k.primary != file.Primary
+	k.primary = file.Primary
+	k.keys = keys
+	return changed, nil
+}
+
+// Primary returns the ID of the key new records are sealed with
+func (k *Keyring) Primary() string {
+	k.keyringLock.RLock()
+	defer k.keyringLock.RUnlock()
+	return k.primary
+}
+
+// Seal encrypts plaintext with a new data key wrapped by the primary key.
+// The associated data, such as the record ID, must be passed again to Open.
+func (k *Keyring) Seal(plaintext []byte, associatedData []byte) (*Envelope, error) {
+	dataKey, keyID, wrappedKey, err := k.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := SealWithKey(dataKey, plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{KeyID: keyID, WrappedKey: wrappedKey, Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts an envelope
+func (k *Keyring) Open(envelope *Envelope, associatedData []byte) ([]byte, error) {
+	dataKey, err := k.UnwrapDataKey(envelope.KeyID, envelope.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return OpenWithKey(dataKey, envelope.Ciphertext, associatedData)
+}
+
+// NewDataKey generates a random data key and returns it along with its copy
+// wrapped by the primary key, for payloads too large to seal in one piece
+func (k *Keyring) NewDataKey() ([]byte, string, []byte, error) {
+	dataKey := make([]byte, KEY_SIZE)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, "", nil, err
+	}
+	keyID, wrappedKey, err := k.WrapDataKey(dataKey)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return dataKey, keyID, wrappedKey, nil
+}
+
+// WrapDataKey seals a data key with the primary key
+func (k *Keyring) WrapDataKey(dataKey []byte) (string, []byte, error) {
+	k.keyringLock.RLock()
+	key := k.keys[k.primary]
+	k.keyringLock.RUnlock()
+
+	wrappedKey, err := SealWithKey(key.Secret, dataKey, []byte(key.ID))
+	if err != nil {
+		return "", nil, err
+	}
+	return key.ID, wrappedKey, nil
+}
+
+// UnwrapDataKey opens a data key wrapped by any key in the keyring
+func (k *Keyring) UnwrapDataKey(keyID string, wrappedKey []byte) ([]byte, error) {
+	k.keyringLock.RLock()
+	key, exists := k.keys[keyID]
+	k.keyringLock.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("key %s is not in the keyring", keyID)
+	}
+
+	dataKey, err := OpenWithKey(key.Secret, wrappedKey, []byte(key.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+	return dataKey, nil
+}
+
+func newGCM(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealWithKey encrypts plaintext with AES-GCM. The nonce is prepended to
+// the ciphertext.
+func SealWithKey(secret []byte, plaintext []byte, associatedData []byte) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+// OpenWithKey decrypts a ciphertext written by SealWithKey
+func OpenWithKey(secret []byte, ciphertext []byte, associatedData []byte) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	return gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], associatedData)
+}
+
+// ReadFile reads a keyring file
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %v", err)
+	}
+	return &file, nil
+}
+
+// AddKey generates a new key, makes it the primary key and writes the
+// keyring file, creating it if needed. The file is only readable by its owner.
+func AddKey(path string, id string) (*Key, error) {
+	file, err := ReadFile(path)
+	if os.IsNotExist(err) {
+		file, err = &File{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if id == "" {
+		id = time.Now().UTC().Format("20060102T150405Z")
+	}
+	for _, key := range file.Keys {
+		if key.ID == id {
+			return nil, fmt.Errorf("key %s already exists", id)
+		}
+	}
+
+	key := &Key{ID: id, Secret: make([]byte, KEY_SIZE), CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	if _, err := io.ReadFull(rand.Reader, key.Secret); err != nil {
+		return nil, err
+	}
+	file.Keys = append(file.Keys, key)
+	file.Primary = id
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpFile, path); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -1,15 +1,14 @@
 package leases
 
 import (
-	"fmt"
-	"encoding/json"
-	
-	"os"
-	"path/filepath"
 	"time"
 	"github.com/google/uuid"
+	"github.com/indkumar8999/ps-tasks/store"
 )
 
+// SCHEMA_VERSION is the version of the lease record written by Save
+const SCHEMA_VERSION = 1
+
 // Lease represents a lease for a task
 type Lease struct {
 	ID        string    `json:"id"`
@@ -19,58 +18,61 @@ type Lease struct {
 	CreatedBy string    `json:"created_by"`
 	UpdatedAt time.Time `json:"updated_at"`
 	UpdatedBy string    `json:"updated_by"`
+	// Tenant is the tenant of the leased task
+	Tenant string `json:"tenant,omitempty"`
+	// Term is the replication term of the leader that granted the lease
+	Term uint64 `json:"term,omitempty"`
+	// FencingToken increases with every lease granted, so downstream systems
+	// can reject writes from holders of an older lease
+	FencingToken uint64 `json:"fencing_token,omitempty"`
+	// Duration is how long the lease was granted for; heartbeats extend it
+	// by as much
+	Duration time.Duration `json:"duration,omitempty"`
+	// SchemaVersion is the version of the record format
+	SchemaVersion int `json:"schema_version"`
 }
 
 // NewLease creates a new lease for a task
 func NewLease(taskID string, duration time.Duration, username string) *Lease {
+	return NewLeaseAt(uuid.New().String(), taskID, time.Now(), duration, username)
+}
+
+// NewLeaseAt creates a new lease with the given ID, starting at the given time
+func NewLeaseAt(id string, taskID string, now time.Time, duration time.Duration, username string) *Lease {
 	return &Lease{
-		ID:        uuid.New().String(),
+		ID:        id,
 		TaskID:    taskID,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(duration),
+		CreatedAt: now,
+		ExpiresAt: now.Add(duration),
+		Duration:  duration,
 		CreatedBy: username,
-		UpdatedAt: time.Now(),
+		UpdatedAt: now,
 		UpdatedBy: username,
 	}
 }
 
 // IsExpired checks if the lease is expired
 func (l *Lease) IsExpired() bool {
-	return time.Now().After(l.ExpiresAt)
+	return l.IsExpiredAt(time.Now())
 }
 
-// Save saves the lease to a file
-func (l *Lease) Save(leasesDir string) error {
-	leaseFile := filepath.Join(leasesDir, fmt.Sprintf("%s.json", l.ID))
-	file, err := os.Create(leaseFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	// Serialize the lease to JSON and write to the file
-	if err := json.NewEncoder(file).Encode(l); err != nil {
-		return err
-	}
-
-	return nil
+// IsExpiredAt checks if the lease is expired at the given time
+func (l *Lease) IsExpiredAt(now time.Time) bool {
+	return now.After(l.ExpiresAt)
 }
 
+// Save saves the lease to <leasesDir>/<id>.json
+func (l *Lease) Save(leasesDir string) error {
+	l.SchemaVersion = SCHEMA_VERSION
+	return store.Save(leasesDir, l.ID, l)
+}
 
-// LoadLease loads a lease from a file
+// LoadLease loads a lease from the leases directory. The ID may be given
+// with or without the .json extension.
 func LoadLease(leasesDir, leaseID string) (*Lease, error) {
-	leaseFile := filepath.Join(leasesDir, fmt.Sprintf("%s.json", leaseID))
-	file, err := os.Open(leaseFile)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
 	var lease Lease
-	if err := json.NewDecoder(file).Decode(&lease); err != nil {
+	if err := store.Load(leasesDir, leaseID, &lease); err != nil {
 		return nil, err
 	}
-
 	return &lease, nil
 }
-
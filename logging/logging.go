@@ -0,0 +1,204 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Log formats
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+// REQUEST_ID_KEY is the metadata key request IDs are read from and returned in
+const REQUEST_ID_KEY = "x-request-id"
+
+// MAX_REQUEST_ID_LENGTH bounds the request IDs accepted from callers
+const MAX_REQUEST_ID_LENGTH = 128
+
+// ParseLevel parses a level: debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return lvl, fmt.Errorf("invalid log level %q", level)
+	}
+	return lvl, nil
+}
+
+// New creates a logger writing to w in a format (FORMAT_TEXT or FORMAT_JSON).
+// The level can be a *slog.LevelVar so it can be changed while running.
+func New(w io.Writer, level slog.Leveler, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case FORMAT_TEXT:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FORMAT_JSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying a request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID of a context, or ""
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// With returns logger annotated with the request ID and trace ID of ctx
+func With(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	if ctx == nil {
+		return logger
+	}
+	if requestID := RequestID(ctx); requestID != "" {
+		logger = logger.With("request_id", requestID)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		logger = logger.With("trace_id", spanContext.TraceID().String())
+	}
+	return logger
+}
+
+// requestID returns the request ID the caller sent, or a new one
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(REQUEST_ID_KEY); len(values) > 0 && values[0] != "" && len(values[0]) <= MAX_REQUEST_ID_LENGTH {
+			return values[0]
+		}
+	}
+	return uuid.New().String()
+}
+
+// Requests with these getters have their IDs logged
+type (
+	taskIDRequest  interface{ GetTaskId() string }
+	idRequest      interface{ GetId() string }
+	leaseIDRequest interface{ GetLeaseId() string }
+)
+
+// requestAttributes returns the task and lease IDs a request names
+func requestAttributes(req interface{}) []any {
+	var attrs []any
+	if r, ok := req.(taskIDRequest); ok && r.GetTaskId() != "" {
+		attrs = append(attrs, "task_id", r.GetTaskId())
+	} else if r, ok := req.(idRequest); ok && r.GetId() != "" {
+		attrs = append(attrs, "task_id", r.GetId())
+	}
+	if r, ok := req.(leaseIDRequest); ok && r.GetLeaseId() != "" {
+		attrs = append(attrs, "lease_id", r.GetLeaseId())
+	}
+	return attrs
+}
+
+// level is the level a call is logged at: server faults are errors, calls
+// the server rejected are warnings
+func level(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Internal, codes.DataLoss, codes.Unimplemented:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
+
+// startCall assigns the call a request ID, returns it to the caller in the
+// response header and adds it to the context
+func startCall(ctx context.Context) context.Context {
+	id := requestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(REQUEST_ID_KEY, id))
+	return WithRequestID(ctx, id)
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, fullMethod string, req interface{}, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []any{"method", strings.TrimPrefix(fullMethod, "/")}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		attrs = append(attrs, "peer", p.Addr.String())
+	}
+	attrs = append(attrs, requestAttributes(req)...)
+	attrs = append(attrs, "duration_ms", float64(time.Since(start).Microseconds())/1000, "code", code.String())
+	if err != nil {
+		attrs = append(attrs, "error", status.Convert(err).Message())
+	}
+	With(ctx, logger).Log(ctx, level(code), "rpc", attrs...)
+}
+
+// UnaryServerInterceptor logs every call with its method, peer, task and
+// lease IDs, duration in milliseconds and status code. Calls are tagged with the request ID
+// in the x-request-id metadata, or a new one, which is returned to the
+// caller and added to the context for the handlers' logs.
+func UnaryServerInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx = startCall(ctx)
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, req, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := startCall(stream.Context())
+		err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		logCall(ctx, logger, info.FullMethod, nil, start, err)
+		return err
+	}
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// outgoing adds the request ID of ctx, if any, to the outgoing metadata
+func outgoing(ctx context.Context) context.Context {
+	if requestID := RequestID(ctx); requestID != "" {
+		return metadata.AppendToOutgoingContext(ctx, REQUEST_ID_KEY, requestID)
+	}
+	return ctx
+}
+
+// UnaryClientInterceptor sends the request ID of the call's context, see
+// WithRequestID, so the server logs the call under the caller's ID
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is the streaming counterpart of UnaryClientInterceptor
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoing(ctx), desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTestLogger returns a JSON logger and the buffer it writes to
+func newTestLogger(t *testing.T) (*slog.Logger, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := New(&buf, slog.LevelDebug, FORMAT_JSON)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return logger, &buf
+}
+
+// lastEntry decodes the last line written to buf
+func lastEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("log line %q is not JSON: %v", lines[len(lines)-1], err)
+	}
+	return entry
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, slog.LevelInfo, "xml"); err == nil {
+		t.Errorf("New accepted an unknown format")
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Errorf("ParseLevel accepted an unknown level")
+	}
+	if level, err := ParseLevel("warn"); err != nil || level != slog.LevelWarn {
+		t.Errorf("ParseLevel(warn) = %v, %v, want %v", level, err, slog.LevelWarn)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	logger, buf := newTestLogger(t)
+	interceptor := UnaryServerInterceptor(logger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/task.TaskService/GetTask"}
+	req := &taskpb.GetTaskRequest{Id: "t1"}
+
+	// The caller's request ID reaches the handler's context and the log
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(REQUEST_ID_KEY, "req-1"))
+	var seen string
+	if _, err := interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		seen = RequestID(ctx)
+		With(ctx, logger).Info("handling")
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+	if seen != "req-1" {
+		t.Errorf("handler saw request ID %q, want req-1", seen)
+	}
+	entry := lastEntry(t, buf)
+	want := map[string]interface{}{"msg": "rpc", "level": "INFO", "method": "task.TaskService/GetTask", "task_id": "t1", "code": "OK", "request_id": "req-1"}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+	if strings.Count(buf.String(), `"request_id":"req-1"`) != 2 {
+		t.Errorf("the handler's log does not carry the request ID:\n%s", buf.String())
+	}
+
+	// Calls without a request ID get a new one; rejected calls are warnings
+	// and server faults errors
+	for code, level := range map[codes.Code]string{codes.NotFound: "WARN", codes.Internal: "ERROR"} {
+		_, err := interceptor(context.Background(), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			seen = RequestID(ctx)
+			return nil, status.Error(code, "boom")
+		})
+		if status.Code(err) != code {
+			t.Errorf("interceptor error = %v, want the handler's", err)
+		}
+		entry := lastEntry(t, buf)
+		if entry["level"] != level || entry["code"] != code.String() || entry["error"] != "boom" {
+			t.Errorf("%s call logged as %v", code, entry)
+		}
+		if seen == "" || entry["request_id"] != seen {
+			t.Errorf("request ID = %v, want the generated %q", entry["request_id"], seen)
+		}
+	}
+}
+
+func TestRequestIDLimits(t *testing.T) {
+	long := strings.Repeat("x", MAX_REQUEST_ID_LENGTH+1)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(REQUEST_ID_KEY, long))
+	if got := requestID(ctx); got == long || got == "" {
+		t.Errorf("requestID kept an ID longer than %d characters", MAX_REQUEST_ID_LENGTH)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-2")
+	var sent []string
+	err := UnaryClientInterceptor()(ctx, "/task.TaskService/GetTask", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		sent = md.Get(REQUEST_ID_KEY)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+	if len(sent) != 1 || sent[0] != "req-2" {
+		t.Errorf("sent request IDs %v, want [req-2]", sent)
+	}
+}
@@ -0,0 +1,336 @@
+package logship
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/indkumar8999/ps-tasks/store"
+)
+
+// Entry is a command applied by the primary, numbered in the order it was
+// applied. Command is encoded by managers.TaskManager.EncodeCommand, so it is
+// sealed when a keyring is configured.
+type Entry struct {
+	Seq     uint64          `json:"seq"`
+	Epoch   uint64          `json:"epoch"`
+	Command json.RawMessage `json:"command"`
+}
+
+// logHeader is the first line of a log file. Entries after it start at Base+1;
+// anything at or below Base has to be sent to a standby as a full state.
+type logHeader struct {
+	Base uint64 `json:"base"`
+}
+
+// Log is the primary's append-only mutation log, one JSON entry per line
+type Log struct {
+	path    string
+	file    *os.File
+	base    uint64
+	lastSeq uint64
+	// generation counts the times the file was rewritten by Compact
+	generation uint64
+	// appended is closed and replaced whenever an entry is appended or the
+	// log is compacted
+	appended chan struct{}
+	logLock  *sync.Mutex
+}
+
+// ErrCompacted is returned by Follow when entries it has yet to send were
+// dropped by Compact
+var ErrCompacted = errors.New("log was compacted past the follower")
+
+// OpenLog opens the log at path. A new log is started after base when the
+// file does not exist yet.
+func OpenLog(path string, base uint64) (*Log, error) {
+	l := &Log{
+		path:     path,
+		appended: make(chan struct{}),
+		logLock:  &sync.Mutex{},
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		header, err := json.Marshal(logHeader{Base: base})
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, append(header, '\n'), 0644); err != nil {
+			return nil, fmt.Errorf("failed to create log: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	header, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log header: %v", err)
+	}
+	var h logHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return nil, fmt.Errorf("failed to read log header: %v", err)
+	}
+	l.base, l.lastSeq = h.Base, h.Base
+	// complete is the length of the header and the entries read so far
+	complete := int64(len(header))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A partial last line is a write cut short by a crash. It is cut
+			// off so the next entry starts on a line of its own.
+			if len(line) > 0 {
+				slog.Warn("truncating partial last log entry", "path", path, "bytes", len(line))
+				if err := os.Truncate(path, complete); err != nil {
+					return nil, fmt.Errorf("failed to truncate partial log entry: %v", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("failed to read log entry: %v", err)
+		}
+		l.lastSeq = entry.Seq
+		complete += int64(len(line))
+	}
+
+	l.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.logLock.Lock()
+	defer l.logLock.Unlock()
+	return l.file.Close()
+}
+
+// Base returns the sequence number before the first entry in the log
+func (l *Log) Base() uint64 {
+	l.logLock.Lock()
+	defer l.logLock.Unlock()
+	return l.base
+}
+
+// LastSeq returns the sequence number of the last entry
+func (l *Log) LastSeq() uint64 {
+	l.logLock.Lock()
+	defer l.logLock.Unlock()
+	return l.lastSeq
+}
+
+// NextSeq returns the sequence number the next entry will get
+func (l *Log) NextSeq() uint64 {
+	return l.LastSeq() + 1
+}
+
+// Append writes an encoded command to the end of the log
+func (l *Log) Append(epoch uint64, command []byte) (uint64, error) {
+	l.logLock.Lock()
+	defer l.logLock.Unlock()
+
+	entry := Entry{Seq: l.lastSeq + 1, Epoch: epoch, Command: command}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to append to log: %v", err)
+	}
+	l.lastSeq = entry.Seq
+	close(l.appended)
+	l.appended = make(chan struct{})
+	return entry.Seq, nil
+}
+
+// Compact drops the entries up to and including seq by rewriting the log
+// after a new base. Standbys that have not applied them yet get the whole
+// state instead.
+func (l *Log) Compact(seq uint64) error {
+	l.logLock.Lock()
+	defer l.logLock.Unlock()
+
+	seq = min(seq, l.lastSeq)
+	if seq <= l.base {
+		return nil
+	}
+
+	src, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	reader := bufio.NewReader(src)
+	if _, err := reader.ReadBytes('\n'); err != nil {
+		return fmt.Errorf("failed to read log header: %v", err)
+	}
+
+	tmpFile := l.path + ".tmp"
+	dst, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile)
+	header, err := json.Marshal(logHeader{Base: seq})
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	writer := bufio.NewWriter(dst)
+	writer.Write(append(header, '\n'))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			dst.Close()
+			return err
+		}
+		var entry struct {
+			Seq uint64 `json:"seq"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			dst.Close()
+			return fmt.Errorf("failed to read log entry: %v", err)
+		}
+		if entry.Seq > seq {
+			writer.Write(line)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to write compacted log: %v", err)
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, l.path); err != nil {
+		return fmt.Errorf("failed to replace log: %v", err)
+	}
+	if err := store.SyncDir(filepath.Dir(l.path)); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	l.file.Close()
+	l.file = file
+	l.base = seq
+	l.generation++
+	close(l.appended)
+	l.appended = make(chan struct{})
+	return nil
+}
+
+// waitChan returns a channel closed by the next Append or Compact, and the
+// generation of the file
+func (l *Log) waitChan() (chan struct{}, uint64) {
+	l.logLock.Lock()
+	defer l.logLock.Unlock()
+	return l.appended, l.generation
+}
+
+// open opens the log file for reading past its header
+func (l *Log) open() (*os.File, *bufio.Reader, uint64, uint64, error) {
+	l.logLock.Lock()
+	defer l.logLock.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	reader := bufio.NewReader(file)
+	if _, err := reader.ReadBytes('\n'); err != nil {
+		file.Close()
+		return nil, nil, 0, 0, fmt.Errorf("failed to read log header: %v", err)
+	}
+	return file, reader, l.base, l.generation, nil
+}
+
+// Follow calls fn for every entry after seq, then waits for new entries
+// until ctx is done or fn returns an error. It returns ErrCompacted if the
+// log is compacted past entries it has not sent.
+func (l *Log) Follow(ctx context.Context, seq uint64, fn func(*Entry) error) error {
+	file, reader, base, generation, err := l.open()
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+	if seq < base {
+		return fmt.Errorf("%w: at %d, log starts after %d", ErrCompacted, seq, base)
+	}
+
+	var partial []byte
+	for {
+		// Take the channel before reading so an append in between is not missed
+		wait, current := l.waitChan()
+		if current != generation {
+			// Compact replaced the file; continue in the new one
+			file.Close()
+			file, reader, base, generation, err = l.open()
+			if err != nil {
+				return err
+			}
+			if seq < base {
+				return fmt.Errorf("%w: at %d, log starts after %d", ErrCompacted, seq, base)
+			}
+			partial = nil
+		}
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err == io.EOF {
+				partial = append(partial, line...)
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if len(partial) > 0 {
+				line = append(partial, line...)
+				partial = nil
+			}
+			var entry Entry
+			if err := json.Unmarshal(bytes.TrimSpace(line), &entry); err != nil {
+				return fmt.Errorf("failed to read log entry: %v", err)
+			}
+			if entry.Seq <= seq {
+				continue
+			}
+			if err := fn(&entry); err != nil {
+				return err
+			}
+			seq = entry.Seq
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wait:
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package logship
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func appendEntries(t *testing.T, l *Log, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		if _, err := l.Append(1, []byte(`{"op":"create_task","task_id":"task"}`)); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+}
+
+// entries returns the sequence numbers Follow sends after seq, stopping
+// after want entries
+func entries(t *testing.T, l *Log, seq uint64, want int) []uint64 {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var seqs []uint64
+	err := l.Follow(ctx, seq, func(entry *Entry) error {
+		seqs = append(seqs, entry.Seq)
+		if len(seqs) == want {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Follow: %v", err)
+	}
+	return seqs
+}
+
+func TestOpenLogTruncatesPartialEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	l, err := OpenLog(path, 0)
+	if err != nil {
+		t.Fatalf("OpenLog: %v", err)
+	}
+	appendEntries(t, l, 2)
+	l.Close()
+
+	// A crash cut the third entry short
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"seq":3,"epoch":1,"comm`)
+	file.Close()
+
+	l, err = OpenLog(path, 0)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer func() { l.Close() }()
+	if l.LastSeq() != 2 {
+		t.Fatalf("LastSeq = %d, want 2", l.LastSeq())
+	}
+	appendEntries(t, l, 1)
+	if got := entries(t, l, 0, 3); len(got) != 3 || got[2] != 3 {
+		t.Errorf("entries = %v, want [1 2 3]", got)
+	}
+
+	// The repaired log opens cleanly again
+	l.Close()
+	l, err = OpenLog(path, 0)
+	if err != nil {
+		t.Fatalf("reopening the repaired log: %v", err)
+	}
+	if l.LastSeq() != 3 {
+		t.Errorf("LastSeq = %d, want 3", l.LastSeq())
+	}
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	l, err := OpenLog(path, 0)
+	if err != nil {
+		t.Fatalf("OpenLog: %v", err)
+	}
+	defer func() { l.Close() }()
+	appendEntries(t, l, 5)
+
+	// A follower streaming while the log is compacted
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	streaming := make(chan error, 1)
+	received := make(chan uint64, 10)
+	go func() {
+		streaming <- l.Follow(ctx, 1, func(entry *Entry) error {
+			received <- entry.Seq
+			return nil
+		})
+	}()
+	for seq := uint64(2); seq <= 5; seq++ {
+		if got := <-received; got != seq {
+			t.Fatalf("received %d, want %d", got, seq)
+		}
+	}
+
+	if err := l.Compact(3); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if l.Base() != 3 || l.LastSeq() != 5 {
+		t.Errorf("base, last = %d, %d, want 3, 5", l.Base(), l.LastSeq())
+	}
+	// The follower had sent every entry before compaction, so it continues
+	// in the new file
+	appendEntries(t, l, 1)
+	select {
+	case got := <-received:
+		if got != 6 {
+			t.Errorf("received %d after compaction, want 6", got)
+		}
+	case err := <-streaming:
+		t.Fatalf("follower stopped: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("follower did not receive the entry appended after compaction")
+	}
+	if got := entries(t, l, 3, 3); len(got) != 3 || got[0] != 4 || got[2] != 6 {
+		t.Errorf("entries after compaction = %v, want [4 5 6]", got)
+	}
+
+	// Reopening keeps the new base
+	l.Close()
+	l, err = OpenLog(path, 0)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	if l.Base() != 3 || l.LastSeq() != 6 {
+		t.Errorf("after reopening base, last = %d, %d, want 3, 6", l.Base(), l.LastSeq())
+	}
+}
+
+func TestFollowBehindCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	l, err := OpenLog(path, 0)
+	if err != nil {
+		t.Fatalf("OpenLog: %v", err)
+	}
+	defer func() { l.Close() }()
+	appendEntries(t, l, 5)
+	if err := l.Compact(3); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	err = l.Follow(context.Background(), 1, func(entry *Entry) error {
+		t.Errorf("sent entry %d of a compacted range", entry.Seq)
+		return nil
+	})
+	if !errors.Is(err, ErrCompacted) {
+		t.Errorf("Follow error = %v, want ErrCompacted", err)
+	}
+}
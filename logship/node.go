@@ -0,0 +1,560 @@
+package logship
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Roles a node can be in
+const (
+	// ROLE_PRIMARY applies writes and ships its log to standbys
+	ROLE_PRIMARY = "primary"
+	// ROLE_STANDBY applies the primary's log and only serves reads
+	ROLE_STANDBY = "standby"
+	// ROLE_FENCED is a primary that learned of a newer epoch. It only serves
+	// reads until it is restarted as a standby.
+	ROLE_FENCED = "fenced"
+)
+
+// ErrStreamsClosed is returned by Stream once CloseStreams was called
+var ErrStreamsClosed = errors.New("log streams are closed for shutdown")
+
+// DEFAULT_RETRY_INTERVAL is how long a standby waits before reconnecting to the primary
+const DEFAULT_RETRY_INTERVAL = 2 * time.Second
+
+// EPOCH_FILE is the name of the fencing epoch file in the metadata directory
+const EPOCH_FILE = "epoch.json"
+
+// Config configures a log shipping node
+type Config struct {
+	// DataDir holds the mutation log and the applied sequence number
+	DataDir string
+	// MetadataDir holds the fencing epoch
+	MetadataDir string
+	// PrimaryAddr is the gRPC address of the primary to follow. The node
+	// starts as the primary when it is empty.
+	PrimaryAddr string
+	// RetryInterval defaults to DEFAULT_RETRY_INTERVAL
+	RetryInterval time.Duration
+	// ClientTLS secures connections to the primary when set
+	ClientTLS *tls.Config
+}
+
+type epochState struct {
+	Epoch  uint64 `json:"epoch"`
+	Fenced bool   `json:"fenced,omitempty"`
+}
+
+type appliedSeq struct {
+	Seq uint64 `json:"seq"`
+}
+
+// Node replicates a TaskManager by log shipping. It implements
+// managers.Replicator: on the primary every command is applied and then
+// appended to the log, which standbys stream and apply asynchronously.
+// Leases carry the epoch they were granted in, and a promoted standby
+// releases every lease from older epochs.
+type Node struct {
+	config      Config
+	taskManager *managers.TaskManager
+	role        string
+	epoch       uint64
+	appliedSeq  uint64
+	log         *Log
+	// compactAt is the last sequence number at the previous compaction
+	compactAt uint64
+	// stopFollowing stops a standby's log stream
+	stopFollowing context.CancelFunc
+	// streams is cancelled by CloseStreams to end the streams to standbys
+	streams      context.Context
+	closeStreams context.CancelFunc
+	// streaming is set while a standby is streaming the primary's log
+	streaming bool
+	nodeLock  *sync.Mutex
+}
+
+// NewNode starts a log shipping node for the given TaskManager.
+// The TaskManager must already have loaded its state from disk.
+func NewNode(config Config, taskManager *managers.TaskManager) (*Node, error) {
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DEFAULT_RETRY_INTERVAL
+	}
+	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log shipping directory: %v", err)
+	}
+
+	n := &Node{
+		config:      config,
+		taskManager: taskManager,
+		nodeLock:    &sync.Mutex{},
+	}
+	n.streams, n.closeStreams = context.WithCancel(context.Background())
+	state, err := n.loadEpoch()
+	if err != nil {
+		return nil, err
+	}
+	n.epoch = state.Epoch
+	if err := n.loadApplied(); err != nil {
+		return nil, err
+	}
+
+	if config.PrimaryAddr != "" {
+		n.role = ROLE_STANDBY
+		if state.Fenced {
+			// Rejoining as a standby is how a fenced primary recovers
+			if err := n.saveEpoch(); err != nil {
+				return nil, err
+			}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		n.stopFollowing = cancel
+		go n.follow(ctx)
+		return n, nil
+	}
+
+	n.role = ROLE_PRIMARY
+	if state.Fenced {
+		n.role = ROLE_FENCED
+	}
+	if n.epoch == 0 {
+		n.epoch = 1
+		if err := n.saveEpoch(); err != nil {
+			return nil, err
+		}
+	}
+	n.log, err = OpenLog(n.logPath(), n.appliedSeq)
+	if err != nil {
+		return nil, err
+	}
+	n.appliedSeq = n.log.LastSeq()
+	return n, nil
+}
+
+func (n *Node) logPath() string {
+	return filepath.Join(n.config.DataDir, "log.jsonl")
+}
+
+func (n *Node) loadEpoch() (epochState, error) {
+	var state epochState
+	data, err := os.ReadFile(filepath.Join(n.config.MetadataDir, EPOCH_FILE))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to read epoch: %v", err)
+	}
+	return state, nil
+}
+
+func (n *Node) saveEpoch() error {
+	return writeJSON(filepath.Join(n.config.MetadataDir, EPOCH_FILE), epochState{Epoch: n.epoch, Fenced: n.role == ROLE_FENCED})
+}
+
+func (n *Node) loadApplied() error {
+	data, err := os.ReadFile(filepath.Join(n.config.DataDir, "applied.json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var applied appliedSeq
+	if err := json.Unmarshal(data, &applied); err != nil {
+		return fmt.Errorf("failed to read applied sequence: %v", err)
+	}
+	n.appliedSeq = applied.Seq
+	return nil
+}
+
+func (n *Node) saveApplied() error {
+	return writeJSON(filepath.Join(n.config.DataDir, "applied.json"), appliedSeq{Seq: n.appliedSeq})
+}
+
+// writeJSON replaces a file through a rename so it is never left half written
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, path)
+}
+
+// Role returns the node's current role
+func (n *Node) Role() string {
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+	return n.role
+}
+
+// Status returns the node's role, epoch and last applied sequence number
+func (n *Node) Status() (role string, epoch uint64, seq uint64) {
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+	return n.role, n.epoch, n.appliedSeq
+}
+
+// PrimaryAddr returns the address of the primary a standby follows
+func (n *Node) PrimaryAddr() string {
+	return n.config.PrimaryAddr
+}
+
+// IsLeader implements managers.Replicator
+func (n *Node) IsLeader() bool {
+	return n.Role() == ROLE_PRIMARY
+}
+
+// Propose implements managers.Replicator. Commands are applied and logged
+// one at a time so the log order is the order they were applied in.
+func (n *Node) Propose(cmd *managers.Command) (*managers.CommandResult, error) {
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+
+	if n.role != ROLE_PRIMARY {
+		return nil, fmt.Errorf("node is a read-only %s", n.role)
+	}
+	cmd.Term = n.epoch
+	cmd.Index = n.log.NextSeq()
+
+	// Encoded before applying, so a command that cannot be logged is not applied either
+	command, err := n.taskManager.EncodeCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+	result := n.taskManager.Apply(cmd)
+	seq, err := n.log.Append(n.epoch, command)
+	if err != nil {
+		return nil, err
+	}
+	n.appliedSeq = seq
+	return result, nil
+}
+
+// CompactLog drops the entries that were already in the log at the previous
+// compaction, so standbys have until the next one to catch up before they
+// need the whole state again. It is called after every snapshot.
+func (n *Node) CompactLog() error {
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+
+	if n.role != ROLE_PRIMARY {
+		return nil
+	}
+	if err := n.log.Compact(n.compactAt); err != nil {
+		return fmt.Errorf("failed to compact log: %v", err)
+	}
+	slog.Info("compacted log", "base", n.log.Base(), "last_seq", n.log.LastSeq())
+	n.compactAt = n.log.LastSeq()
+	return nil
+}
+
+// Stream sends the log entries after afterSeq to a standby, and keeps sending
+// new entries until ctx is done. A standby that is new, too far behind, or
+// from another epoch gets the whole state first.
+func (n *Node) Stream(ctx context.Context, afterSeq uint64, epoch uint64, send func(entry *Entry, state []byte) error) error {
+	if n.streams.Err() != nil {
+		return ErrStreamsClosed
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(n.streams, cancel)()
+
+	n.nodeLock.Lock()
+	if epoch > n.epoch {
+		n.fenceLocked(epoch)
+		n.nodeLock.Unlock()
+		return fmt.Errorf("standby is ahead at epoch %d", epoch)
+	}
+	if n.role != ROLE_PRIMARY {
+		n.nodeLock.Unlock()
+		return fmt.Errorf("node is not the primary")
+	}
+
+	currentEpoch := n.epoch
+	if afterSeq == 0 || afterSeq < n.log.Base() || afterSeq > n.log.LastSeq() || epoch != currentEpoch {
+		// Proposals are held off while the state is copied, so it matches the sequence number
+		state, err := n.taskManager.ExportSealedState()
+		afterSeq = n.log.LastSeq()
+		n.nodeLock.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to export state: %v", err)
+		}
+		if err := send(&Entry{Seq: afterSeq, Epoch: currentEpoch}, state); err != nil {
+			return err
+		}
+	} else {
+		n.nodeLock.Unlock()
+	}
+
+	err := n.log.Follow(ctx, afterSeq, func(entry *Entry) error {
+		return send(entry, nil)
+	})
+	if n.streams.Err() != nil {
+		return ErrStreamsClosed
+	}
+	return err
+}
+
+// CloseStreams ends the log streams to standbys and refuses new ones, so
+// stopping the gRPC server gracefully does not wait for them. Standbys
+// resume from the entries they applied when the primary is back.
+func (n *Node) CloseStreams() {
+	n.closeStreams()
+}
+
+// follow streams the primary's log until the node is promoted
+func (n *Node) follow(ctx context.Context) {
+	for {
+		err := n.streamFromPrimary(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("log stream ended", "primary", n.config.PrimaryAddr, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(n.config.RetryInterval):
+		}
+	}
+}
+
+func (n *Node) streamFromPrimary(ctx context.Context) error {
+	conn, err := n.dialPrimary()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, epoch, seq := n.Status()
+	stream, err := taskpb.NewStandbyServiceClient(conn).StreamLog(ctx, &taskpb.StreamLogRequest{AfterSeq: seq, Epoch: epoch})
+	if err != nil {
+		return err
+	}
+	n.setStreaming(true)
+	defer n.setStreaming(false)
+	for {
+		entry, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := n.applyEntry(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// applyEntry applies one entry streamed from the primary
+func (n *Node) applyEntry(entry *taskpb.LogEntry) error {
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+
+	if n.role != ROLE_STANDBY {
+		return fmt.Errorf("node is no longer a standby")
+	}
+	if entry.State != nil {
+		if err := n.taskManager.RestoreState(entry.State); err != nil {
+			return fmt.Errorf("failed to restore state: %v", err)
+		}
+	} else {
+		if entry.Seq <= n.appliedSeq {
+			return nil
+		}
+		cmd, err := n.taskManager.DecodeCommand(entry.Command)
+		if err != nil {
+			return err
+		}
+		cmd.Term = entry.Epoch
+		cmd.Index = entry.Seq
+		n.taskManager.Apply(cmd)
+	}
+
+	n.appliedSeq = entry.Seq
+	if err := n.saveApplied(); err != nil {
+		return fmt.Errorf("failed to save applied sequence: %v", err)
+	}
+	if entry.Epoch != n.epoch {
+		n.epoch = entry.Epoch
+		if err := n.saveEpoch(); err != nil {
+			return fmt.Errorf("failed to save epoch: %v", err)
+		}
+	}
+	return nil
+}
+
+// Promote makes a standby the primary under the next epoch. Leases granted
+// by the old primary are released, and the old primary is told to step
+// down if it can still be reached.
+func (n *Node) Promote() error {
+	n.nodeLock.Lock()
+	if n.role != ROLE_STANDBY {
+		n.nodeLock.Unlock()
+		return fmt.Errorf("only a standby can be promoted, node is %s", n.role)
+	}
+	n.stopFollowing()
+
+	// Start a fresh log after the last entry this node applied
+	if err := os.Remove(n.logPath()); err != nil && !os.IsNotExist(err) {
+		n.nodeLock.Unlock()
+		return err
+	}
+	log, err := OpenLog(n.logPath(), n.appliedSeq)
+	if err != nil {
+		n.nodeLock.Unlock()
+		return err
+	}
+	n.log = log
+	n.epoch++
+	n.role = ROLE_PRIMARY
+	if err := n.saveEpoch(); err != nil {
+		n.nodeLock.Unlock()
+		return fmt.Errorf("failed to save epoch: %v", err)
+	}
+	epoch := n.epoch
+	n.nodeLock.Unlock()
+
+	if err := n.taskManager.FenceLeases(); err != nil {
+		return fmt.Errorf("failed to fence leases: %v", err)
+	}
+	n.fencePrimary(epoch)
+	return nil
+}
+
+// dialPrimary connects to the primary's gRPC server
+func (n *Node) dialPrimary() (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if n.config.ClientTLS != nil {
+		creds = credentials.NewTLS(n.config.ClientTLS)
+	}
+	return grpc.NewClient(n.config.PrimaryAddr, grpc.WithTransportCredentials(creds))
+}
+
+// FetchBlob implements managers.BlobFetcher. Payloads are stored on the
+// primary, so a standby fetches the blobs its log entries refer to from it.
+func (n *Node) FetchBlob(digest string) (io.ReadCloser, error) {
+	if n.config.PrimaryAddr == "" {
+		return nil, fmt.Errorf("no primary to fetch blob %s from", digest)
+	}
+	conn, err := n.dialPrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), blobs.FETCH_TIMEOUT)
+	closeAll := func() {
+		cancel()
+		conn.Close()
+	}
+	stream, err := taskpb.NewStandbyServiceClient(conn).FetchBlob(ctx, &taskpb.FetchBlobRequest{Digest: digest})
+	if err != nil {
+		closeAll()
+		return nil, fmt.Errorf("failed to fetch blob from the primary: %v", err)
+	}
+	// The first chunk tells whether the primary holds the blob
+	first, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		closeAll()
+		return nil, fmt.Errorf("failed to fetch blob from the primary: %v", err)
+	}
+	recv := func() ([]byte, error) {
+		chunk, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		return chunk.Data, nil
+	}
+	return blobs.NewChunkReader(first.GetData(), recv, closeAll), nil
+}
+
+// fencePrimary tells the old primary about the new epoch. It is best effort:
+// an unreachable primary fences itself when it next sees the new epoch.
+func (n *Node) fencePrimary(epoch uint64) {
+	conn, err := n.dialPrimary()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := taskpb.NewStandbyServiceClient(conn).Fence(ctx, &taskpb.FenceRequest{Epoch: epoch}); err != nil {
+		slog.Warn("could not fence old primary", "primary", n.config.PrimaryAddr, "error", err)
+	}
+}
+
+// Fence makes a primary read-only if the given epoch is newer than its own
+func (n *Node) Fence(epoch uint64) error {
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+	return n.fenceLocked(epoch)
+}
+
+func (n *Node) fenceLocked(epoch uint64) error {
+	if epoch <= n.epoch {
+		return nil
+	}
+	n.epoch = epoch
+	if n.role == ROLE_PRIMARY {
+		n.role = ROLE_FENCED
+	}
+	return n.saveEpoch()
+}
+
+func (n *Node) setStreaming(streaming bool) {
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+	n.streaming = streaming
+}
+
+// Check returns an error if the node is a fenced primary, or a standby
+// that is not streaming the primary's log. A streaming standby applies
+// entries as the primary writes them.
+func (n *Node) Check() error {
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+
+	switch {
+	case n.role == ROLE_FENCED:
+		return fmt.Errorf("fenced by a primary with a newer epoch")
+	case n.role == ROLE_STANDBY && !n.streaming:
+		return fmt.Errorf("not streaming the log of primary %s", n.config.PrimaryAddr)
+	}
+	return nil
+}
+
+// Shutdown closes the streams to standbys, stops following the primary
+// and closes the log
+func (n *Node) Shutdown() error {
+	n.closeStreams()
+	n.nodeLock.Lock()
+	defer n.nodeLock.Unlock()
+
+	if n.stopFollowing != nil {
+		n.stopFollowing()
+	}
+	if n.log != nil {
+		return n.log.Close()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package logship
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/archive"
+	"github.com/indkumar8999/ps-tasks/managers"
+)
+
+// newTestPrimary starts a primary replicating a task manager stored in a
+// temporary directory
+func newTestPrimary(t *testing.T) *Node {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tasks"), 0755); err != nil {
+		t.Fatalf("failed to create tasks directory: %v", err)
+	}
+	leaseManager, err := managers.NewLeaseManager(filepath.Join(dir, "leases"))
+	if err != nil {
+		t.Fatalf("failed to create lease manager: %v", err)
+	}
+	queueManager, err := managers.NewQueueManager(filepath.Join(dir, "metadata", "queues"))
+	if err != nil {
+		t.Fatalf("failed to create queue manager: %v", err)
+	}
+	taskArchive, err := archive.NewArchive(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	shardManager := managers.NewShardManager(filepath.Join(dir, "metadata"))
+	taskManager := managers.NewTaskManager(filepath.Join(dir, "tasks"), leaseManager, queueManager, shardManager, taskArchive)
+	node, err := NewNode(Config{DataDir: filepath.Join(dir, "logship"), MetadataDir: filepath.Join(dir, "metadata")}, taskManager)
+	if err != nil {
+		t.Fatalf("NewNode: %v", err)
+	}
+	taskManager.SetReplicator(node)
+	t.Cleanup(func() { node.Shutdown() })
+	return node
+}
+
+func TestCloseStreams(t *testing.T) {
+	node := newTestPrimary(t)
+	if _, err := node.taskManager.CreateTask("task", "", "default", []byte("input"), nil); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// A standby that is caught up keeps streaming until the streams are closed
+	received := make(chan uint64, 10)
+	streaming := make(chan error, 1)
+	go func() {
+		streaming <- node.Stream(context.Background(), 0, 0, func(entry *Entry, state []byte) error {
+			received <- entry.Seq
+			return nil
+		})
+	}()
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatalf("standby did not receive the state")
+	}
+
+	node.CloseStreams()
+	select {
+	case err := <-streaming:
+		if !errors.Is(err, ErrStreamsClosed) {
+			t.Errorf("Stream error = %v, want ErrStreamsClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("stream did not end after CloseStreams")
+	}
+	err := node.Stream(context.Background(), 0, 0, func(entry *Entry, state []byte) error {
+		t.Errorf("sent entry %d after CloseStreams", entry.Seq)
+		return nil
+	})
+	if !errors.Is(err, ErrStreamsClosed) {
+		t.Errorf("Stream after CloseStreams error = %v, want ErrStreamsClosed", err)
+	}
+}
@@ -1,15 +1,41 @@
 package main
 
 import (
+	"crypto/tls"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"path/filepath"
 	"net"
-	"log"
+	"log/slog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"github.com/indkumar8999/ps-tasks/keyring"
+	"github.com/indkumar8999/ps-tasks/logging"
+	"github.com/indkumar8999/ps-tasks/logship"
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/indkumar8999/ps-tasks/migrate"
 	"github.com/indkumar8999/ps-tasks/managers"
 	"github.com/indkumar8999/ps-tasks/service"
+	"github.com/indkumar8999/ps-tasks/admission"
+	"github.com/indkumar8999/ps-tasks/archive"
+	"github.com/indkumar8999/ps-tasks/auth"
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/cluster"
+	"github.com/indkumar8999/ps-tasks/config"
+	"github.com/indkumar8999/ps-tasks/health"
+	"github.com/indkumar8999/ps-tasks/snapshot"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/tlsconfig"
+	"github.com/indkumar8999/ps-tasks/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -18,17 +44,16 @@ const (
 	TASKS_DIR = "tasks"
 )
 
-func GetOrCreateDBPath(cwd string) string {
-	dbPath := filepath.Join(cwd, "database")
+func GetOrCreateDBPath(dbPath string) string {
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
 		err := os.MkdirAll(dbPath, os.ModePerm)
 		if err != nil {
-			fmt.Println("Error creating database directory:", err)
-			return ""
+			slog.Error("failed to create database directory", "error", err)
+			os.Exit(1)
 		}
-		fmt.Println("Database directory created:", dbPath)
+		slog.Info("database directory created", "path", dbPath)
 	} else {
-		fmt.Println("Database directory already exists:", dbPath)
+		slog.Info("database directory already exists", "path", dbPath)
 	}
 	return dbPath
 }
@@ -39,12 +64,12 @@ func GetOrCreateMetadataPath(dbPath string) string {
 	if _, err := os.Stat(metadataPath); os.IsNotExist(err) {
 		err := os.MkdirAll(metadataPath, os.ModePerm)
 		if err != nil {
-			fmt.Println("Error creating metadata directory:", err)
-			return ""
+			slog.Error("failed to create metadata directory", "error", err)
+			os.Exit(1)
 		}
-		fmt.Println("Metadata directory created:", metadataPath)
+		slog.Info("metadata directory created", "path", metadataPath)
 	} else {
-		fmt.Println("Metadata directory already exists:", metadataPath)
+		slog.Info("metadata directory already exists", "path", metadataPath)
 	}
 	return metadataPath
 }
@@ -55,12 +80,12 @@ func GetOrCreateLeasesPath(dbPath string) string {
 	if _, err := os.Stat(leasesPath); os.IsNotExist(err) {
 		err := os.MkdirAll(leasesPath, os.ModePerm)
 		if err != nil {
-			fmt.Println("Error creating leases directory:", err)
-			return ""
+			slog.Error("failed to create leases directory", "error", err)
+			os.Exit(1)
 		}
-		fmt.Println("Leases directory created:", leasesPath)
+		slog.Info("leases directory created", "path", leasesPath)
 	} else {
-		fmt.Println("Leases directory already exists:", leasesPath)
+		slog.Info("leases directory already exists", "path", leasesPath)
 	}
 	return leasesPath
 }
@@ -71,67 +96,504 @@ func GetOrCreateTasksPath(dbPath string) string {
 	if _, err := os.Stat(tasksPath); os.IsNotExist(err) {
 		err := os.MkdirAll(tasksPath, os.ModePerm)
 		if err != nil {
-			fmt.Println("Error creating tasks directory:", err)
-			return ""
+			slog.Error("failed to create tasks directory", "error", err)
+			os.Exit(1)
 		}
-		fmt.Println("Tasks directory created:", tasksPath)
+		slog.Info("tasks directory created", "path", tasksPath)
 	} else {
-		fmt.Println("Tasks directory already exists:", tasksPath)
+		slog.Info("tasks directory already exists", "path", tasksPath)
 	}
 	return tasksPath
 }
 
 
 func main() {
+	loader := config.Register(flag.CommandLine)
+	flag.Parse()
 
-	// current directory
-	cwd, err := os.Getwd()
+	cfg, err := loader.Load()
 	if err != nil {
-		fmt.Println("Error getting current directory:", err)
-		return
+		fmt.Fprintln(os.Stderr, "Error loading configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	dbPath := GetOrCreateDBPath(cwd)
-	_ = GetOrCreateMetadataPath(dbPath)
+	// The level is a variable so SIGHUP can change it
+	logLevel := &slog.LevelVar{}
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logLevel.Set(level)
+	logger, err := logging.New(os.Stderr, logLevel, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error configuring logging:", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	instance := cfg.Cluster.NodeID
+	if instance == "" {
+		instance = cfg.Listen.RPC
+	}
+	tracer, err := tracing.Setup(tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		Insecure:    cfg.Tracing.OTLPInsecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		Attributes:  []attribute.KeyValue{attribute.String("service.instance.id", instance)},
+	})
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	dbPath := GetOrCreateDBPath(cfg.Data.Dir)
+	metadataPath := GetOrCreateMetadataPath(dbPath)
 	leasesPath := GetOrCreateLeasesPath(dbPath)
 	tasksPath := GetOrCreateTasksPath(dbPath)
 
+	// Records written by older versions are upgraded before anything loads them
+	migrationReport, err := migrate.Run(dbPath, migrate.Options{DryRun: cfg.Data.NoAutoMigrate, Backup: true})
+	if err != nil {
+		slog.Error("failed to migrate database", "error", err)
+		os.Exit(1)
+	}
+	if len(migrationReport.Changes) > 0 {
+		if cfg.Data.NoAutoMigrate {
+			slog.Error("records need migrating; run cmd/migrate or start without -no-auto-migrate", "records", len(migrationReport.Changes))
+			os.Exit(1)
+		}
+		slog.Info("migrated records", "records", len(migrationReport.Changes), "backup", migrationReport.BackupDir)
+	}
+
 	leaseManager, err := managers.NewLeaseManager(leasesPath)
 	if err != nil {
-		fmt.Println("Error creating lease manager:", err)
-		return
+		slog.Error("failed to create lease manager", "error", err)
+		os.Exit(1)
+	}
+	leaseManager.SetLogger(logger)
+	// Unreadable records are set aside in database/quarantine so the server can start
+	var quarantine *store.Quarantine
+	if !cfg.Data.StrictLoad {
+		quarantine = store.NewQuarantine(filepath.Join(dbPath, "quarantine"))
+	}
+	if err := leaseManager.LoadLeases(quarantine); err != nil {
+		slog.Error("failed to load leases", "error", err)
+		os.Exit(1)
+	}
+
+	queueManager, err := managers.NewQueueManager(filepath.Join(metadataPath, "queues"))
+	if err != nil {
+		slog.Error("failed to create queue manager", "error", err)
+		os.Exit(1)
+	}
+	if err := queueManager.LoadQueues(quarantine); err != nil {
+		slog.Error("failed to load queues", "error", err)
+		os.Exit(1)
+	}
+	queueManager.SetDefaultRetention(cfg.DefaultRetention())
+
+	taskArchive, err := archive.NewArchive(filepath.Join(dbPath, "archive"))
+	if err != nil {
+		slog.Error("failed to create task archive", "error", err)
+		os.Exit(1)
+	}
+
+	shardManager := managers.NewShardManager(metadataPath)
+	if err := shardManager.LoadShardMap(); err != nil {
+		slog.Error("failed to load shard map", "error", err)
+		os.Exit(1)
+	}
+
+	taskManager := managers.NewTaskManager(tasksPath, leaseManager, queueManager, shardManager, taskArchive)
+	taskManager.SetLogger(logger)
+	taskManager.SetSettings(cfg.Settings())
+	if cfg.Keyring.Path != "" {
+		keys, err := keyring.Load(cfg.Keyring.Path)
+		if err != nil {
+			slog.Error("failed to load keyring", "error", err)
+			os.Exit(1)
+		}
+		taskManager.SetKeyring(keys)
+		slog.Info("encrypting task payloads", "key", keys.Primary())
+	}
+	blobStore, err := blobs.NewStore(filepath.Join(dbPath, "blobs"), taskManager.Keyring())
+	if err != nil {
+		slog.Error("failed to create blob store", "error", err)
+		os.Exit(1)
+	}
+	taskManager.SetBlobStore(blobStore, cfg.Blobs.Threshold)
+	if err := taskManager.LoadTasks(quarantine); err != nil {
+		slog.Error("failed to load tasks", "error", err)
+		os.Exit(1)
+	}
+
+	if quarantine != nil {
+		report, err := quarantine.WriteReport()
+		if err != nil {
+			slog.Error("failed to write quarantine report", "error", err)
+			os.Exit(1)
+		}
+		if report != "" {
+			slog.Warn("quarantined unreadable records", "records", len(quarantine.Files()), "report", report)
+		}
+	}
+
+	// Nodes connect to each other with the server certificate, so peers that
+	// require client certificates accept them
+	var serverTLS, peerTLS *tls.Config
+	if cfg.TLS.Cert != "" {
+		serverTLS, _, err = tlsconfig.ServerConfig(tlsconfig.ServerOptions{
+			CertFile:           cfg.TLS.Cert,
+			KeyFile:            cfg.TLS.Key,
+			ClientCAFile:       cfg.TLS.ClientCA,
+			ClientCertOptional: cfg.TLS.ClientCertOptional,
+			ReloadInterval:     cfg.TLS.ReloadInterval,
+		})
+		if err != nil {
+			slog.Error("failed to load TLS certificate", "error", err)
+			os.Exit(1)
+		}
+		peerCA := cfg.TLS.PeerCA
+		if peerCA == "" {
+			peerCA = cfg.TLS.ClientCA
+		}
+		peerTLS, _, err = tlsconfig.ClientConfig(tlsconfig.ClientOptions{
+			CAFile:         peerCA,
+			CertFile:       cfg.TLS.Cert,
+			KeyFile:        cfg.TLS.Key,
+			ReloadInterval: cfg.TLS.ReloadInterval,
+		})
+		if err != nil {
+			slog.Error("failed to load TLS peer configuration", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Policy != "" {
+		authenticator, err = auth.NewAuthenticator(cfg.Auth.TokenKeys, cfg.Auth.Policy)
+		if err != nil {
+			slog.Error("failed to load auth configuration", "error", err)
+			os.Exit(1)
+		}
+		go authenticator.PeriodicallyReload(cfg.Auth.ReloadInterval)
+	}
+
+	// The rate limiter and admission controller are always installed so
+	// SIGHUP can turn their limits on
+	limits, err := loadLimits(cfg.Limits.RateLimits)
+	if err != nil {
+		slog.Error("failed to load rate limits", "error", err)
+		os.Exit(1)
+	}
+	rateLimiter := admission.NewRateLimiter(limits)
+
+	controller := admission.NewController(admission.Config{
+		MaxPendingTasks: cfg.Limits.MaxPendingTasks,
+		MaxDiskUsage:    cfg.Limits.MaxDiskUsage / 100,
+		DiskPath:        dbPath,
+		Methods: []string{
+			taskpb.TaskService_CreateTask_FullMethodName,
+			taskpb.TaskService_UploadPayload_FullMethodName,
+		},
+		SampleInterval: cfg.Limits.AdmissionInterval,
+	}, taskManager.PendingTasks)
+	go controller.PeriodicallySample()
+
+	var node *cluster.Node
+	if cfg.Cluster.NodeID != "" {
+		clusterPeers, err := cluster.ParsePeers(cfg.Cluster.Peers)
+		if err != nil {
+			slog.Error("failed to parse cluster peers", "error", err)
+			os.Exit(1)
+		}
+		node, err = cluster.NewNode(cluster.Config{
+			NodeID:    cfg.Cluster.NodeID,
+			RaftAddr:  cfg.Cluster.RaftAddr,
+			DataDir:   filepath.Join(dbPath, "raft"),
+			Peers:     clusterPeers,
+			Bootstrap: cfg.Cluster.Bootstrap,
+			ServerTLS: serverTLS,
+			ClientTLS: peerTLS,
+		}, taskManager)
+		if err != nil {
+			slog.Error("failed to start cluster node", "error", err)
+			os.Exit(1)
+		}
+		taskManager.SetReplicator(node)
+	}
+
+	var shipper *logship.Node
+	if cfg.Replication.LogShipping || cfg.Replication.StandbyOf != "" {
+		shipper, err = logship.NewNode(logship.Config{
+			DataDir:     filepath.Join(dbPath, "logship"),
+			MetadataDir: metadataPath,
+			PrimaryAddr: cfg.Replication.StandbyOf,
+			ClientTLS:   peerTLS,
+		}, taskManager)
+		if err != nil {
+			slog.Error("failed to start log shipping", "error", err)
+			os.Exit(1)
+		}
+		taskManager.SetReplicator(shipper)
+	}
+
+	snapshotManager, err := snapshot.NewManager(filepath.Join(dbPath, "snapshots"), taskManager, cfg.Snapshots.Keep)
+	if err != nil {
+		slog.Error("failed to create snapshot manager", "error", err)
+		os.Exit(1)
+	}
+	if shipper != nil {
+		// Standbys behind the previous snapshot get the whole state instead
+		snapshotManager.SetOnCreate(func(*snapshot.Manifest) {
+			if err := shipper.CompactLog(); err != nil {
+				slog.Error("failed to compact log", "error", err)
+			}
+		})
 	}
-	leaseManager.LoadLeases()
+	if cfg.Snapshots.Interval > 0 {
+		go snapshotManager.PeriodicallyCreateSnapshots(cfg.Snapshots.Interval)
+	}
+
+	go taskManager.PeriodicallyApplyRetention()
+	go taskManager.PeriodicallyFinalizeCancelledTasks()
+	go taskManager.PeriodicallyRotateKeys(cfg.Keyring.ReloadInterval)
+	go taskManager.PeriodicallyCollectBlobs(cfg.Blobs.GCInterval, cfg.Blobs.GCGrace)
+	go taskManager.PeriodicallyReapExpiredLeases()
+	go reloadOnHangup(loader, cfg, logLevel, taskManager, queueManager, rateLimiter, controller)
+
+	if cfg.Listen.Metrics != "" {
+		metrics.RegisterQueueDepth(taskManager.QueueDepths)
+		go func() {
+			slog.Info("serving metrics", "addr", cfg.Listen.Metrics)
+			if err := metrics.Serve(cfg.Listen.Metrics); err != nil {
+				slog.Error("failed to serve metrics", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Not serving until the first check, once the sweeps have started
+	checker := newHealthChecker(dbPath, taskManager, node, shipper)
+	go checker.PeriodicallyCheck(cfg.Health.CheckInterval)
 
-	taskManager := managers.NewTaskManager(tasksPath, leaseManager)
-	if err != nil {	
-		fmt.Println("Error creating task manager:", err)
-		return
+	grpcServer := startRpcServer(cfg.Listen.RPC, serverTLS, logger, authenticator, rateLimiter, controller, leaseManager, taskManager, snapshotManager, node, shipper, checker)
+	// Run until SIGTERM or SIGINT
+	shutdownOnSignal(cfg.Shutdown.Timeout, grpcServer, checker, taskManager, snapshotManager, node, shipper, tracer)
+}
+
+// loadLimits reads the rate limits file, or returns no limits if path is empty
+func loadLimits(path string) (*admission.Limits, error) {
+	if path == "" {
+		return &admission.Limits{}, nil
 	}
-	taskManager.LoadTasks()
-	go taskManager.PeriodicallyDeleteTasks()
+	return admission.LoadLimits(path)
+}
+
+// reloadOnHangup reads the configuration again on every SIGHUP and applies
+// the settings that can change while running: the log level, lease bounds,
+// sweep intervals, default retention, rate limits and admission thresholds.
+// An invalid configuration is logged and the running one kept.
+func reloadOnHangup(loader *config.Loader, cfg *config.Config, logLevel *slog.LevelVar, taskManager *managers.TaskManager, queueManager *managers.QueueManager, rateLimiter *admission.RateLimiter, controller *admission.Controller) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	previous := cfg
+	for range hangup {
+		next, err := loader.Load()
+		if err != nil {
+			slog.Error("failed to reload configuration; keeping the running one", "error", err)
+			continue
+		}
+		// Rate limits are read again even when the file name is unchanged
+		limits, err := loadLimits(next.Limits.RateLimits)
+		if err != nil {
+			slog.Error("failed to reload configuration; keeping the running one", "error", err)
+			continue
+		}
+
+		level, _ := logging.ParseLevel(next.Log.Level)
+		logLevel.Set(level)
+		taskManager.SetSettings(next.Settings())
+		queueManager.SetDefaultRetention(next.DefaultRetention())
+		rateLimiter.SetLimits(limits)
+		controller.SetThresholds(next.Limits.MaxPendingTasks, next.Limits.MaxDiskUsage/100)
 
-	startRpcServer(leaseManager, taskManager)
-	// Wait indefinitely
-	// to keep the server running
-	select {}
+		// Settings that need a restart are compared with the ones the
+		// server started with, since those are still in effect
+		reloaded, _ := config.Changes(previous, next)
+		_, restart := config.Changes(cfg, next)
+		slog.Info("reloaded configuration", "changed", reloaded)
+		if len(restart) > 0 {
+			slog.Warn("configuration changes need a restart to take effect", "settings", restart)
+		}
+		previous = next
+	}
 }
 
-func startRpcServer(leaseManager *managers.LeaseManager, taskManager *managers.TaskManager) {
+// newHealthChecker checks that the data directory is writable, that the
+// sweeps finalizing expired leases run, that replication is caught up when
+// the node replicates, and that the node is not draining
+func newHealthChecker(dbPath string, taskManager *managers.TaskManager, node *cluster.Node, shipper *logship.Node) *health.Checker {
+	checker := health.NewChecker(taskpb.TaskService_ServiceDesc.ServiceName)
+	checker.Add(health.STORAGE, health.Writable(dbPath))
+	checker.Add(health.LEASE_REAPER, func() error {
+		return taskManager.CheckSweeps(managers.SWEEP_CANCEL, managers.SWEEP_LEASE_EXPIRY)
+	})
+	if node != nil {
+		checker.Add(health.REPLICATION, node.Check)
+	}
+	if shipper != nil {
+		checker.Add(health.REPLICATION, shipper.Check)
+	}
+	checker.Add(health.DRAIN, func() error {
+		if taskManager.Draining() {
+			return managers.ErrDraining
+		}
+		return nil
+	})
+	return checker
+}
+
+// SHUTDOWN_FLUSH_TIMEOUT bounds the steps after in-flight calls finish:
+// stopping the sweeps, closing replication and flushing spans
+const SHUTDOWN_FLUSH_TIMEOUT = 10 * time.Second
+
+// shutdownOnSignal waits for SIGTERM or SIGINT, then stops granting leases,
+// closes the log streams to standbys, lets in-flight calls finish for up to timeout before cancelling them,
+// waits for running sweeps and snapshots, closes replication and flushes
+// spans. The process exits if that takes longer than SHUTDOWN_FLUSH_TIMEOUT
+// more, or on a second signal.
+func shutdownOnSignal(timeout time.Duration, grpcServer *grpc.Server, checker *health.Checker, taskManager *managers.TaskManager, snapshotManager *snapshot.Manager, node *cluster.Node, shipper *logship.Node, tracer *tracing.Provider) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	received := <-signals
+	slog.Info("shutting down", "signal", received.String(), "timeout", timeout)
+
+	go func() {
+		select {
+		case <-signals:
+			slog.Error("exiting before shutdown finished: received a second signal")
+		case <-time.After(timeout + SHUTDOWN_FLUSH_TIMEOUT):
+			slog.Error("exiting before shutdown finished: timed out")
+		}
+		os.Exit(1)
+	}()
+
+	// Probes see the node going away before it stops taking calls
+	checker.Shutdown()
+	// Workers keep reporting progress and completing the tasks they hold
+	// while new claims are turned away
+	taskManager.SetDraining(true)
+	// Streams to standbys never end on their own, so they are closed for
+	// the graceful stop to finish
+	if shipper != nil {
+		shipper.CloseStreams()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		slog.Warn("cancelling calls still running after the shutdown timeout")
+		grpcServer.Stop()
+		<-stopped
+	}
+
+	taskManager.Stop()
+	snapshotManager.Stop()
+	if node != nil {
+		if err := node.Shutdown(); err != nil {
+			slog.Error("failed to shut down cluster node", "error", err)
+		}
+	}
+	if shipper != nil {
+		if err := shipper.Shutdown(); err != nil {
+			slog.Error("failed to shut down log shipping", "error", err)
+		}
+	}
+	if err := tracer.Shutdown(SHUTDOWN_FLUSH_TIMEOUT); err != nil {
+		slog.Error("failed to flush spans", "error", err)
+	}
+	slog.Info("shut down")
+}
+
+func startRpcServer(rpcAddr string, serverTLS *tls.Config, logger *slog.Logger, authenticator *auth.Authenticator, rateLimiter *admission.RateLimiter, controller *admission.Controller, leaseManager *managers.LeaseManager, taskManager *managers.TaskManager, snapshotManager *snapshot.Manager, node *cluster.Node, shipper *logship.Node, checker *health.Checker) *grpc.Server {
 	// Start gRPC server
-	listener, err := net.Listen("tcp", ":50051")
+	listener, err := net.Listen("tcp", rpcAddr)
 	if err != nil {
-		log.Fatalf("Failed to listen on port 50051: %v", err)
+		slog.Error("failed to listen", "addr", rpcAddr, "error", err)
+		os.Exit(1)
 	}
 
-	grpcServer := grpc.NewServer()
+	var serverOpts []grpc.ServerOption
+	if serverTLS != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(serverTLS)))
+	}
+	// Continues the trace of the caller and starts a span for every call
+	serverOpts = append(serverOpts, grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithPropagators(tracing.Propagator))))
+	// Calls are logged and measured first so rejected calls are included
+	serverOpts = append(serverOpts,
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(logger), metrics.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor(logger), metrics.StreamServerInterceptor()))
+	// The task service checks roles per queue itself; the other services
+	// are for operators and other nodes. Health checks need no credentials
+	// so probes can reach them.
+	if authenticator != nil {
+		authenticator.SetPublicServices(healthpb.Health_ServiceDesc.ServiceName)
+		adminServices := []string{
+			taskpb.SnapshotService_ServiceDesc.ServiceName,
+			taskpb.AdminService_ServiceDesc.ServiceName,
+			taskpb.ClusterService_ServiceDesc.ServiceName,
+			taskpb.StandbyService_ServiceDesc.ServiceName,
+		}
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor(adminServices...)),
+			grpc.ChainStreamInterceptor(authenticator.StreamServerInterceptor(adminServices...)))
+	}
+	// Rate limits run after authentication so they can key on the caller
+	if rateLimiter != nil {
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(rateLimiter.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(rateLimiter.StreamServerInterceptor()))
+	}
+	if controller != nil {
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(controller.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(controller.StreamServerInterceptor()))
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	taskService := service.NewTaskService(leaseManager, taskManager)
+	taskService.SetLogger(logger)
+	if authenticator != nil {
+		taskService.SetAuthenticator(authenticator)
+	}
 
 	taskpb.RegisterTaskServiceServer(grpcServer, taskService)
-
-	fmt.Println("Server is running on port 50051...")
-	if err = grpcServer.Serve(listener); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	taskpb.RegisterSnapshotServiceServer(grpcServer, service.NewSnapshotService(snapshotManager))
+	adminService := service.NewAdminService(taskManager)
+	if authenticator != nil {
+		adminService.SetAuthenticator(authenticator)
+	}
+	taskpb.RegisterAdminServiceServer(grpcServer, adminService)
+	if node != nil {
+		taskpb.RegisterClusterServiceServer(grpcServer, service.NewClusterService(node, taskManager))
 	}
+	if shipper != nil {
+		taskpb.RegisterStandbyServiceServer(grpcServer, service.NewStandbyService(shipper, taskManager))
+	}
+	healthpb.RegisterHealthServer(grpcServer, checker.Server())
+	// Lets tools such as grpcurl list and call the services without the protos
+	reflection.Register(grpcServer)
+
+	slog.Info("server is running", "addr", rpcAddr)
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			slog.Error("failed to serve", "error", err)
+			os.Exit(1)
+		}
+	}()
+	return grpcServer
 }
\ No newline at end of file
@@ -0,0 +1,242 @@
+package managers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// SetBlobStore makes payloads larger than threshold bytes be kept in the
+// blob store instead of the task record. It must be called before the tasks
+// are loaded.
+func (tm *TaskManager) SetBlobStore(blobStore *blobs.Store, threshold int) {
+	tm.blobs = blobStore
+	tm.blobThreshold = threshold
+}
+
+// Blobs returns the blob store, or nil
+func (tm *TaskManager) Blobs() *blobs.Store {
+	return tm.blobs
+}
+
+// BlobFetcher is implemented by replicators whose nodes each keep their own
+// blob store. Payloads are stored on the node that received them and only
+// their digest is replicated, so the other nodes fetch the blob from it.
+type BlobFetcher interface {
+	// FetchBlob returns a reader of a blob's payload held by another node
+	FetchBlob(digest string) (io.ReadCloser, error)
+}
+
+// blobFetches tracks the blobs being fetched from other nodes, so a blob
+// referred to by many commands is fetched once
+type blobFetches struct {
+	fetching  map[string]bool
+	fetchLock *sync.Mutex
+}
+
+func newBlobFetches() *blobFetches {
+	return &blobFetches{fetching: make(map[string]bool), fetchLock: &sync.Mutex{}}
+}
+
+// start reports whether the caller should fetch a blob
+func (b *blobFetches) start(digest string) bool {
+	b.fetchLock.Lock()
+	defer b.fetchLock.Unlock()
+	if b.fetching[digest] {
+		return false
+	}
+	b.fetching[digest] = true
+	return true
+}
+
+func (b *blobFetches) done(digest string) {
+	b.fetchLock.Lock()
+	defer b.fetchLock.Unlock()
+	delete(b.fetching, digest)
+}
+
+// fetchBlob copies a blob from another node into the local blob store
+func (tm *TaskManager) fetchBlob(digest string) error {
+	fetcher, ok := tm.replicator.(BlobFetcher)
+	if !ok {
+		return fmt.Errorf("%w: %s", blobs.ErrNotFound, digest)
+	}
+	reader, err := fetcher.FetchBlob(digest)
+	if err != nil {
+		return fmt.Errorf("failed to fetch blob %s: %v", digest, err)
+	}
+	defer reader.Close()
+	meta, err := tm.blobs.Put(reader)
+	if err != nil {
+		return fmt.Errorf("failed to fetch blob %s: %v", digest, err)
+	}
+	if meta.Digest != digest {
+		return fmt.Errorf("failed to fetch blob %s: received blob %s", digest, meta.Digest)
+	}
+	return nil
+}
+
+// fetchMissingBlobs fetches the blobs of a task this node does not hold in
+// the background, so they are still available if the node that stored
+// them goes away. It is called with the task lock held.
+func (tm *TaskManager) fetchMissingBlobs(t *task.Task) {
+	if _, ok := tm.replicator.(BlobFetcher); !ok {
+		return
+	}
+	for _, ref := range []*task.BlobRef{t.InputBlob, t.ResultBlob} {
+		if ref == nil {
+			continue
+		}
+		if _, err := tm.blobs.Stat(ref.Digest); !errors.Is(err, blobs.ErrNotFound) {
+			continue
+		}
+		if !tm.fetches.start(ref.Digest) {
+			continue
+		}
+		go func(digest string) {
+			defer tm.fetches.done(digest)
+			if err := tm.fetchBlob(digest); err != nil {
+				// OpenBlob fetches it again when it is read
+				tm.log().Warn("failed to fetch blob", "digest", digest, "error", err)
+			}
+		}(ref.Digest)
+	}
+}
+
+// OpenBlob returns a reader of a blob's payload. A blob stored on another
+// node is fetched from it first.
+func (tm *TaskManager) OpenBlob(digest string) (io.ReadCloser, *blobs.Meta, error) {
+	if tm.blobs == nil {
+		return nil, nil, fmt.Errorf("blob storage is not enabled")
+	}
+	reader, meta, err := tm.blobs.Open(digest)
+	if !errors.Is(err, blobs.ErrNotFound) {
+		return reader, meta, err
+	}
+	if err := tm.fetchBlob(digest); err != nil {
+		return nil, nil, err
+	}
+	return tm.blobs.Open(digest)
+}
+
+// offload moves a payload above the threshold into the blob store. It runs
+// before a command is proposed, so the replicated log only carries the
+// digest.
+func (tm *TaskManager) offload(payload []byte) ([]byte, *task.BlobRef, error) {
+	if tm.blobs == nil || len(payload) <= tm.blobThreshold {
+		return payload, nil, nil
+	}
+	meta, err := tm.blobs.Put(bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to store payload: %v", err)
+	}
+	return nil, &task.BlobRef{Digest: meta.Digest, Size: meta.Size}, nil
+}
+
+// blobRef looks up an uploaded payload
+func (tm *TaskManager) blobRef(digest string) (*task.BlobRef, error) {
+	if tm.blobs == nil {
+		return nil, fmt.Errorf("blob storage is not enabled")
+	}
+	if !tm.canUseBlob(digest) {
+		return nil, fmt.Errorf("blob not found")
+	}
+	meta, err := tm.blobs.Stat(digest)
+	if errors.Is(err, blobs.ErrNotFound) {
+		if err := tm.fetchBlob(digest); err != nil {
+			return nil, err
+		}
+		meta, err = tm.blobs.Stat(digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task.BlobRef{Digest: meta.Digest, Size: meta.Size}, nil
+}
+
+// CreateTaskWithInputBlob creates a task whose input was uploaded with
+// UploadPayload. An empty taskID generates one.
+func (tm *TaskManager) CreateTaskWithInputBlob(taskID string, name string, description string, queue string, digest string, metadata map[string]string) (*task.Task, error) {
+	inputBlob, err := tm.blobRef(digest)
+	if err != nil {
+		return nil, err
+	}
+	return tm.createTask(taskID, name, description, queue, nil, inputBlob, metadata)
+}
+
+// CompleteTaskWithResultBlob completes a task with a result uploaded with UploadPayload
+func (tm *TaskManager) CompleteTaskWithResultBlob(taskID string, digest string) (*task.Task, error) {
+	resultBlob, err := tm.blobRef(digest)
+	if err != nil {
+		return nil, err
+	}
+	return tm.completeTask(taskID, nil, resultBlob)
+}
+
+// refBlobs and unrefBlobs keep the blob reference counts in step with the
+// tasks. They are called with the task lock held.
+func (tm *TaskManager) refBlobs(t *task.Task) {
+	if tm.blobs == nil {
+		return
+	}
+	for _, ref := range []*task.BlobRef{t.InputBlob, t.ResultBlob} {
+		if ref != nil {
+			tm.blobs.Ref(ref.Digest)
+		}
+	}
+	tm.fetchMissingBlobs(t)
+}
+
+func (tm *TaskManager) unrefBlobs(t *task.Task) {
+	if tm.blobs == nil {
+		return
+	}
+	for _, ref := range []*task.BlobRef{t.InputBlob, t.ResultBlob} {
+		if ref != nil {
+			tm.blobs.Unref(ref.Digest)
+		}
+	}
+}
+
+// resetBlobRefs counts the blob references of all tasks. It is called with
+// the task lock held.
+func (tm *TaskManager) resetBlobRefs() {
+	if tm.blobs == nil {
+		return
+	}
+	refs := make(map[string]int)
+	for _, t := range tm.tasks {
+		for _, ref := range []*task.BlobRef{t.InputBlob, t.ResultBlob} {
+			if ref != nil {
+				refs[ref.Digest]++
+			}
+		}
+		tm.fetchMissingBlobs(t)
+	}
+	tm.blobs.ResetRefs(refs)
+}
+
+// PeriodicallyCollectBlobs deletes blobs no task refers to once they are
+// older than grace
+func (tm *TaskManager) PeriodicallyCollectBlobs(interval time.Duration, grace time.Duration) {
+	if tm.blobs == nil {
+		return
+	}
+	tm.every(SWEEP_BLOB_GC, func() time.Duration { return interval }, func() {
+		// Uploads no task refers to by now may be collected below
+		tm.uploads.prune(time.Now().Add(-grace))
+		collected, err := tm.blobs.Collect(grace)
+		if err != nil {
+			tm.log().Error("failed to collect blobs", "error", err)
+		}
+		if collected > 0 {
+			tm.log().Info("collected unreferenced blobs", "count", collected)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package managers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/shards"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// Operations that can be carried by a Command
+const (
+	OP_CREATE_TASK          = "create_task"
+	OP_UPDATE_TASK          = "update_task"
+	OP_COMPLETE_TASK        = "complete_task"
+	OP_FAIL_TASK            = "fail_task"
+	OP_DELETE_TASK          = "delete_task"
+	OP_LEASE_TASK           = "lease_task"
+	OP_REPORT_PROGRESS      = "report_progress"
+	OP_CANCEL_TASK          = "cancel_task"
+	OP_ACKNOWLEDGE_CANCEL   = "acknowledge_cancel"
+	OP_FINALIZE_CANCEL      = "finalize_cancel"
+	OP_PAUSE_TASK           = "pause_task"
+	OP_RESUME_TASK          = "resume_task"
+	OP_EXPIRE_TASK          = "expire_task"
+	OP_PAUSE_QUEUE          = "pause_queue"
+	OP_RESUME_QUEUE         = "resume_queue"
+	OP_SET_RETENTION_POLICY = "set_retention_policy"
+	OP_SET_SHARD_MAP        = "set_shard_map"
+	OP_IMPORT_TASK          = "import_task"
+	OP_DROP_TASK            = "drop_task"
+	OP_FENCE_LEASES         = "fence_leases"
+	OP_RESTORE_STATE        = "restore_state"
+	OP_SET_COMPRESSION      = "set_compression"
+	OP_REAP_LEASES          = "reap_leases"
+)
+
+// Command is a single mutation of the task manager state. Commands carry every
+// value that would otherwise be generated while applying them (IDs and times),
+// so applying the same commands in the same order always gives the same state.
+type Command struct {
+	Op          string                  `json:"op"`
+	Time        time.Time               `json:"time"`
+	TaskID      string                  `json:"task_id,omitempty"`
+	LeaseID     string                  `json:"lease_id,omitempty"`
+	Username    string                  `json:"username,omitempty"`
+	Name        string                  `json:"name,omitempty"`
+	Description string                  `json:"description,omitempty"`
+	Queue       string                  `json:"queue,omitempty"`
+	State       string                  `json:"state,omitempty"`
+	Data        []byte                  `json:"data,omitempty"`
+	Input       []byte                  `json:"input,omitempty"`
+	Result      []byte                  `json:"result,omitempty"`
+	InputBlob   *task.BlobRef           `json:"input_blob,omitempty"`
+	ResultBlob  *task.BlobRef           `json:"result_blob,omitempty"`
+	Metadata    map[string]string       `json:"metadata,omitempty"`
+	Error       *task.TaskError         `json:"error,omitempty"`
+	Progress    *task.Progress          `json:"progress,omitempty"`
+	Retention   *queues.RetentionPolicy `json:"retention,omitempty"`
+	Compression string                  `json:"compression,omitempty"`
+	ShardMap    *shards.ShardMap        `json:"shard_map,omitempty"`
+	Task        *task.Task              `json:"task,omitempty"`
+	Leases      []*leases.Lease         `json:"leases,omitempty"`
+	Version     string                  `json:"version,omitempty"`
+	Restore     *State                  `json:"restore,omitempty"`
+	// FencingToken is the token of the lease LeaseID that a worker finished
+	// or reported on a task with; zero when it sent none
+	FencingToken uint64 `json:"fencing_token,omitempty"`
+	// LeaseDuration is how long a lease granted by the command lasts
+	LeaseDuration time.Duration `json:"lease_duration,omitempty"`
+	// Tenant is the tenant the command was issued for, if any
+	Tenant string `json:"tenant,omitempty"`
+	// TraceContext is the trace the command was issued in. Created tasks
+	// keep it.
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+
+	// Term and Index identify the replicated log entry the command was
+	// applied from. They are zero when running without replication.
+	Term  uint64 `json:"-"`
+	Index uint64 `json:"-"`
+}
+
+// CommandResult is the outcome of applying a Command
+type CommandResult struct {
+	Task     *task.Task       `json:"task,omitempty"`
+	Lease    *leases.Lease    `json:"lease,omitempty"`
+	Queue    *queues.Queue    `json:"queue,omitempty"`
+	ShardMap *shards.ShardMap `json:"shard_map,omitempty"`
+	Applied  bool             `json:"applied,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// resultErrors are the errors that Err returns as themselves, so callers
+// can match them with errors.Is after the result crossed nodes as text
+var resultErrors = []error{ErrLeaseLost}
+
+// Err returns the error the command failed with, if any
+func (r *CommandResult) Err() error {
+	if r.Error == "" {
+		return nil
+	}
+	for _, err := range resultErrors {
+		if detail, found := strings.CutPrefix(r.Error, err.Error()); found {
+			return fmt.Errorf("%w%s", err, detail)
+		}
+	}
+	return fmt.Errorf("%s", r.Error)
+}
+
+// Replicator orders commands before they are applied. Without a replicator
+// the TaskManager applies commands directly.
+type Replicator interface {
+	// Propose applies a command through the replicated log and returns the
+	// result of applying it
+	Propose(cmd *Command) (*CommandResult, error)
+	// IsLeader reports whether this node may run background sweeps
+	IsLeader() bool
+}
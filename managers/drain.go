@@ -0,0 +1,37 @@
+package managers
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDraining is returned instead of granting a lease while the node drains
+var ErrDraining = errors.New("server is draining and grants no new leases")
+
+// SetDraining stops or resumes granting leases. Leases already granted
+// can still report progress, complete and fail.
+func (tm *TaskManager) SetDraining(draining bool) {
+	if tm.lifecycle.draining.Swap(draining) != draining {
+		tm.log().Info("set draining", "draining", draining)
+	}
+}
+
+// Draining reports whether the node has stopped granting leases
+func (tm *TaskManager) Draining() bool {
+	return tm.lifecycle.draining.Load()
+}
+
+// ActiveLeases counts the leases that have not expired on unfinished tasks,
+// which workers may still complete
+func (tm *TaskManager) ActiveLeases() int {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	count := 0
+	for _, lease := range tm.leaseManager.ActiveLeases(time.Now()) {
+		if t, exists := tm.tasks[lease.TaskID]; exists && !isTerminal(t.State) {
+			count++
+		}
+	}
+	return count
+}
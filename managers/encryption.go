@@ -0,0 +1,177 @@
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/keyring"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// DEFAULT_KEYRING_RELOAD_INTERVAL is how often the keyring file is checked for a new primary key
+const DEFAULT_KEYRING_RELOAD_INTERVAL = 1 * time.Minute
+
+// Keyring returns the keyring task payloads are encrypted with, or nil
+func (tm *TaskManager) Keyring() *keyring.Keyring {
+	return tm.keyring
+}
+
+// ReencryptTasks rewrites every stored task that is not sealed with the
+// primary key, including tasks stored before encryption was enabled. The
+// task lock is only held for one task at a time, so requests keep being
+// served while a rotation runs. Re-encryption is local to this node and is
+// not replicated.
+func (tm *TaskManager) ReencryptTasks() (int, error) {
+	if tm.keyring == nil {
+		return 0, nil
+	}
+	ids, err := store.List(tm.tasksDir)
+	if err != nil {
+		return 0, err
+	}
+
+	reencrypted := 0
+	for _, id := range ids {
+		keyID, err := task.StoredKeyID(tm.tasksDir, id)
+		if err != nil || keyID == tm.keyring.Primary() {
+			// Tasks deleted in the meantime are skipped
+			continue
+		}
+		saved, err := tm.reencryptTask(id)
+		if err != nil {
+			return reencrypted, err
+		}
+		if saved {
+			reencrypted++
+		}
+	}
+	return reencrypted, nil
+}
+
+func (tm *TaskManager) reencryptTask(taskID string) (bool, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	t, exists := tm.tasks[taskID]
+	if !exists {
+		return false, nil
+	}
+	if err := tm.saveTask(t); err != nil {
+		return false, fmt.Errorf("failed to re-encrypt task %s: %v", taskID, err)
+	}
+	return true, nil
+}
+
+// PeriodicallyRotateKeys re-encrypts tasks not sealed with the primary key,
+// then reloads the keyring file every interval and re-encrypts again
+// whenever the primary key changes
+func (tm *TaskManager) PeriodicallyRotateKeys(interval time.Duration) {
+	if tm.keyring == nil {
+		return
+	}
+	tm.logReencryption()
+
+	tm.every(SWEEP_KEYRING, func() time.Duration { return interval }, func() {
+		changed, err := tm.keyring.Reload()
+		if err != nil {
+			tm.log().Error("failed to reload keyring", "error", err)
+			return
+		}
+		if changed {
+			tm.log().Info("primary encryption key changed", "key", tm.keyring.Primary())
+			tm.logReencryption()
+		}
+	})
+}
+
+func (tm *TaskManager) logReencryption() {
+	count, err := tm.ReencryptTasks()
+	if err != nil {
+		tm.log().Error("failed to re-encrypt tasks", "error", err)
+	}
+	if count > 0 {
+		tm.log().Info("re-encrypted tasks", "count", count, "key", tm.keyring.Primary())
+	}
+	if tm.blobs == nil {
+		return
+	}
+	count, err = tm.blobs.Reencrypt()
+	if err != nil {
+		tm.log().Error("failed to re-encrypt blobs", "error", err)
+	}
+	if count > 0 {
+		tm.log().Info("re-encrypted blobs", "count", count, "key", tm.keyring.Primary())
+	}
+}
+
+// sealedRecord is how commands and states are written to the replication
+// logs and raft snapshots when a keyring is configured
+type sealedRecord struct {
+	Sealed *keyring.Envelope `json:"sealed"`
+}
+
+// EncodeCommand serializes a command for the raft or log shipping log. With
+// a keyring the whole command is sealed, so task payloads never reach the
+// log in plaintext.
+func (tm *TaskManager) EncodeCommand(cmd *Command) ([]byte, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode command: %v", err)
+	}
+	return tm.seal("command", data)
+}
+
+// DecodeCommand reads a command written by EncodeCommand. Commands logged
+// before a keyring was configured are read as they are.
+func (tm *TaskManager) DecodeCommand(data []byte) (*Command, error) {
+	data, err := tm.open("command", data)
+	if err != nil {
+		return nil, err
+	}
+	var cmd Command
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return nil, fmt.Errorf("failed to decode command: %v", err)
+	}
+	return &cmd, nil
+}
+
+// ExportSealedState is ExportState sealed with the keyring if one is
+// configured. It is what raft snapshots and standbys are given; RestoreState
+// accepts either form.
+func (tm *TaskManager) ExportSealedState() ([]byte, error) {
+	data, err := tm.ExportState()
+	if err != nil {
+		return nil, err
+	}
+	return tm.seal("state", data)
+}
+
+// seal wraps data in a sealedRecord, using kind as the associated data
+func (tm *TaskManager) seal(kind string, data []byte) ([]byte, error) {
+	if tm.keyring == nil {
+		return data, nil
+	}
+	envelope, err := tm.keyring.Seal(data, []byte(kind))
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal %s: %v", kind, err)
+	}
+	return json.Marshal(sealedRecord{Sealed: envelope})
+}
+
+// open returns the data sealed by seal, or data itself if it is not sealed
+func (tm *TaskManager) open(kind string, data []byte) ([]byte, error) {
+	var record sealedRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Sealed == nil {
+		return data, nil
+	}
+	if tm.keyring == nil {
+		return nil, fmt.Errorf("%s is encrypted with key %s but no keyring is configured", kind, record.Sealed.KeyID)
+	}
+	opened, err := tm.keyring.Open(record.Sealed, []byte(kind))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", kind, err)
+	}
+	return opened, nil
+}
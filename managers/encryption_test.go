@@ -0,0 +1,89 @@
+package managers
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/indkumar8999/ps-tasks/keyring"
+)
+
+// newTestKeyring returns a keyring holding one key
+func newTestKeyring(t *testing.T) *keyring.Keyring {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	if _, err := keyring.AddKey(path, "k1"); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	keys, err := keyring.Load(path)
+	if err != nil {
+		t.Fatalf("Load keyring: %v", err)
+	}
+	return keys
+}
+
+func TestEncodeCommandSealsPayloads(t *testing.T) {
+	keys := newTestKeyring(t)
+	sealed := newTestTaskManager(t)
+	sealed.SetKeyring(keys)
+	plain := newTestTaskManager(t)
+
+	cmd := &Command{Op: OP_CREATE_TASK, TaskID: "t1", Input: []byte("secret input"), Metadata: map[string]string{"key": "hidden"}}
+	data, err := sealed.EncodeCommand(cmd)
+	if err != nil {
+		t.Fatalf("EncodeCommand: %v", err)
+	}
+	if bytes.Contains(data, []byte("secret input")) || bytes.Contains(data, []byte("hidden")) {
+		t.Errorf("sealed command holds its payload as is: %s", data)
+	}
+	decoded, err := sealed.DecodeCommand(data)
+	if err != nil {
+		t.Fatalf("DecodeCommand: %v", err)
+	}
+	if decoded.TaskID != "t1" || string(decoded.Input) != "secret input" || decoded.Metadata["key"] != "hidden" {
+		t.Errorf("decoded command = %+v, want the encoded one", decoded)
+	}
+	if _, err := plain.DecodeCommand(data); err == nil {
+		t.Errorf("decoding a sealed command without the keyring succeeded")
+	}
+
+	// Commands logged before the keyring was configured are still read
+	data, err = plain.EncodeCommand(cmd)
+	if err != nil {
+		t.Fatalf("EncodeCommand without a keyring: %v", err)
+	}
+	if decoded, err := sealed.DecodeCommand(data); err != nil || string(decoded.Input) != "secret input" {
+		t.Errorf("DecodeCommand of a plain command = %+v, %v", decoded, err)
+	}
+}
+
+func TestExportSealedState(t *testing.T) {
+	keys := newTestKeyring(t)
+	source := newTestTaskManager(t)
+	source.SetKeyring(keys)
+	created, err := source.CreateTask("task", "", "default", []byte("secret input"), nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	data, err := source.ExportSealedState()
+	if err != nil {
+		t.Fatalf("ExportSealedState: %v", err)
+	}
+	if bytes.Contains(data, []byte("secret input")) || bytes.Contains(data, []byte(`"tasks"`)) {
+		t.Errorf("sealed state holds its tasks as is: %s", data)
+	}
+
+	if err := newTestTaskManager(t).RestoreState(data); err == nil {
+		t.Errorf("restoring a sealed state without the keyring succeeded")
+	}
+	target := newTestTaskManager(t)
+	target.SetKeyring(keys)
+	if err := target.RestoreState(data); err != nil {
+		t.Fatalf("RestoreState: %v", err)
+	}
+	restored, err := target.GetTask(created.ID)
+	if err != nil || string(restored.Input) != "secret input" {
+		t.Errorf("restored task = %+v, %v, want the exported task", restored, err)
+	}
+}
@@ -0,0 +1,87 @@
+package managers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/leases"
+)
+
+// ErrLeaseLost is returned when a worker finishes or reports on a task
+// through a lease that is no longer the task's newest lease, or with a
+// fencing token that is not its lease's
+var ErrLeaseLost = errors.New("lease is no longer held")
+
+// WithLease returns a view of the task manager that completes and fails
+// tasks as the holder of the given lease. They are rejected with
+// ErrLeaseLost once another lease has been granted on the task since. A
+// nonzero fencingToken must also match the lease's. Without a lease, tasks
+// can only be finished while they are not leased.
+func (tm *TaskManager) WithLease(leaseID string, fencingToken uint64) *TaskManager {
+	if leaseID == "" && fencingToken == 0 {
+		return tm
+	}
+	view := *tm
+	view.leaseID = leaseID
+	view.fencingToken = fencingToken
+	return &view
+}
+
+// checkFence fails with ErrLeaseLost unless leaseID names the newest lease
+// granted on the task and fencingToken, when set, is its fencing token.
+// Leases are superseded once a newer one is granted, and released when
+// leases are fenced after a failover. Commands carrying no lease are only
+// accepted while nobody holds an active lease on the task at now. It is
+// called with the task lock held.
+func (tm *TaskManager) checkFence(taskID string, leaseID string, fencingToken uint64, now time.Time) (*leases.Lease, error) {
+	if leaseID == "" {
+		if fencingToken != 0 {
+			return nil, fmt.Errorf("%w: a fencing token needs a lease ID", ErrLeaseLost)
+		}
+		if active, err := tm.leaseManager.GetActiveLeaseForTask(taskID, now); err == nil {
+			return nil, fmt.Errorf("%w: the task is leased with lease %s", ErrLeaseLost, active.ID)
+		}
+		return nil, nil
+	}
+	lease, err := tm.leaseManager.GetLease(leaseID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLeaseLost, err)
+	}
+	if lease.TaskID != taskID {
+		return nil, fmt.Errorf("%w: lease %s is for task %s", ErrLeaseLost, leaseID, lease.TaskID)
+	}
+	if fencingToken != 0 && fencingToken != lease.FencingToken {
+		return nil, fmt.Errorf("%w: fencing token %d does not match the lease's %d", ErrLeaseLost, fencingToken, lease.FencingToken)
+	}
+	for _, other := range tm.leaseManager.LeasesForTask(taskID) {
+		if other.ID != lease.ID && newer(other, lease) {
+			return nil, fmt.Errorf("%w: lease %s was superseded by lease %s", ErrLeaseLost, leaseID, other.ID)
+		}
+	}
+	return lease, nil
+}
+
+// newer reports whether lease a was granted after lease b. Fencing tokens
+// order leases granted through replication; without it they are zero and
+// the grant times decide.
+func newer(a *leases.Lease, b *leases.Lease) bool {
+	if a.FencingToken != b.FencingToken {
+		return a.FencingToken > b.FencingToken
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+// checkLeaseHeld is checkFence for the view's lease before a command is
+// proposed, so a stale worker fails without a round trip through the log.
+// Followers may not have applied the newest lease yet and leave the check
+// to the leader.
+func (tm *TaskManager) checkLeaseHeld(taskID string) error {
+	if !tm.isLeader() {
+		return nil
+	}
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+	_, err := tm.checkFence(taskID, tm.leaseID, tm.fencingToken, time.Now())
+	return err
+}
@@ -0,0 +1,108 @@
+package managers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+func TestStaleLeaseCannotFinishTask(t *testing.T) {
+	tm := newTestTaskManager(t)
+	created, err := tm.CreateTask("task", "", "default", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// The first worker's lease expires and the task is leased again
+	granted := time.Now()
+	stale := tm.Apply(&Command{Op: OP_LEASE_TASK, Time: granted, TaskID: created.ID, LeaseID: "stale", Username: "worker1", LeaseDuration: time.Second, Index: 1})
+	if err := stale.Err(); err != nil {
+		t.Fatalf("first lease: %v", err)
+	}
+	current := tm.Apply(&Command{Op: OP_LEASE_TASK, Time: granted.Add(time.Minute), TaskID: created.ID, LeaseID: "current", Username: "worker2", LeaseDuration: time.Hour, Index: 2})
+	if err := current.Err(); err != nil {
+		t.Fatalf("second lease: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		leaseID string
+		token   uint64
+	}{
+		{name: "superseded lease", leaseID: "stale", token: 1},
+		{name: "superseded lease without token", leaseID: "stale"},
+		{name: "token of another lease", leaseID: "current", token: 1},
+		{name: "unknown lease", leaseID: "unknown"},
+		{name: "token without lease", token: 2},
+		{name: "no lease while the task is leased"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tm.WithLease(tt.leaseID, tt.token).CompleteTask(created.ID, []byte("stale result"))
+			if !errors.Is(err, ErrLeaseLost) {
+				t.Errorf("CompleteTask error = %v, want ErrLeaseLost", err)
+			}
+			_, err = tm.WithLease(tt.leaseID, tt.token).FailTask(created.ID, &task.TaskError{Code: "stale"})
+			if !errors.Is(err, ErrLeaseLost) {
+				t.Errorf("FailTask error = %v, want ErrLeaseLost", err)
+			}
+			// Apply enforces the fence too, whatever the proposing node checked
+			result := tm.Apply(&Command{Op: OP_COMPLETE_TASK, Time: time.Now(), TaskID: created.ID, LeaseID: tt.leaseID, FencingToken: tt.token})
+			if result.Err() == nil {
+				t.Errorf("applying the completion succeeded")
+			}
+		})
+	}
+
+	progress := tm.Apply(&Command{Op: OP_REPORT_PROGRESS, Time: granted.Add(time.Minute), LeaseID: "current", Username: "worker2", FencingToken: 1, Progress: &task.Progress{Percent: 50}})
+	if progress.Err() == nil {
+		t.Errorf("progress with the token of another lease succeeded")
+	}
+
+	completed, err := tm.WithLease("current", 2).CompleteTask(created.ID, []byte("result"))
+	if err != nil {
+		t.Fatalf("CompleteTask with the current lease: %v", err)
+	}
+	if completed.State != COMPLETED || string(completed.Result) != "result" {
+		t.Errorf("task = %s %q, want %s %q", completed.State, completed.Result, COMPLETED, "result")
+	}
+}
+
+func TestReapExpiredLeases(t *testing.T) {
+	tm := newTestTaskManager(t)
+	created, err := tm.CreateTask("task", "", "default", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	granted := time.Now()
+	result := tm.Apply(&Command{Op: OP_LEASE_TASK, Time: granted, TaskID: created.ID, LeaseID: "expired", Username: "worker", LeaseDuration: time.Second, Index: 1})
+	if err := result.Err(); err != nil {
+		t.Fatalf("LeaseTask: %v", err)
+	}
+	expiresAt := result.Lease.ExpiresAt
+
+	if reaped, err := tm.ReapExpiredLeases(expiresAt.Add(-time.Millisecond)); err != nil || reaped != 0 {
+		t.Errorf("ReapExpiredLeases before the lease expired = %d, %v, want 0", reaped, err)
+	}
+	if reaped, err := tm.ReapExpiredLeases(expiresAt); err != nil || reaped != 1 {
+		t.Fatalf("ReapExpiredLeases = %d, %v, want 1", reaped, err)
+	}
+	if _, err := tm.leaseManager.GetLease("expired"); err == nil {
+		t.Errorf("reaped lease is still stored")
+	}
+
+	// The worker that held it can no longer finish the task, but the task
+	// can be leased and finished again
+	if _, err := tm.WithLease("expired", 1).CompleteTask(created.ID, []byte("late")); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("CompleteTask with a reaped lease error = %v, want ErrLeaseLost", err)
+	}
+	lease, err := tm.LeaseTask(created.ID, "worker2", time.Minute)
+	if err != nil {
+		t.Fatalf("LeaseTask after reaping: %v", err)
+	}
+	if _, err := tm.WithLease(lease.ID, lease.FencingToken).CompleteTask(created.ID, []byte("result")); err != nil {
+		t.Errorf("CompleteTask: %v", err)
+	}
+}
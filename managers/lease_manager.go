@@ -3,12 +3,14 @@ package managers
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"time"
 	"sync"
 
 	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/indkumar8999/ps-tasks/store"
 )
 
 
@@ -16,6 +18,7 @@ type LeaseManager struct {
 	leasesDir string
 	leases     map[string]*leases.Lease
 	leaseLock *sync.Mutex
+	logger    *slog.Logger
 }
 
 // NewLeaseManager creates a new LeaseManager
@@ -23,7 +26,7 @@ func NewLeaseManager(leasesDir string) (*LeaseManager, error) {
 	// Create the leases directory if it doesn't exist
 	if _, err := os.Stat(leasesDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(leasesDir, 0755); err != nil {
-			fmt.Printf("Error creating leases directory: %v\n", err)
+			slog.Error("failed to create leases directory", "path", leasesDir, "error", err)
 			return nil, err
 		}
 	}
@@ -32,11 +35,12 @@ func NewLeaseManager(leasesDir string) (*LeaseManager, error) {
 		leasesDir:  leasesDir,
 		leases:     make(map[string]*leases.Lease),
 		leaseLock:  &sync.Mutex{},
+		logger:     slog.Default(),
 	}, nil
 }
 
-// AcquireLease acquires a lease for a task
-func (lm *LeaseManager) AcquireLease(taskID string, duration time.Duration, username string) (*leases.Lease, error) {
+// AcquireLease acquires a lease with the given ID for a task, starting at now
+func (lm *LeaseManager) AcquireLease(leaseID string, taskID string, duration time.Duration, username string, now time.Time) (*leases.Lease, error) {
 	lm.leaseLock.Lock()
 	defer lm.leaseLock.Unlock()
 	// Check if the task ID is valid
@@ -49,19 +53,19 @@ func (lm *LeaseManager) AcquireLease(taskID string, duration time.Duration, user
 	}
 
 	// Check if a lease already exists for the task
-	if lease, exists := lm.leases[taskID]; exists {
-		if !lease.IsExpired() {
-			return nil, fmt.Errorf("lease already exists and is not expired")
-		}
+	if lm.activeLeaseForTask(taskID, now) != nil {
+		return nil, fmt.Errorf("lease already exists and is not expired")
 	}
 	// Create a new lease
 
-	lease := leases.NewLease(taskID, duration, username)
+	lease := leases.NewLeaseAt(leaseID, taskID, now, duration, username)
 	if err := lease.Save(lm.leasesDir); err != nil {
 		return nil, err
 	}
 
 	lm.leases[lease.ID] = lease
+	metrics.LeasesGranted.Inc()
+	lm.logger.Debug("lease acquired", "lease_id", lease.ID, "task_id", taskID, "owner", username, "expires_at", lease.ExpiresAt)
 	return lease, nil
 }
 
@@ -79,11 +83,12 @@ func (lm *LeaseManager) ReleaseLease(leaseID string) error {
 		return fmt.Errorf("lease not found")
 	}
 
-	if err := os.Remove(filepath.Join(lm.leasesDir, fmt.Sprintf("%s.json", lease.ID))); err != nil {
+	if err := store.Remove(lm.leasesDir, lease.ID); err != nil {
 		return err
 	}
 
 	delete(lm.leases, leaseID)
+	lm.logger.Debug("lease released", "lease_id", leaseID, "task_id", lease.TaskID)
 	return nil
 }
 
@@ -104,62 +109,153 @@ func (lm *LeaseManager) GetLease(leaseID string) (*leases.Lease, error) {
 	return lease, nil
 }
 
-// LoadLeases loads all leases from the leases directory
-func (lm *LeaseManager) LoadLeases() error {
+// GetActiveLeaseForTask retrieves the lease held on a task that is unexpired at now
+func (lm *LeaseManager) GetActiveLeaseForTask(taskID string, now time.Time) (*leases.Lease, error) {
+	lm.leaseLock.Lock()
+	defer lm.leaseLock.Unlock()
+	// Check if the task ID is valid
+	if taskID == "" {
+		return nil, fmt.Errorf("invalid task ID")
+	}
+	lease := lm.activeLeaseForTask(taskID, now)
+	if lease == nil {
+		return nil, fmt.Errorf("lease not found")
+	}
+	return lease, nil
+}
+
+// activeLeaseForTask returns the unexpired lease on a task, if any.
+// The caller must hold leaseLock.
+func (lm *LeaseManager) activeLeaseForTask(taskID string, now time.Time) *leases.Lease {
+	for _, lease := range lm.leases {
+		if lease.TaskID == taskID && !lease.IsExpiredAt(now) {
+			return lease
+		}
+	}
+	return nil
+}
+
+// LeasesForTask returns every lease held on a task, expired or not
+func (lm *LeaseManager) LeasesForTask(taskID string) []*leases.Lease {
 	lm.leaseLock.Lock()
 	defer lm.leaseLock.Unlock()
 
-	files, err := os.ReadDir(lm.leasesDir)
-	if err != nil {
+	var taskLeases []*leases.Lease
+	for _, lease := range lm.leases {
+		if lease.TaskID == taskID {
+			taskLeases = append(taskLeases, lease)
+		}
+	}
+	return taskLeases
+}
+
+// PutLease stores a lease as is, replacing any lease with the same ID
+func (lm *LeaseManager) PutLease(lease *leases.Lease) error {
+	lm.leaseLock.Lock()
+	defer lm.leaseLock.Unlock()
+
+	if lease == nil || lease.ID == "" {
+		return fmt.Errorf("invalid lease")
+	}
+	if err := lease.Save(lm.leasesDir); err != nil {
 		return err
 	}
+	lm.leases[lease.ID] = lease
+	return nil
+}
+
+// ReleaseLeasesForTask releases every lease held on a task
+func (lm *LeaseManager) ReleaseLeasesForTask(taskID string) error {
+	lm.leaseLock.Lock()
+	defer lm.leaseLock.Unlock()
 
-	for _, file := range files {
-		if file.IsDir() {
+	for _, lease := range lm.leases {
+		if lease.TaskID != taskID {
 			continue
 		}
-		leaseID := file.Name()
-		lease, err := leases.LoadLease(lm.leasesDir, leaseID)
-		if err != nil {
+		if err := store.Remove(lm.leasesDir, lease.ID); err != nil {
 			return err
 		}
-		lm.leases[lease.ID] = lease
+		delete(lm.leases, lease.ID)
 	}
-
 	return nil
 }
 
-// CleanupExpiredLeases removes expired leases from the directory
-func (lm *LeaseManager) CleanupExpiredLeases() error {
+// ReleaseLeasesBefore releases every lease granted in a term older than the
+// given one and returns how many were released
+func (lm *LeaseManager) ReleaseLeasesBefore(term uint64) (int, error) {
 	lm.leaseLock.Lock()
 	defer lm.leaseLock.Unlock()
 
-	files, err := os.ReadDir(lm.leasesDir)
+	released := 0
+	for _, lease := range lm.leases {
+		if lease.Term >= term {
+			continue
+		}
+		if err := store.Remove(lm.leasesDir, lease.ID); err != nil {
+			return released, err
+		}
+		delete(lm.leases, lease.ID)
+		released++
+	}
+	if released > 0 {
+		lm.logger.Info("released leases of older terms", "count", released, "term", term)
+	}
+	return released, nil
+}
+
+// LoadLeases loads all leases from the leases directory. Unreadable lease
+// files are moved into the quarantine, or fail the load if quarantine is nil.
+func (lm *LeaseManager) LoadLeases(quarantine *store.Quarantine) error {
+	lm.leaseLock.Lock()
+	defer lm.leaseLock.Unlock()
+
+	loaded, err := store.LoadAll(lm.leasesDir, "leases", quarantine, func(l *leases.Lease) string { return l.ID })
 	if err != nil {
 		return err
 	}
+	for _, lease := range loaded {
+		lm.leases[lease.ID] = lease
+	}
+	lm.logger.Info("loaded leases", "count", len(loaded))
+	return nil
+}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		leaseID := file.Name()
-		lease, err := leases.LoadLease(lm.leasesDir, leaseID)
-		if err != nil {
-			return err
+// ActiveLeases returns the leases that have not expired at now
+func (lm *LeaseManager) ActiveLeases(now time.Time) []*leases.Lease {
+	lm.leaseLock.Lock()
+	defer lm.leaseLock.Unlock()
+
+	var active []*leases.Lease
+	for _, lease := range lm.leases {
+		if !lease.IsExpiredAt(now) {
+			active = append(active, lease)
 		}
-		if lease.IsExpired() {
-			if err := os.Remove(filepath.Join(lm.leasesDir, fmt.Sprintf("%s.json", lease.ID))); err != nil {
-				return err
+	}
+	return active
+}
+
+// CleanupExpiredLeases removes the leases that expired at or before a time
+// from the directory, and returns how many it removed
+func (lm *LeaseManager) CleanupExpiredLeases(before time.Time) (int, error) {
+	lm.leaseLock.Lock()
+	defer lm.leaseLock.Unlock()
+
+	removed := 0
+	for _, lease := range lm.leases {
+		if !lease.ExpiresAt.After(before) {
+			if err := store.Remove(lm.leasesDir, lease.ID); err != nil {
+				return removed, err
 			}
 			delete(lm.leases, lease.ID)
+			removed++
 		}
 	}
-	return nil
+	return removed, nil
 }
 
-// ExtendLease extends the lease duration for a task
-func (lm *LeaseManager) ExtendLease(leaseID string, duration time.Duration, username string) error {
+// ExtendLease extends the lease duration for a task, counting from now
+func (lm *LeaseManager) ExtendLease(leaseID string, duration time.Duration, username string, now time.Time) error {
 	lm.leaseLock.Lock()
 	defer lm.leaseLock.Unlock()
 	// Check if the lease ID is valid
@@ -176,21 +272,39 @@ func (lm *LeaseManager) ExtendLease(leaseID string, duration time.Duration, user
 		return fmt.Errorf("lease not found")
 	}
 	// Check if the lease is expired
-	if lease.IsExpired() {
+	if lease.IsExpiredAt(now) {
 		return fmt.Errorf("lease is expired")
 	}
 	// Check if the lease is already extended
-	if time.Now().Add(duration).Before(lease.ExpiresAt) {
+	if now.Add(duration).Before(lease.ExpiresAt) {
 		return fmt.Errorf("lease is already extended")
 	}
 	if lease.CreatedBy != username {
 		return fmt.Errorf("lease was created by another user")
 	}
 	// Extend the lease duration
-	lease.ExpiresAt = time.Now().Add(duration)
+	lease.ExpiresAt = now.Add(duration)
+	lease.UpdatedAt = now
+	lease.UpdatedBy = username
 	if err := lease.Save(lm.leasesDir); err != nil {
 		return err
 	}
+	metrics.LeasesExtended.Inc()
+	lm.logger.Debug("lease extended", "lease_id", lease.ID, "task_id", lease.TaskID, "expires_at", lease.ExpiresAt)
 	return nil
 }
 
+// ExpiredBetween returns the leases that expired after from and up to to
+func (lm *LeaseManager) ExpiredBetween(from time.Time, to time.Time) []*leases.Lease {
+	lm.leaseLock.Lock()
+	defer lm.leaseLock.Unlock()
+
+	var expired []*leases.Lease
+	for _, lease := range lm.leases {
+		if lease.ExpiresAt.After(from) && !lease.ExpiresAt.After(to) {
+			expired = append(expired, lease)
+		}
+	}
+	return expired
+}
+
@@ -0,0 +1,101 @@
+package managers
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Names of the background sweeps
+const (
+	SWEEP_RETENTION    = "retention"
+	SWEEP_CANCEL       = "cancel"
+	SWEEP_LEASE_EXPIRY = "lease_expiry"
+	SWEEP_KEYRING      = "keyring"
+	SWEEP_BLOB_GC      = "blob_gc"
+)
+
+// SWEEP_OVERDUE_FACTOR is how many intervals a sweep can go without running
+// before CheckSweeps reports it
+const SWEEP_OVERDUE_FACTOR = 3
+
+// lifecycle is shared by a TaskManager and its views
+type lifecycle struct {
+	draining atomic.Bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	sweeps   sync.WaitGroup
+	// running are the sweeps that have started, by name
+	running   map[string]*sweep
+	sweepLock sync.Mutex
+}
+
+// sweep is the progress of a background sweep
+type sweep struct {
+	interval func() time.Duration
+	last     time.Time
+	stopped  bool
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{stop: make(chan struct{}), running: make(map[string]*sweep)}
+}
+
+// Stop ends the background sweeps, waiting for running ones to finish, so
+// no sweep writes after it returns
+func (tm *TaskManager) Stop() {
+	tm.lifecycle.stopOnce.Do(func() { close(tm.lifecycle.stop) })
+	tm.lifecycle.sweeps.Wait()
+}
+
+// CheckSweeps returns an error if a named sweep has not started, has
+// stopped or has not run for SWEEP_OVERDUE_FACTOR intervals
+func (tm *TaskManager) CheckSweeps(names ...string) error {
+	tm.lifecycle.sweepLock.Lock()
+	defer tm.lifecycle.sweepLock.Unlock()
+
+	now := time.Now()
+	for _, name := range names {
+		s, exists := tm.lifecycle.running[name]
+		switch {
+		case !exists:
+			return fmt.Errorf("%s sweep has not started", name)
+		case s.stopped:
+			return fmt.Errorf("%s sweep has stopped", name)
+		case now.Sub(s.last) > SWEEP_OVERDUE_FACTOR*s.interval():
+			return fmt.Errorf("%s sweep has not run since %s", name, s.last.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// every calls fn after each wait of interval, which is read again every
+// time, until Stop is called. Each run is recorded for CheckSweeps.
+func (tm *TaskManager) every(name string, interval func() time.Duration, fn func()) {
+	tm.lifecycle.sweeps.Add(1)
+	defer tm.lifecycle.sweeps.Done()
+	s := &sweep{interval: interval}
+	tm.sweepRan(name, s, false)
+	defer tm.sweepRan(name, s, true)
+
+	for {
+		timer := time.NewTimer(interval())
+		select {
+		case <-tm.lifecycle.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			fn()
+			tm.sweepRan(name, s, false)
+		}
+	}
+}
+
+func (tm *TaskManager) sweepRan(name string, s *sweep, stopped bool) {
+	tm.lifecycle.sweepLock.Lock()
+	defer tm.lifecycle.sweepLock.Unlock()
+	s.last = time.Now()
+	s.stopped = stopped
+	tm.lifecycle.running[name] = s
+}
@@ -0,0 +1,29 @@
+package managers
+
+import (
+	"log/slog"
+
+	"github.com/indkumar8999/ps-tasks/logging"
+)
+
+// SetLogger makes the task manager log to logger instead of the default
+// logger
+func (tm *TaskManager) SetLogger(logger *slog.Logger) {
+	tm.logger = logger
+}
+
+// log returns the logger annotated with the view's request and trace IDs
+// and tenant
+func (tm *TaskManager) log() *slog.Logger {
+	logger := logging.With(tm.ctx, tm.logger)
+	if tm.tenant != "" {
+		logger = logger.With("tenant", tm.tenant)
+	}
+	return logger
+}
+
+// SetLogger makes the lease manager log to logger instead of the default
+// logger
+func (lm *LeaseManager) SetLogger(logger *slog.Logger) {
+	lm.logger = logger
+}
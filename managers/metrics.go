@@ -0,0 +1,88 @@
+package managers
+
+import (
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// setState moves a task to a state, recording how long it spent in the old
+// one and, for final states, how old it got. The caller must hold taskLock.
+func (tm *TaskManager) setState(t *task.Task, state string, now time.Time) {
+	if t.State != state {
+		queue := queues.Normalize(t.Queue)
+		if since, ok := parseTime(t.StateChangedAt, t.CreatedAt); ok {
+			metrics.TaskStateDuration.WithLabelValues(queue, t.State).Observe(now.Sub(since).Seconds())
+		}
+		if isTerminal(state) && !isTerminal(t.State) {
+			metrics.TasksFinished.WithLabelValues(queue, state).Inc()
+			if createdAt, ok := parseTime(t.CreatedAt); ok {
+				metrics.TaskAge.WithLabelValues(queue, state).Observe(now.Sub(createdAt).Seconds())
+			}
+		}
+		t.StateChangedAt = now.Format(time.RFC3339)
+	}
+	t.State = state
+}
+
+// parseTime parses the first set RFC 3339 time
+func parseTime(values ...string) (time.Time, bool) {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
+
+// QueueDepths counts the tasks visible to the view by queue and state
+func (tm *TaskManager) QueueDepths() map[metrics.Depth]int {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	depths := make(map[metrics.Depth]int)
+	for _, t := range tm.tasks {
+		if tm.visible(t) {
+			depths[metrics.Depth{Queue: queues.Normalize(t.Queue), State: t.State}]++
+		}
+	}
+	return depths
+}
+
+// ObserveLeaseExpiries counts the leases that expired after from and up to
+// to while their task was unfinished
+func (tm *TaskManager) ObserveLeaseExpiries(from time.Time, to time.Time) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	for _, lease := range tm.leaseManager.ExpiredBetween(from, to) {
+		if t, exists := tm.tasks[lease.TaskID]; exists && !isTerminal(t.State) {
+			metrics.LeasesExpired.Inc()
+			tm.log().Warn("lease expired before its task finished", "lease_id", lease.ID, "task_id", lease.TaskID, "owner", lease.CreatedBy)
+		}
+	}
+}
+
+// PeriodicallyReapExpiredLeases counts the leases that expired every
+// LeaseExpirySweepInterval, and then removes the ones counted by the
+// previous sweep. Workers get one more interval to finish a task after
+// their lease expires, unless it is leased again.
+func (tm *TaskManager) PeriodicallyReapExpiredLeases() {
+	last := time.Now()
+	tm.every(SWEEP_LEASE_EXPIRY, func() time.Duration { return tm.Settings().LeaseExpirySweepInterval }, func() {
+		now := time.Now()
+		tm.ObserveLeaseExpiries(last, now)
+		reaped, err := tm.ReapExpiredLeases(last)
+		if err != nil {
+			tm.log().Error("failed to reap expired leases", "error", err)
+		}
+		if reaped > 0 {
+			tm.log().Info("reaped expired leases", "count", reaped)
+		}
+		last = now
+	})
+}
@@ -0,0 +1,78 @@
+package managers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTaskMetrics(t *testing.T) {
+	tm := newTestTaskManager(t)
+	// The metrics are shared by every test, so each check compares against
+	// the value before the call and uses a queue of its own
+	const queue = "metrics-test"
+	created := testutil.ToFloat64(metrics.TasksCreated.WithLabelValues(queue))
+	claimed := testutil.ToFloat64(metrics.TasksClaimed.WithLabelValues(queue))
+	finished := testutil.ToFloat64(metrics.TasksFinished.WithLabelValues(queue, COMPLETED))
+	granted := testutil.ToFloat64(metrics.LeasesGranted)
+
+	first, err := tm.CreateTask("first", "", queue, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if _, err := tm.CreateTask("second", "", queue, nil, nil); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	lease, err := tm.LeaseTask(first.ID, "worker", time.Minute)
+	if err != nil {
+		t.Fatalf("LeaseTask: %v", err)
+	}
+	if _, err := tm.WithLease(lease.ID, lease.FencingToken).CompleteTask(first.ID, nil); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+
+	if got := testutil.ToFloat64(metrics.TasksCreated.WithLabelValues(queue)) - created; got != 2 {
+		t.Errorf("tasks created = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.TasksClaimed.WithLabelValues(queue)) - claimed; got != 1 {
+		t.Errorf("tasks claimed = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.TasksFinished.WithLabelValues(queue, COMPLETED)) - finished; got != 1 {
+		t.Errorf("tasks finished = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.LeasesGranted) - granted; got < 1 {
+		t.Errorf("leases granted rose by %v, want at least 1", got)
+	}
+
+	depths := tm.QueueDepths()
+	if depths[metrics.Depth{Queue: queue, State: CREATED}] != 1 || depths[metrics.Depth{Queue: queue, State: COMPLETED}] != 1 {
+		t.Errorf("queue depths = %v, want one created and one completed task", depths)
+	}
+}
+
+func TestObserveLeaseExpiries(t *testing.T) {
+	tm := newTestTaskManager(t)
+	created, err := tm.CreateTask("task", "", "default", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	lease, err := tm.LeaseTask(created.ID, "worker", time.Minute)
+	if err != nil {
+		t.Fatalf("LeaseTask: %v", err)
+	}
+	expired := testutil.ToFloat64(metrics.LeasesExpired)
+
+	// A sweep before the expiry counts nothing, the one spanning it counts
+	// the lease once
+	tm.ObserveLeaseExpiries(time.Now(), lease.ExpiresAt.Add(-time.Second))
+	if got := testutil.ToFloat64(metrics.LeasesExpired) - expired; got != 0 {
+		t.Errorf("leases expired before the expiry = %v, want 0", got)
+	}
+	tm.ObserveLeaseExpiries(lease.ExpiresAt.Add(-time.Second), lease.ExpiresAt.Add(time.Second))
+	tm.ObserveLeaseExpiries(lease.ExpiresAt.Add(time.Second), lease.ExpiresAt.Add(time.Minute))
+	if got := testutil.ToFloat64(metrics.LeasesExpired) - expired; got != 1 {
+		t.Errorf("leases expired = %v, want 1", got)
+	}
+}
@@ -0,0 +1,177 @@
+package managers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/shards"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// ExportedTask is a copy of a task and its leases taken while moving a slot
+// to another shard. Version identifies the exact content that was copied.
+type ExportedTask struct {
+	Task    *task.Task
+	Leases  []*leases.Lease
+	Version string
+}
+
+// taskVersion returns a digest of a task and its leases, so that a lease
+// granted after the task was exported also counts as a change
+func taskVersion(t *task.Task, taskLeases []*leases.Lease) (string, error) {
+	sorted := append([]*leases.Lease{}, taskLeases...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	data, err := json.Marshal(struct {
+		Task   *task.Task      `json:"task"`
+		Leases []*leases.Lease `json:"leases"`
+	}{t, sorted})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetShardMap returns the shard map published to sharded clients
+func (tm *TaskManager) GetShardMap() (*shards.ShardMap, error) {
+	return tm.shardManager.GetShardMap()
+}
+
+// SetShardMap publishes a new shard map
+func (tm *TaskManager) SetShardMap(shardMap *shards.ShardMap) (*shards.ShardMap, error) {
+	result, err := tm.propose(&Command{Op: OP_SET_SHARD_MAP, Time: time.Now(), ShardMap: shardMap})
+	if err != nil {
+		return nil, err
+	}
+	return result.ShardMap, nil
+}
+
+// ExportSlot copies every task whose ID hashes to the given slot. The tasks
+// and leases are copied while taskLock is held and their versions are
+// computed after it is released.
+func (tm *TaskManager) ExportSlot(slot int, slots int) ([]*ExportedTask, error) {
+	if slots <= 0 || slot < 0 || slot >= slots {
+		return nil, fmt.Errorf("invalid slot %d of %d", slot, slots)
+	}
+
+	tm.taskLock.Lock()
+	var exported []*ExportedTask
+	for _, t := range tm.tasks {
+		if shards.SlotFor(t.ID, slots) != slot {
+			continue
+		}
+		copied := *t
+		var taskLeases []*leases.Lease
+		for _, lease := range tm.leaseManager.LeasesForTask(t.ID) {
+			copiedLease := *lease
+			taskLeases = append(taskLeases, &copiedLease)
+		}
+		exported = append(exported, &ExportedTask{Task: &copied, Leases: taskLeases})
+	}
+	tm.taskLock.Unlock()
+
+	for _, e := range exported {
+		version, err := taskVersion(e.Task, e.Leases)
+		if err != nil {
+			return nil, err
+		}
+		e.Version = version
+	}
+	return exported, nil
+}
+
+// ImportTask stores a task and its leases copied from another shard.
+// It reports false if a newer copy of the task is already present.
+func (tm *TaskManager) ImportTask(t *task.Task, taskLeases []*leases.Lease) (bool, error) {
+	if t == nil || t.ID == "" {
+		return false, fmt.Errorf("invalid task")
+	}
+	result, err := tm.propose(&Command{Op: OP_IMPORT_TASK, Time: time.Now(), Task: t, Leases: taskLeases})
+	if err != nil {
+		return false, err
+	}
+	return result.Applied, nil
+}
+
+// DropTask deletes a task that has been copied to another shard, along with
+// its leases. It reports false, leaving the task alone, if the task changed
+// since it was exported with the given version.
+func (tm *TaskManager) DropTask(taskID string, version string) (bool, error) {
+	result, err := tm.propose(&Command{Op: OP_DROP_TASK, Time: time.Now(), TaskID: taskID, Version: version})
+	if err != nil {
+		return false, err
+	}
+	return result.Applied, nil
+}
+
+func (tm *TaskManager) applyImportTask(cmd *Command) (bool, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	t := cmd.Task
+	if t == nil {
+		return false, fmt.Errorf("task is required")
+	}
+	existing, exists := tm.tasks[t.ID]
+	if exists {
+		existingTime, err1 := time.Parse(time.RFC3339, existing.UpdatedAt)
+		importedTime, err2 := time.Parse(time.RFC3339, t.UpdatedAt)
+		if err1 == nil && err2 == nil && existingTime.After(importedTime) {
+			return false, nil
+		}
+	}
+	// The queue moves to this shard along with its tenant's tasks
+	if t.Tenant != "" {
+		if _, err := tm.queueManager.ClaimQueue(t.GetQueue(), t.Tenant, cmd.Time); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tm.saveTask(t); err != nil {
+		return false, fmt.Errorf("failed to save task: %v", err)
+	}
+	if exists {
+		tm.unrefBlobs(existing)
+	}
+	tm.tasks[t.ID] = t
+	tm.refBlobs(t)
+	for _, lease := range cmd.Leases {
+		if err := tm.leaseManager.PutLease(lease); err != nil {
+			return false, fmt.Errorf("failed to save lease: %v", err)
+		}
+	}
+	return true, nil
+}
+
+func (tm *TaskManager) applyDropTask(cmd *Command) (bool, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	t, exists := tm.tasks[cmd.TaskID]
+	if !exists {
+		return true, nil
+	}
+	version, err := taskVersion(t, tm.leaseManager.LeasesForTask(t.ID))
+	if err != nil {
+		return false, err
+	}
+	if version != cmd.Version {
+		return false, nil
+	}
+
+	if err := store.Remove(tm.tasksDir, t.ID); err != nil {
+		return false, fmt.Errorf("failed to delete task file: %v", err)
+	}
+	delete(tm.tasks, t.ID)
+	tm.unrefBlobs(t)
+	if err := tm.leaseManager.ReleaseLeasesForTask(t.ID); err != nil {
+		return false, fmt.Errorf("failed to release leases: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,197 @@
+package managers
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/codec"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/store"
+)
+
+// DEFAULT_RETENTION applies to queues that have no policy for a state.
+// Tasks in states without a policy are never deleted.
+var DEFAULT_RETENTION = map[string]*queues.RetentionPolicy{
+	COMPLETED: {MaxAgeSeconds: int64((7 * 24 * time.Hour).Seconds())},
+	FAILED:    {MaxAgeSeconds: int64((30 * 24 * time.Hour).Seconds())},
+	ABORTED:   {MaxAgeSeconds: int64((30 * 24 * time.Hour).Seconds())},
+}
+
+// QueueManager keeps the persisted settings of task queues
+type QueueManager struct {
+	queuesDir string
+	queues    map[string]*queues.Queue
+	// defaultRetention applies to queues that have no policy for a state,
+	// DEFAULT_RETENTION unless set with SetDefaultRetention
+	defaultRetention map[string]*queues.RetentionPolicy
+	queueLock        *sync.Mutex
+}
+
+// NewQueueManager creates a new QueueManager
+func NewQueueManager(queuesDir string) (*QueueManager, error) {
+	// Create the queues directory if it doesn't exist
+	if _, err := os.Stat(queuesDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(queuesDir, 0755); err != nil {
+			slog.Error("failed to create queues directory", "path", queuesDir, "error", err)
+			return nil, err
+		}
+	}
+
+	return &QueueManager{
+		queuesDir:        queuesDir,
+		queues:           make(map[string]*queues.Queue),
+		defaultRetention: DEFAULT_RETENTION,
+		queueLock:        &sync.Mutex{},
+	}, nil
+}
+
+// LoadQueues loads all queues from the queues directory. Unreadable queue
+// files are moved into the quarantine, or fail the load if quarantine is nil.
+func (qm *QueueManager) LoadQueues(quarantine *store.Quarantine) error {
+	qm.queueLock.Lock()
+	defer qm.queueLock.Unlock()
+
+	loaded, err := store.LoadAll(qm.queuesDir, "queues", quarantine, func(q *queues.Queue) string { return q.Name })
+	if err != nil {
+		return err
+	}
+	for _, queue := range loaded {
+		qm.queues[queue.Name] = queue
+	}
+	return nil
+}
+
+// GetQueue retrieves a queue by name, returning default settings for unknown queues
+func (qm *QueueManager) GetQueue(name string) *queues.Queue {
+	qm.queueLock.Lock()
+	defer qm.queueLock.Unlock()
+	return qm.getQueueLocked(name)
+}
+
+// getQueueLocked is GetQueue for callers holding the queue lock
+func (qm *QueueManager) getQueueLocked(name string) *queues.Queue {
+	name = queues.Normalize(name)
+	if queue, exists := qm.queues[name]; exists {
+		return queue
+	}
+	return queues.NewQueue(name)
+}
+
+// IsPaused reports whether a queue is paused
+func (qm *QueueManager) IsPaused(name string) bool {
+	return qm.GetQueue(name).Paused
+}
+
+// PauseQueue stops tasks in a queue from being handed out
+func (qm *QueueManager) PauseQueue(name string, now time.Time) (*queues.Queue, error) {
+	return qm.setPaused(name, true, now)
+}
+
+// ResumeQueue lets tasks in a paused queue be handed out again
+func (qm *QueueManager) ResumeQueue(name string, now time.Time) (*queues.Queue, error) {
+	return qm.setPaused(name, false, now)
+}
+
+func (qm *QueueManager) setPaused(name string, paused bool, now time.Time) (*queues.Queue, error) {
+	return qm.update(name, now, func(queue *queues.Queue) error {
+		queue.Paused = paused
+		return nil
+	})
+}
+
+// GetRetentionPolicy returns the retention policy for tasks in a state,
+// or nil if such tasks are kept forever
+func (qm *QueueManager) GetRetentionPolicy(name string, state string) *queues.RetentionPolicy {
+	queue := qm.GetQueue(name)
+	policy, exists := queue.Retention[state]
+	if !exists {
+		qm.queueLock.Lock()
+		policy = qm.defaultRetention[state]
+		qm.queueLock.Unlock()
+	}
+	if policy == nil || policy.MaxAgeSeconds <= 0 {
+		return nil
+	}
+	return policy
+}
+
+// SetDefaultRetention replaces the retention of tasks in queues that have
+// no policy for their state. Every node of a cluster should use the same
+// defaults, since each one checks them before deleting a task.
+func (qm *QueueManager) SetDefaultRetention(retention map[string]*queues.RetentionPolicy) {
+	qm.queueLock.Lock()
+	defer qm.queueLock.Unlock()
+	qm.defaultRetention = retention
+}
+
+// SetRetentionPolicy overrides the retention of tasks in a state for a queue
+func (qm *QueueManager) SetRetentionPolicy(name string, state string, policy *queues.RetentionPolicy, now time.Time) (*queues.Queue, error) {
+	if state == "" {
+		return nil, fmt.Errorf("invalid task state")
+	}
+	if policy == nil || policy.MaxAgeSeconds < 0 {
+		return nil, fmt.Errorf("invalid retention policy")
+	}
+	return qm.update(name, now, func(queue *queues.Queue) error {
+		if queue.Retention == nil {
+			queue.Retention = make(map[string]*queues.RetentionPolicy)
+		}
+		queue.Retention[state] = policy
+		return nil
+	})
+}
+
+// SetCompression sets the codec task payloads in a queue are stored with
+func (qm *QueueManager) SetCompression(name string, compression string, now time.Time) (*queues.Queue, error) {
+	if err := codec.Validate(compression); err != nil {
+		return nil, err
+	}
+	return qm.update(name, now, func(queue *queues.Queue) error {
+		queue.Compression = compression
+		return nil
+	})
+}
+
+// ClaimQueue makes a tenant the owner of a queue that has none, and fails if
+// another tenant owns it
+func (qm *QueueManager) ClaimQueue(name string, tenant string, now time.Time) (*queues.Queue, error) {
+	if owner := qm.GetQueue(name).Tenant; owner == tenant {
+		return qm.GetQueue(name), nil
+	}
+	return qm.update(name, now, func(queue *queues.Queue) error {
+		if queue.Tenant != "" && queue.Tenant != tenant {
+			return fmt.Errorf("queue %s belongs to another tenant", name)
+		}
+		queue.Tenant = tenant
+		return nil
+	})
+}
+
+// update applies a change to a queue and persists it
+func (qm *QueueManager) update(name string, now time.Time, change func(queue *queues.Queue) error) (*queues.Queue, error) {
+	qm.queueLock.Lock()
+	defer qm.queueLock.Unlock()
+
+	if err := queues.ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	queue, exists := qm.queues[name]
+	if !exists {
+		queue = queues.NewQueue(name)
+	}
+	if err := change(queue); err != nil {
+		return nil, err
+	}
+	queue.UpdatedAt = now
+
+	if err := queue.Save(qm.queuesDir); err != nil {
+		return nil, fmt.Errorf("failed to save queue: %v", err)
+	}
+	qm.queues[name] = queue
+
+	return queue, nil
+}
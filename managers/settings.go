@@ -0,0 +1,72 @@
+package managers
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/metrics"
+)
+
+// MIN_LEASE_DURATION is the shortest lease a worker can ask for by default
+const MIN_LEASE_DURATION = 5 * time.Second
+
+// MAX_LEASE_DURATION is the longest lease a worker can ask for by default
+const MAX_LEASE_DURATION = 1 * time.Hour
+
+// Settings are the lease bounds and sweep intervals of a TaskManager. They
+// can be changed while it runs with SetSettings.
+type Settings struct {
+	// LeaseDuration is how long a lease lasts when the worker does not ask
+	// for a duration
+	LeaseDuration time.Duration
+	// MinLeaseDuration and MaxLeaseDuration bound the durations workers ask for
+	MinLeaseDuration time.Duration
+	MaxLeaseDuration time.Duration
+	// RetentionSweepInterval is how often retention policies are applied
+	RetentionSweepInterval time.Duration
+	// CancelSweepInterval is how often cancelled tasks whose lease has
+	// expired are finalized
+	CancelSweepInterval time.Duration
+	// LeaseExpirySweepInterval is how often expired leases are counted and
+	// reaped
+	LeaseExpirySweepInterval time.Duration
+}
+
+// DefaultSettings returns the settings a new TaskManager starts with
+func DefaultSettings() Settings {
+	return Settings{
+		LeaseDuration:            DEFAULT_LEASE_DURATION,
+		MinLeaseDuration:         MIN_LEASE_DURATION,
+		MaxLeaseDuration:         MAX_LEASE_DURATION,
+		RetentionSweepInterval:   RETENTION_SWEEP_INTERVAL,
+		CancelSweepInterval:      CANCEL_SWEEP_INTERVAL,
+		LeaseExpirySweepInterval: metrics.DEFAULT_LEASE_SWEEP_INTERVAL,
+	}
+}
+
+// leaseDuration returns the duration of a lease a worker asked for, zero
+// for the default, within the bounds
+func (s Settings) leaseDuration(requested time.Duration) time.Duration {
+	if requested <= 0 {
+		return s.LeaseDuration
+	}
+	return min(max(requested, s.MinLeaseDuration), s.MaxLeaseDuration)
+}
+
+func newSettings() *atomic.Pointer[Settings] {
+	settings := &atomic.Pointer[Settings]{}
+	defaults := DefaultSettings()
+	settings.Store(&defaults)
+	return settings
+}
+
+// Settings returns the current settings
+func (tm *TaskManager) Settings() Settings {
+	return *tm.settings.Load()
+}
+
+// SetSettings replaces the settings. Leases already granted keep their
+// duration, and sweeps pick up new intervals after their current wait.
+func (tm *TaskManager) SetSettings(settings Settings) {
+	tm.settings.Store(&settings)
+}
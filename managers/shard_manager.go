@@ -0,0 +1,72 @@
+package managers
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/indkumar8999/ps-tasks/shards"
+)
+
+// ShardManager keeps the shard map published to clients of a sharded deployment
+type ShardManager struct {
+	metadataDir string
+	shardMap    *shards.ShardMap
+	shardLock   *sync.Mutex
+}
+
+// NewShardManager creates a new ShardManager
+func NewShardManager(metadataDir string) *ShardManager {
+	return &ShardManager{
+		metadataDir: metadataDir,
+		shardLock:   &sync.Mutex{},
+	}
+}
+
+// LoadShardMap loads the shard map from the metadata directory, if one was saved
+func (sm *ShardManager) LoadShardMap() error {
+	sm.shardLock.Lock()
+	defer sm.shardLock.Unlock()
+
+	shardMap, err := shards.LoadShardMap(sm.metadataDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sm.shardMap = shardMap
+	return nil
+}
+
+// GetShardMap returns the current shard map
+func (sm *ShardManager) GetShardMap() (*shards.ShardMap, error) {
+	sm.shardLock.Lock()
+	defer sm.shardLock.Unlock()
+
+	if sm.shardMap == nil {
+		return nil, fmt.Errorf("no shard map configured")
+	}
+	return sm.shardMap, nil
+}
+
+// SetShardMap replaces the shard map. Maps older than the current one are rejected.
+func (sm *ShardManager) SetShardMap(shardMap *shards.ShardMap) (*shards.ShardMap, error) {
+	sm.shardLock.Lock()
+	defer sm.shardLock.Unlock()
+
+	if shardMap == nil {
+		return nil, fmt.Errorf("shard map is required")
+	}
+	if err := shardMap.Validate(); err != nil {
+		return nil, err
+	}
+	if sm.shardMap != nil && shardMap.Version < sm.shardMap.Version {
+		return nil, fmt.Errorf("shard map version %d is older than %d", shardMap.Version, sm.shardMap.Version)
+	}
+	if err := shardMap.Save(sm.metadataDir); err != nil {
+		return nil, fmt.Errorf("failed to save shard map: %v", err)
+	}
+	sm.shardMap = shardMap
+	return shardMap, nil
+}
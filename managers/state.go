@@ -0,0 +1,171 @@
+package managers
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/shards"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// State is a point-in-time copy of everything the managers persist
+type State struct {
+	Tasks  []*task.Task    `json:"tasks"`
+	Leases []*leases.Lease `json:"leases"`
+	Queues []*queues.Queue `json:"queues"`
+	// ShardMap is nil unless the deployment is sharded
+	ShardMap *shards.ShardMap `json:"shard_map,omitempty"`
+}
+
+// ExportState serializes the current tasks, leases, queues and shard map.
+// The records are copied while the managers' locks are held and encoded
+// after they are released.
+func (tm *TaskManager) ExportState() ([]byte, error) {
+	tm.taskLock.Lock()
+	tm.leaseManager.leaseLock.Lock()
+	tm.queueManager.queueLock.Lock()
+	tm.shardManager.shardLock.Lock()
+
+	var state State
+	if tm.shardManager.shardMap != nil {
+		shardMap := *tm.shardManager.shardMap
+		state.ShardMap = &shardMap
+	}
+	for _, t := range tm.tasks {
+		copied := *t
+		state.Tasks = append(state.Tasks, &copied)
+	}
+	for _, lease := range tm.leaseManager.leases {
+		copied := *lease
+		state.Leases = append(state.Leases, &copied)
+	}
+	for _, queue := range tm.queueManager.queues {
+		copied := *queue
+		// Retention policies are changed in place
+		copied.Retention = maps.Clone(queue.Retention)
+		state.Queues = append(state.Queues, &copied)
+	}
+
+	tm.shardManager.shardLock.Unlock()
+	tm.queueManager.queueLock.Unlock()
+	tm.leaseManager.leaseLock.Unlock()
+	tm.taskLock.Unlock()
+	return json.Marshal(state)
+}
+
+// RestoreState replaces the current tasks, leases and queues with an
+// exported state, both on disk and in memory. The state may be sealed by
+// ExportSealedState.
+func (tm *TaskManager) RestoreState(data []byte) error {
+	data, err := tm.open("state", data)
+	if err != nil {
+		return err
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode state: %v", err)
+	}
+	return tm.restoreState(&state)
+}
+
+// ReplaceState replaces the whole state through the replicator, so every
+// node of a cluster ends up with the same copy
+func (tm *TaskManager) ReplaceState(state *State) error {
+	_, err := tm.propose(&Command{Op: OP_RESTORE_STATE, Time: time.Now(), Restore: state})
+	return err
+}
+
+// restoreState writes the new records before it removes the ones the state
+// does not have, so a crash part way leaves the restored records on disk
+// next to some old ones rather than an empty database.
+func (tm *TaskManager) restoreState(state *State) error {
+	if state == nil {
+		return fmt.Errorf("state is required")
+	}
+
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+	tm.leaseManager.leaseLock.Lock()
+	defer tm.leaseManager.leaseLock.Unlock()
+	tm.queueManager.queueLock.Lock()
+	defer tm.queueManager.queueLock.Unlock()
+	tm.shardManager.shardLock.Lock()
+	defer tm.shardManager.shardLock.Unlock()
+
+	restoredQueues := make(map[string]*queues.Queue)
+	for _, queue := range state.Queues {
+		if err := queue.Save(tm.queueManager.queuesDir); err != nil {
+			return fmt.Errorf("failed to save queue: %v", err)
+		}
+		restoredQueues[queue.Name] = queue
+	}
+	// Queues are restored first, so tasks are saved with their codec
+	tm.queueManager.queues = restoredQueues
+	restoredTasks := make(map[string]bool)
+	for _, t := range state.Tasks {
+		compression := tm.queueManager.getQueueLocked(t.Queue).Compression
+		if err := t.Save(tm.tasksDir, tm.keyring, compression); err != nil {
+			return fmt.Errorf("failed to save task: %v", err)
+		}
+		restoredTasks[t.ID] = true
+	}
+	restoredLeases := make(map[string]*leases.Lease)
+	for _, lease := range state.Leases {
+		if err := lease.Save(tm.leaseManager.leasesDir); err != nil {
+			return fmt.Errorf("failed to save lease: %v", err)
+		}
+		restoredLeases[lease.ID] = lease
+	}
+	if state.ShardMap != nil {
+		if err := state.ShardMap.Save(tm.shardManager.metadataDir); err != nil {
+			return fmt.Errorf("failed to save shard map: %v", err)
+		}
+	} else if err := os.Remove(filepath.Join(tm.shardManager.metadataDir, shards.SHARD_MAP_FILE)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove shard map: %v", err)
+	}
+
+	if err := removeOthers(tm.queueManager.queuesDir, func(name string) bool { return restoredQueues[name] != nil }); err != nil {
+		return err
+	}
+	if err := removeOthers(tm.tasksDir, func(id string) bool { return restoredTasks[id] }); err != nil {
+		return err
+	}
+	if err := removeOthers(tm.leaseManager.leasesDir, func(id string) bool { return restoredLeases[id] != nil }); err != nil {
+		return err
+	}
+
+	// The map is cleared rather than replaced because tenant views share it
+	clear(tm.tasks)
+	for _, t := range state.Tasks {
+		tm.tasks[t.ID] = t
+	}
+	tm.resetBlobRefs()
+	tm.leaseManager.leases = restoredLeases
+	tm.shardManager.shardMap = state.ShardMap
+	return nil
+}
+
+// removeOthers removes the record files in a directory that keep does not
+// accept
+func removeOthers(dir string, keep func(id string) bool) error {
+	ids, err := store.List(dir)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if keep(id) {
+			continue
+		}
+		if err := store.Remove(dir, id); err != nil {
+			return fmt.Errorf("failed to remove %s: %v", store.FileName(id), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package managers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/shards"
+	"github.com/indkumar8999/ps-tasks/store"
+)
+
+func TestRestoreState(t *testing.T) {
+	source := newTestTaskManager(t)
+	created, err := source.CreateTask("task", "", "default", []byte("input"), nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if _, err := source.PauseQueue("default"); err != nil {
+		t.Fatalf("PauseQueue: %v", err)
+	}
+	data, err := source.ExportState()
+	if err != nil {
+		t.Fatalf("ExportState: %v", err)
+	}
+
+	target := newTestTaskManager(t)
+	restored := make(chan error, 1)
+	go func() { restored <- target.RestoreState(data) }()
+	select {
+	case err := <-restored:
+		if err != nil {
+			t.Fatalf("RestoreState: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("RestoreState did not return")
+	}
+	got, err := target.GetTask(created.ID)
+	if err != nil || string(got.Input) != "input" {
+		t.Errorf("restored task = %+v, %v, want the exported task", got, err)
+	}
+	if !target.queueManager.IsPaused("default") {
+		t.Errorf("restored queue is not paused")
+	}
+}
+
+func TestRestoreStateRemovesStaleRecords(t *testing.T) {
+	source := newTestTaskManager(t)
+	kept, err := source.CreateTask("kept", "", "default", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	data, err := source.ExportState()
+	if err != nil {
+		t.Fatalf("ExportState: %v", err)
+	}
+
+	target := newTestTaskManager(t)
+	stale, err := target.CreateTask("stale", "", "default", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	shardMapPath := filepath.Join(target.shardManager.metadataDir, shards.SHARD_MAP_FILE)
+	if err := os.WriteFile(shardMapPath, []byte(`{"version":1}`), 0644); err != nil {
+		t.Fatalf("failed to write shard map: %v", err)
+	}
+
+	if err := target.RestoreState(data); err != nil {
+		t.Fatalf("RestoreState: %v", err)
+	}
+	ids, err := store.List(target.tasksDir)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != kept.ID {
+		t.Errorf("task records = %v, want only %s", ids, kept.ID)
+	}
+	if _, err := target.GetTask(stale.ID); err == nil {
+		t.Errorf("stale task is still loaded")
+	}
+	if _, err := os.Stat(shardMapPath); !os.IsNotExist(err) {
+		t.Errorf("shard map the state does not have was kept: %v", err)
+	}
+}
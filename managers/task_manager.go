@@ -2,14 +2,25 @@ package managers
 
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+	"log/slog"
+	"sort"
 	"time"
 	"github.com/google/uuid"
 	"sync"
+	"sync/atomic"
 	"github.com/indkumar8999/ps-tasks/task"
 	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/archive"
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/codec"
+	"github.com/indkumar8999/ps-tasks/keyring"
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/ratelimit"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/tenants"
 )
 
 // TaskManager manages tasks and leases
@@ -18,6 +29,27 @@ type TaskManager struct {
 	tasksDir string
 	tasks     map[string]*task.Task
 	leaseManager *LeaseManager
+	queueManager *QueueManager
+	shardManager *ShardManager
+	archive *archive.Archive
+	replicator Replicator
+	keyring *keyring.Keyring
+	blobs *blobs.Store
+	blobThreshold int
+	// tenant and quota are set on views returned by ForTenant
+	tenant string
+	quota tenants.Quota
+	// ctx is set on views returned by WithContext
+	ctx context.Context
+	// leaseID and fencingToken are set on views returned by WithLease
+	leaseID string
+	fencingToken uint64
+	logger *slog.Logger
+	createLimits *ratelimit.Limiters
+	uploads *blobUploads
+	fetches *blobFetches
+	settings *atomic.Pointer[Settings]
+	lifecycle *lifecycle
 	taskLock  *sync.Mutex
 }
 
@@ -33,73 +65,265 @@ const (
 	COMPLETED = "completed"
 )
 
+// RETENTION_SWEEP_INTERVAL is how often retention policies are applied
+const RETENTION_SWEEP_INTERVAL = 1 * time.Hour
+
+// CANCEL_SWEEP_INTERVAL is how often cancelled tasks whose lease has expired are finalized
+const CANCEL_SWEEP_INTERVAL = 30 * time.Second
+
+// DEFAULT_LEASE_DURATION is how long a lease lasts before it has to be
+// extended, unless the worker asks for another duration
+const DEFAULT_LEASE_DURATION = 3 * time.Minute
+
 // NewTaskManager creates a new TaskManager
-func NewTaskManager(tasksDir string, leaseManager *LeaseManager) *TaskManager {
+func NewTaskManager(tasksDir string, leaseManager *LeaseManager, queueManager *QueueManager, shardManager *ShardManager, taskArchive *archive.Archive) *TaskManager {
 	return &TaskManager{
 		tasksDir:    tasksDir,
 		tasks:       make(map[string]*task.Task),
 		leaseManager: leaseManager,
+		queueManager: queueManager,
+		shardManager: shardManager,
+		archive:     taskArchive,
+		createLimits: ratelimit.NewLimiters(),
+		uploads:     newBlobUploads(),
+		fetches:     newBlobFetches(),
+		logger:      slog.Default(),
+		settings:    newSettings(),
+		lifecycle:   newLifecycle(),
 		taskLock:    &sync.Mutex{},
 	}
 }
 
-func (tm *TaskManager) LoadTasks() {
+// SetReplicator makes every mutation go through the given replicator
+// instead of being applied directly
+func (tm *TaskManager) SetReplicator(replicator Replicator) {
+	tm.replicator = replicator
+}
+
+// SetKeyring makes task payloads be encrypted at rest with the given
+// keyring. It must be called before the tasks are loaded.
+func (tm *TaskManager) SetKeyring(keys *keyring.Keyring) {
+	tm.keyring = keys
+}
+
+// saveTask writes a task, compressed with its queue's codec and sealed
+// with the keyring if one is set. It is called with the task lock held.
+func (tm *TaskManager) saveTask(t *task.Task) error {
+	return t.Save(tm.tasksDir, tm.keyring, tm.queueManager.GetQueue(t.Queue).Compression)
+}
+
+// isLeader reports whether this node should run background sweeps
+func (tm *TaskManager) isLeader() bool {
+	return tm.replicator == nil || tm.replicator.IsLeader()
+}
+
+// replicate applies a command, through the replicator if one is set
+func (tm *TaskManager) replicate(cmd *Command) (*CommandResult, error) {
+	if tm.tenant != "" {
+		cmd.Tenant = tm.tenant
+	}
+	var result *CommandResult
+	if tm.replicator == nil {
+		result = tm.Apply(cmd)
+	} else {
+		var err error
+		result, err = tm.replicator.Propose(cmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Apply applies a single command to the task manager state. It is called
+// directly without replication, or for every committed log entry otherwise.
+func (tm *TaskManager) Apply(cmd *Command) *CommandResult {
+	result := &CommandResult{}
+	var err error
+	switch cmd.Op {
+	case OP_CREATE_TASK:
+		result.Task, err = tm.applyCreateTask(cmd)
+	case OP_UPDATE_TASK:
+		result.Task, err = tm.applyUpdateTask(cmd)
+	case OP_COMPLETE_TASK:
+		result.Task, err = tm.applyCompleteTask(cmd)
+	case OP_FAIL_TASK:
+		result.Task, err = tm.applyFailTask(cmd)
+	case OP_DELETE_TASK:
+		err = tm.applyDeleteTask(cmd)
+	case OP_LEASE_TASK:
+		result.Lease, err = tm.applyLeaseTask(cmd)
+	case OP_REPORT_PROGRESS:
+		result.Task, result.Lease, err = tm.applyReportProgress(cmd)
+	case OP_CANCEL_TASK:
+		result.Task, err = tm.applyCancelTask(cmd)
+	case OP_ACKNOWLEDGE_CANCEL:
+		result.Task, err = tm.applyAcknowledgeCancel(cmd)
+	case OP_FINALIZE_CANCEL:
+		result.Task, err = tm.applyFinalizeCancel(cmd)
+	case OP_PAUSE_TASK:
+		result.Task, err = tm.applyPauseTask(cmd)
+	case OP_RESUME_TASK:
+		result.Task, err = tm.applyResumeTask(cmd)
+	case OP_EXPIRE_TASK:
+		err = tm.applyExpireTask(cmd)
+	case OP_PAUSE_QUEUE:
+		result.Queue, err = tm.queueManager.PauseQueue(cmd.Queue, cmd.Time)
+	case OP_RESUME_QUEUE:
+		result.Queue, err = tm.queueManager.ResumeQueue(cmd.Queue, cmd.Time)
+	case OP_SET_RETENTION_POLICY:
+		result.Queue, err = tm.queueManager.SetRetentionPolicy(cmd.Queue, cmd.State, cmd.Retention, cmd.Time)
+	case OP_SET_COMPRESSION:
+		result.Queue, err = tm.queueManager.SetCompression(cmd.Queue, cmd.Compression, cmd.Time)
+	case OP_SET_SHARD_MAP:
+		result.ShardMap, err = tm.shardManager.SetShardMap(cmd.ShardMap)
+	case OP_IMPORT_TASK:
+		result.Applied, err = tm.applyImportTask(cmd)
+	case OP_DROP_TASK:
+		result.Applied, err = tm.applyDropTask(cmd)
+	case OP_FENCE_LEASES:
+		_, err = tm.leaseManager.ReleaseLeasesBefore(cmd.Term)
+	case OP_REAP_LEASES:
+		_, err = tm.leaseManager.CleanupExpiredLeases(cmd.Time)
+	case OP_RESTORE_STATE:
+		err = tm.restoreState(cmd.Restore)
+	default:
+		err = fmt.Errorf("unknown command: %s", cmd.Op)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (tm *TaskManager) LoadTasks(quarantine *store.Quarantine) error {
 	tm.taskLock.Lock()
 	defer tm.taskLock.Unlock()
 
-	files, err := os.ReadDir(tm.tasksDir)
+	tasks, err := store.LoadAll(tm.tasksDir, "tasks", quarantine, func(t *task.Task) string { return t.ID })
 	if err != nil {
-		fmt.Printf("Error reading tasks directory: %v\n", err)
-		return
+		return err
 	}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		taskID := file.Name()
-		task, err := task.LoadTask(tm.tasksDir, taskID)
-		if err != nil {
-			fmt.Printf("Error loading task: %v\n", err)
-			continue
+	for _, t := range tasks {
+		if err := t.Unpack(tm.keyring); err != nil {
+			return err
 		}
-		tm.tasks[task.ID] = task
+		tm.tasks[t.ID] = t
 	}
+	tm.resetBlobRefs()
+	tm.log().Info("loaded tasks", "count", len(tasks))
+	return nil
 }
 
-func (tm *TaskManager) PeriodicallyDeleteTasks() {
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
+// PeriodicallyApplyRetention deletes or archives tasks whose queue retention policy has expired
+func (tm *TaskManager) PeriodicallyApplyRetention() {
+	tm.every(SWEEP_RETENTION, func() time.Duration { return tm.Settings().RetentionSweepInterval }, func() {
+		if err := tm.ApplyRetention(time.Now()); err != nil {
+			tm.log().Error("failed to apply retention", "error", err)
+		}
+	})
+}
 
-	for {
-		select {
-		case <-ticker.C:
-			threshold := time.Now().Add(-24 * time.Hour) // 24 hours threshold
-			if err := tm.DeleteOlderTasks(threshold); err != nil {
-				fmt.Printf("Error deleting older tasks: %v\n", err)
-			}
+// PeriodicallyFinalizeCancelledTasks aborts cancelled tasks once their lease expires
+func (tm *TaskManager) PeriodicallyFinalizeCancelledTasks() {
+	tm.every(SWEEP_CANCEL, func() time.Duration { return tm.Settings().CancelSweepInterval }, func() {
+		if err := tm.FinalizeCancelledTasks(); err != nil {
+			tm.log().Error("failed to finalize cancelled tasks", "error", err)
 		}
-	}
+	})
 }
 
 // CreateTask creates a new task
-func (tm *TaskManager) CreateTask(name string, description string, data []byte, metadata map[string]string) (*task.Task, error) {
+func (tm *TaskManager) CreateTask(name string, description string, queue string, input []byte, metadata map[string]string) (*task.Task, error) {
+	// Generate a unique ID for the task
+	return tm.CreateTaskWithID(uuid.New().String(), name, description, queue, input, metadata)
+}
+
+// CreateTaskWithID creates a new task with a caller chosen ID, which lets
+// sharded clients route the task before it exists
+func (tm *TaskManager) CreateTaskWithID(taskID string, name string, description string, queue string, input []byte, metadata map[string]string) (*task.Task, error) {
+	input, inputBlob, err := tm.offload(input)
+	if err != nil {
+		return nil, err
+	}
+	return tm.createTask(taskID, name, description, queue, input, inputBlob, metadata)
+}
+
+func (tm *TaskManager) createTask(taskID string, name string, description string, queue string, input []byte, inputBlob *task.BlobRef, metadata map[string]string) (*task.Task, error) {
+	if taskID == "" {
+		taskID = uuid.New().String()
+	}
+	if _, err := uuid.Parse(taskID); err != nil {
+		return nil, fmt.Errorf("invalid task ID: %v", err)
+	}
+	queue = tm.tenantQueue(queue)
+	size := int64(len(input))
+	if inputBlob != nil {
+		size += inputBlob.Size
+	}
+	if err := tm.checkQuota(size, time.Now()); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{
+		Op:          OP_CREATE_TASK,
+		Time:        time.Now(),
+		TaskID:      taskID,
+		Name:        name,
+		Description: description,
+		Queue:       queue,
+		Input:       input,
+		InputBlob:   inputBlob,
+		Metadata:    metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Task, nil
+}
+
+func (tm *TaskManager) applyCreateTask(cmd *Command) (*task.Task, error) {
 	tm.taskLock.Lock()
 	defer tm.taskLock.Unlock()
 
-	// Generate a unique ID for the task
-	taskID := uuid.New().String()
+	queue := queues.Normalize(cmd.Queue)
+	if err := queues.ValidateName(queue); err != nil {
+		return nil, err
+	}
+
+	taskID := cmd.TaskID
+	if _, exists := tm.tasks[taskID]; exists {
+		return nil, fmt.Errorf("task already exists")
+	}
+
+	// A tenant's first task in a queue claims it, and tasks belong to the
+	// tenant of their queue
+	if cmd.Tenant != "" {
+		if _, err := tm.queueManager.ClaimQueue(queue, cmd.Tenant, cmd.Time); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create a new task
-	newTask := task.NewTask(taskID, name, description, time.Now().Format(time.RFC3339), time.Now().Format(time.RFC3339), CREATED, data, metadata)
+	now := cmd.Time.Format(time.RFC3339)
+	newTask := task.NewTask(taskID, cmd.Name, cmd.Description, now, now, CREATED, cmd.Input, cmd.Metadata)
+	newTask.Queue = queue
+	newTask.Tenant = tm.queueManager.GetQueue(queue).Tenant
+	newTask.InputBlob = cmd.InputBlob
+	newTask.StateChangedAt = now
+	newTask.TraceContext = cmd.TraceContext
 
 	// Save the task to the tasks directory
-	if err := newTask.Save(tm.tasksDir); err != nil {
+	if err := tm.saveTask(newTask); err != nil {
 		return nil, fmt.Errorf("failed to save task: %v", err)
 	}
 
 	// Add the task to the in-memory map
 	tm.tasks[taskID] = newTask
+	tm.refBlobs(newTask)
+	metrics.TasksCreated.WithLabelValues(queue).Inc()
 
 	return newTask, nil
 }
@@ -112,14 +336,24 @@ func (tm *TaskManager) GetTask(taskID string) (*task.Task, error) {
 
 	// Check if the task exists in the in-memory map
 	if task, exists := tm.tasks[taskID]; exists {
+		if !tm.visible(task) {
+			return nil, fmt.Errorf("task not found")
+		}
 		return task, nil
 	}
 
-	// If not found in memory, load from disk
-	task, err := task.LoadTask(tm.tasksDir, taskID)
+	// If not found in memory, load from disk. IDs that cannot name a task
+	// file are not looked up.
+	if !store.ValidID(taskID) {
+		return nil, fmt.Errorf("task not found")
+	}
+	task, err := task.LoadTask(tm.tasksDir, taskID, tm.keyring)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load task: %v", err)
 	}
+	if !tm.visible(task) {
+		return nil, fmt.Errorf("task not found")
+	}
 
 	// Add the loaded task to the in-memory map
 	tm.tasks[taskID] = task
@@ -127,24 +361,106 @@ func (tm *TaskManager) GetTask(taskID string) (*task.Task, error) {
 	return task, nil
 }
 
-// UpdateTask updates a task by ID
-func (tm *TaskManager) UpdateTask(taskID string, taskState string, data []byte) (*task.Task, error) {
+// ListTasks returns tasks in creation order, optionally filtered by queue and
+// state. A limit of zero returns every match.
+func (tm *TaskManager) ListTasks(queue string, state string, limit int) []*task.Task {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	var matches []*task.Task
+	for _, t := range tm.tasks {
+		if !tm.visible(t) {
+			continue
+		}
+		if queue != "" && t.GetQueue() != queues.Normalize(queue) {
+			continue
+		}
+		if state != "" && t.State != state {
+			continue
+		}
+		matches = append(matches, t)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].CreatedAt != matches[j].CreatedAt {
+			return matches[i].CreatedAt < matches[j].CreatedAt
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// FenceLeases releases every lease granted before the current fencing term,
+// so tasks leased from a deposed primary can be handed out again
+func (tm *TaskManager) FenceLeases() error {
+	_, err := tm.propose(&Command{Op: OP_FENCE_LEASES, Time: time.Now()})
+	return err
+}
+
+// ReapExpiredLeases removes the leases that expired at or before a time.
+// Workers still holding one can no longer finish their task with it.
+func (tm *TaskManager) ReapExpiredLeases(before time.Time) (int, error) {
+	if !tm.isLeader() {
+		return 0, nil
+	}
+	// Nothing is proposed while there is nothing to reap
+	expired := len(tm.leaseManager.ExpiredBetween(time.Time{}, before))
+	if expired == 0 {
+		return 0, nil
+	}
+	if _, err := tm.propose(&Command{Op: OP_REAP_LEASES, Time: before}); err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// UpdateTask sets the state of a task and, unless input is nil, replaces
+// its input
+func (tm *TaskManager) UpdateTask(taskID string, taskState string, input []byte) (*task.Task, error) {
+	if err := tm.checkTask(taskID); err != nil {
+		return nil, err
+	}
+	input, inputBlob, err := tm.offload(input)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_UPDATE_TASK, Time: time.Now(), TaskID: taskID, State: taskState, Input: input, InputBlob: inputBlob})
+	if err != nil {
+		return nil, err
+	}
+	return result.Task, nil
+}
+
+func (tm *TaskManager) applyUpdateTask(cmd *Command) (*task.Task, error) {
 	tm.taskLock.Lock()
 	defer tm.taskLock.Unlock()
 
 	// Check if the task exists
-	task, exists := tm.tasks[taskID]
+	task, exists := tm.tasks[cmd.TaskID]
 	if !exists {
 		return nil, fmt.Errorf("task not found")
 	}
 
-	// Update the task fields
-	task.Data = data
-	task.State = taskState
-	task.UpdatedAt = time.Now().Format(time.RFC3339)
+	// Update the task fields. Commands logged before updates replaced the
+	// input carry it in Data.
+	input := cmd.Input
+	if input == nil {
+		input = cmd.Data
+	}
+	if input != nil || cmd.InputBlob != nil {
+		tm.unrefBlobs(task)
+		task.Input = input
+		task.InputBlob = cmd.InputBlob
+		task.Data = nil
+		tm.refBlobs(task)
+	}
+	tm.setState(task, cmd.State, cmd.Time)
+	task.UpdatedAt = cmd.Time.Format(time.RFC3339)
 
 	// Save the updated task to disk
-	if err := task.Save(tm.tasksDir); err != nil {
+	if err := tm.saveTask(task); err != nil {
 		return nil, fmt.Errorf("failed to save updated task: %v", err)
 	}
 
@@ -152,44 +468,146 @@ func (tm *TaskManager) UpdateTask(taskID string, taskState string, data []byte)
 }
 
 
-// CompleteTask marks a task as completed
-func (tm *TaskManager) CompleteTask(taskID string) (*task.Task, error) {
+// CompleteTask marks a task as completed and records its result
+func (tm *TaskManager) CompleteTask(taskID string, result []byte) (*task.Task, error) {
+	result, resultBlob, err := tm.offload(result)
+	if err != nil {
+		return nil, err
+	}
+	return tm.completeTask(taskID, result, resultBlob)
+}
+
+func (tm *TaskManager) completeTask(taskID string, result []byte, resultBlob *task.BlobRef) (*task.Task, error) {
+	if err := tm.checkTask(taskID); err != nil {
+		return nil, err
+	}
+	if err := tm.checkLeaseHeld(taskID); err != nil {
+		return nil, err
+	}
+	res, err := tm.propose(&Command{
+		Op:           OP_COMPLETE_TASK,
+		Time:         time.Now(),
+		TaskID:       taskID,
+		LeaseID:      tm.leaseID,
+		FencingToken: tm.fencingToken,
+		Result:       result,
+		ResultBlob:   resultBlob,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Task, nil
+}
+
+func (tm *TaskManager) applyCompleteTask(cmd *Command) (*task.Task, error) {
 	tm.taskLock.Lock()
 	defer tm.taskLock.Unlock()
 	// Check if the task exists
-	task, exists := tm.tasks[taskID]
+	task, exists := tm.tasks[cmd.TaskID]
 	if !exists {
 		return nil, fmt.Errorf("task not found")
 	}
-	// Mark the task as completed
-	task.State = COMPLETED
-	task.UpdatedAt = time.Now().Format(time.RFC3339)
+	if isTerminal(task.State) {
+		return nil, fmt.Errorf("task is already %s", task.State)
+	}
+	if _, err := tm.checkFence(task.ID, cmd.LeaseID, cmd.FencingToken, cmd.Time); err != nil {
+		return nil, err
+	}
+	// Mark the task as completed; a pending cancellation no longer applies
+	tm.setState(task, COMPLETED, cmd.Time)
+	task.CancelRequested = false
+	tm.unrefBlobs(task)
+	task.Result = cmd.Result
+	task.ResultBlob = cmd.ResultBlob
+	tm.refBlobs(task)
+	task.Error = nil
+	task.UpdatedAt = cmd.Time.Format(time.RFC3339)
 	// Save the updated task to disk
-	if err := task.Save(tm.tasksDir); err != nil {
+	if err := tm.saveTask(task); err != nil {
 		return nil, fmt.Errorf("failed to save updated task: %v", err)
 	}
-	// Remove the task from the in-memory map
-	delete(tm.tasks, taskID)
 	return task, nil
 }
+
+// FailTask marks a task as failed and records the error
+func (tm *TaskManager) FailTask(taskID string, taskErr *task.TaskError) (*task.Task, error) {
+	if taskErr == nil {
+		return nil, fmt.Errorf("task error is required")
+	}
+	if err := tm.checkTask(taskID); err != nil {
+		return nil, err
+	}
+	if err := tm.checkLeaseHeld(taskID); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{
+		Op:           OP_FAIL_TASK,
+		Time:         time.Now(),
+		TaskID:       taskID,
+		LeaseID:      tm.leaseID,
+		FencingToken: tm.fencingToken,
+		Error:        taskErr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Task, nil
+}
+
+func (tm *TaskManager) applyFailTask(cmd *Command) (*task.Task, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+	// Check if the task exists
+	t, exists := tm.tasks[cmd.TaskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found")
+	}
+	if isTerminal(t.State) {
+		return nil, fmt.Errorf("task is already %s", t.State)
+	}
+	if _, err := tm.checkFence(t.ID, cmd.LeaseID, cmd.FencingToken, cmd.Time); err != nil {
+		return nil, err
+	}
+	// Mark the task as failed; a pending cancellation no longer applies
+	tm.setState(t, FAILED, cmd.Time)
+	t.CancelRequested = false
+	t.Error = cmd.Error
+	t.UpdatedAt = cmd.Time.Format(time.RFC3339)
+	// Save the updated task to disk
+	if err := tm.saveTask(t); err != nil {
+		return nil, fmt.Errorf("failed to save updated task: %v", err)
+	}
+	return t, nil
+}
 	
 
 // DeleteTask deletes a task by ID
 func (tm *TaskManager) DeleteTask(taskID string) error {
+	if err := tm.checkTask(taskID); err != nil {
+		return err
+	}
+	_, err := tm.propose(&Command{Op: OP_DELETE_TASK, Time: time.Now(), TaskID: taskID})
+	return err
+}
+
+func (tm *TaskManager) applyDeleteTask(cmd *Command) error {
 	tm.taskLock.Lock()
 	defer tm.taskLock.Unlock()
 
+	taskID := cmd.TaskID
+
 	// Check if the task exists
-	if _, exists := tm.tasks[taskID]; !exists {
+	t, exists := tm.tasks[taskID]
+	if !exists {
 		return fmt.Errorf("task not found")
 	}
 
 	// Delete the task from the in-memory map
 	delete(tm.tasks, taskID)
+	tm.unrefBlobs(t)
 
 	// Delete the task file from disk
-	taskFile := filepath.Join(tm.tasksDir, fmt.Sprintf("%s.json", taskID))
-	if err := os.Remove(taskFile); err != nil {
+	if err := store.Remove(tm.tasksDir, taskID); err != nil {
 		return fmt.Errorf("failed to delete task file: %v", err)
 	}
 
@@ -197,78 +615,572 @@ func (tm *TaskManager) DeleteTask(taskID string) error {
 }
 
 func (tm *TaskManager) GetUnLeasdTask() (*task.Task, error) {
-	tm.taskLock.Lock()
-	defer tm.taskLock.Unlock()
+	return tm.GetUnLeasedTask("")
+}
 
-	for _, task := range tm.tasks {
-		if task.State == CREATED {
-			return task, nil
+
+// ApplyRetention deletes tasks that have been in their current state longer than
+// their queue's retention policy allows, archiving them first if the policy asks for it.
+// Leased tasks and tasks in states without a policy are always kept.
+func (tm *TaskManager) ApplyRetention(now time.Time) error {
+	if !tm.isLeader() {
+		return nil
+	}
+
+	tm.taskLock.Lock()
+	var expired []string
+	for _, t := range tm.tasks {
+		if tm.isExpired(t, now) {
+			expired = append(expired, t.ID)
 		}
 	}
+	tm.taskLock.Unlock()
 
-	return nil, fmt.Errorf("no unleased tasks available")
+	for _, taskID := range expired {
+		if _, err := tm.propose(&Command{Op: OP_EXPIRE_TASK, Time: now, TaskID: taskID}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// isExpired reports whether a task has outlived its retention policy at now.
+// The caller must hold taskLock.
+func (tm *TaskManager) isExpired(t *task.Task, now time.Time) bool {
+	policy := tm.queueManager.GetRetentionPolicy(t.Queue, t.State)
+	if policy == nil {
+		return false
+	}
+	updatedAt, err := time.Parse(time.RFC3339, t.UpdatedAt)
+	if err != nil {
+		tm.log().Warn("failed to parse update time", "task_id", t.ID, "error", err)
+		return false
+	}
+	if now.Sub(updatedAt) < policy.MaxAge() {
+		return false
+	}
+	if _, err := tm.leaseManager.GetActiveLeaseForTask(t.ID, now); err == nil {
+		return false
+	}
+	return true
+}
 
-// delete older tasks
-func (tm *TaskManager) DeleteOlderTasks(threshold time.Time) error {
+func (tm *TaskManager) applyExpireTask(cmd *Command) error {
 	tm.taskLock.Lock()
 	defer tm.taskLock.Unlock()
 
-	files, err := os.ReadDir(tm.tasksDir)
-	if err != nil {
-		return fmt.Errorf("failed to read tasks directory: %v", err)
+	t, exists := tm.tasks[cmd.TaskID]
+	if !exists || !tm.isExpired(t, cmd.Time) {
+		return nil
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		taskID := file.Name()
-		task, err := task.LoadTask(tm.tasksDir, taskID)
+	// Archive before deleting so an archive failure never loses tasks
+	if tm.queueManager.GetRetentionPolicy(t.Queue, t.State).Archive {
+		// Archive files are gzipped already, so payloads are only sealed
+		archived, err := t.Pack(tm.keyring, codec.NONE)
 		if err != nil {
-			return fmt.Errorf("failed to load task: %v", err)
+			return err
 		}
-		// Check if the task is older than the threshold
-		taskTime, err := time.Parse(time.RFC3339, task.CreatedAt)
-		if taskTime.Before(threshold) {
-			if err := os.Remove(filepath.Join(tm.tasksDir, fmt.Sprintf("%s.json", task.ID))); err != nil {
-				return fmt.Errorf("failed to delete task file: %v", err)
-			}
-			delete(tm.tasks, task.ID)
+		if err := tm.archive.Append([]*task.Task{archived}); err != nil {
+			return fmt.Errorf("failed to archive task: %v", err)
 		}
 	}
+
+	if err := store.Remove(tm.tasksDir, t.ID); err != nil {
+		return fmt.Errorf("failed to delete task file: %v", err)
+	}
+	delete(tm.tasks, t.ID)
+	tm.unrefBlobs(t)
 	return nil
 }
 
-func (tm *TaskManager) LeaseTask(taskID string, username string) (*leases.Lease, error) {
+// SearchArchive returns archived tasks matching the filter
+func (tm *TaskManager) SearchArchive(filter archive.Filter) ([]*task.Task, error) {
+	tasks, err := tm.archive.Search(filter)
+	if err != nil {
+		return nil, err
+	}
+	var visible []*task.Task
+	for _, t := range tasks {
+		if !tm.visible(t) {
+			continue
+		}
+		if err := t.Unpack(tm.keyring); err != nil {
+			return nil, err
+		}
+		visible = append(visible, t)
+	}
+	return visible, nil
+}
+
+func (tm *TaskManager) LeaseTask(taskID string, username string, duration time.Duration) (*leases.Lease, error) {
+	if tm.Draining() {
+		return nil, ErrDraining
+	}
+	if err := tm.checkTask(taskID); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{
+		Op:       OP_LEASE_TASK,
+		Time:     time.Now(),
+		TaskID:   taskID,
+		LeaseID:  uuid.New().String(),
+		Username: username,
+		LeaseDuration: tm.Settings().leaseDuration(duration),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Lease, nil
+}
+
+func (tm *TaskManager) applyLeaseTask(cmd *Command) (*leases.Lease, error) {
 	tm.taskLock.Lock()
 	defer tm.taskLock.Unlock()
 
 	// Check if the task exists
-	task, exists := tm.tasks[taskID]
+	task, exists := tm.tasks[cmd.TaskID]
 	if !exists {
 		return nil, fmt.Errorf("task not found")
 	}
+	if err := tm.leasable(task); err != nil {
+		return nil, err
+	}
 
 	// Create a new lease for the task
-	lease, err := tm.leaseManager.AcquireLease(task.ID, time.Minute*3, username)
-	if err = lease.Save(tm.leaseManager.leasesDir); err != nil {
+	// Commands replicated before lease durations were configurable carry none
+	duration := cmd.LeaseDuration
+	if duration <= 0 {
+		duration = DEFAULT_LEASE_DURATION
+	}
+	lease, err := tm.leaseManager.AcquireLease(cmd.LeaseID, task.ID, duration, cmd.Username, cmd.Time)
+	if err != nil {
+		return nil, err
+	}
+	lease.Term = cmd.Term
+	lease.FencingToken = cmd.Index
+	lease.Tenant = task.Tenant
+	if err := lease.Save(tm.leaseManager.leasesDir); err != nil {
 		return nil, fmt.Errorf("failed to save lease: %v", err)
 	}
+	metrics.TasksClaimed.WithLabelValues(task.GetQueue()).Inc()
 
 	return lease, nil
 }
 
-func (tm *TaskManager) GetUnLeasedTask() (*task.Task, error) {
+// GetUnLeasedTask returns a task that is ready to be leased from the given queue,
+// or from any queue if none is given. Paused tasks and paused queues are skipped.
+func (tm *TaskManager) GetUnLeasedTask(queue string) (*task.Task, error) {
+	if tm.Draining() {
+		return nil, ErrDraining
+	}
 	tm.taskLock.Lock()
 	defer tm.taskLock.Unlock()
 
+	if queue != "" {
+		queue = tm.QueueName(queue)
+	}
 	for _, task := range tm.tasks {
-		if task.State == CREATED {
-			return task, nil
+		if !tm.visible(task) {
+			continue
 		}
+		if queue != "" && queues.Normalize(task.Queue) != queue {
+			continue
+		}
+		if tm.leasable(task) != nil {
+			continue
+		}
+		if _, err := tm.leaseManager.GetActiveLeaseForTask(task.ID, time.Now()); err == nil {
+			continue
+		}
+		return task, nil
 	}
 
 	return nil, fmt.Errorf("no unleased tasks available")
-}
\ No newline at end of file
+}
+
+// leasable fails for tasks that cannot be leased: tasks that are not
+// waiting to run, tasks being cancelled and tasks in paused queues. The
+// caller must hold taskLock.
+func (tm *TaskManager) leasable(t *task.Task) error {
+	if t.State != CREATED && t.State != RESUMED {
+		return fmt.Errorf("task is %s", t.State)
+	}
+	if t.CancelRequested {
+		return fmt.Errorf("task is being cancelled")
+	}
+	if tm.queueManager.IsPaused(t.Queue) {
+		return fmt.Errorf("queue %s is paused", queues.Normalize(t.Queue))
+	}
+	return nil
+}
+
+// ReportProgress records the latest progress of a leased task and extends its lease
+func (tm *TaskManager) ReportProgress(leaseID string, username string, progress *task.Progress) (*task.Task, *leases.Lease, error) {
+	if progress == nil {
+		return nil, nil, fmt.Errorf("progress is required")
+	}
+	if progress.Percent < 0 || progress.Percent > 100 {
+		return nil, nil, fmt.Errorf("invalid progress percent: %d", progress.Percent)
+	}
+	if err := tm.checkLease(leaseID); err != nil {
+		return nil, nil, err
+	}
+
+	result, err := tm.propose(&Command{
+		Op:           OP_REPORT_PROGRESS,
+		Time:         time.Now(),
+		LeaseID:      leaseID,
+		FencingToken: tm.fencingToken,
+		Username:     username,
+		Progress:     progress,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Task, result.Lease, nil
+}
+
+func (tm *TaskManager) applyReportProgress(cmd *Command) (*task.Task, *leases.Lease, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	lease, err := tm.leaseManager.GetLease(cmd.LeaseID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check if the task exists
+	t, exists := tm.tasks[lease.TaskID]
+	if !exists {
+		return nil, nil, fmt.Errorf("task not found")
+	}
+	if _, err := tm.checkFence(t.ID, cmd.LeaseID, cmd.FencingToken, cmd.Time); err != nil {
+		return nil, nil, err
+	}
+
+	// The heartbeat keeps the lease alive for the duration it was granted
+	// for. Once the task is cancelled the lease is left to expire, so a
+	// worker that ignores the cancel cannot keep the task, and the cancel
+	// sweep aborts it.
+	if !t.CancelRequested {
+		duration := lease.Duration
+		if duration <= 0 {
+			duration = DEFAULT_LEASE_DURATION
+		}
+		if err := tm.leaseManager.ExtendLease(lease.ID, duration, cmd.Username, cmd.Time); err != nil {
+			return nil, nil, fmt.Errorf("failed to extend lease: %v", err)
+		}
+	}
+
+	now := cmd.Time.Format(time.RFC3339)
+	progress := *cmd.Progress
+	progress.UpdatedAt = now
+	t.Progress = &progress
+	t.LastHeartbeat = now
+	t.UpdatedAt = now
+
+	// Save the updated task to disk
+	if err := tm.saveTask(t); err != nil {
+		return nil, nil, fmt.Errorf("failed to save updated task: %v", err)
+	}
+
+	return t, lease, nil
+}
+
+// IsPauseRequested reports whether the worker holding a task should pause it,
+// either because the task itself or its queue is paused
+func (tm *TaskManager) IsPauseRequested(t *task.Task) bool {
+	return t.State == PAUSED || tm.queueManager.IsPaused(t.Queue)
+}
+
+
+// isTerminal reports whether a task state can no longer change
+func isTerminal(state string) bool {
+	return state == COMPLETED || state == FAILED || state == ABORTED
+}
+
+// PendingTasks returns the number of tasks visible to the view that have not
+// completed, failed or been aborted
+func (tm *TaskManager) PendingTasks() int {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	pending := 0
+	for _, t := range tm.tasks {
+		if tm.visible(t) && !isTerminal(t.State) {
+			pending++
+		}
+	}
+	return pending
+}
+
+// CancelTask requests cancellation of a task. A task without an active lease is
+// aborted immediately; otherwise the lease holder is signalled on its next
+// heartbeat or update and the task is aborted once it acknowledges or the lease expires.
+func (tm *TaskManager) CancelTask(taskID string) (*task.Task, error) {
+	if err := tm.checkTask(taskID); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_CANCEL_TASK, Time: time.Now(), TaskID: taskID})
+	if err != nil {
+		return nil, err
+	}
+	return result.Task, nil
+}
+
+func (tm *TaskManager) applyCancelTask(cmd *Command) (*task.Task, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	// Check if the task exists
+	t, exists := tm.tasks[cmd.TaskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found")
+	}
+	if isTerminal(t.State) {
+		return nil, fmt.Errorf("task is already %s", t.State)
+	}
+
+	if _, err := tm.leaseManager.GetActiveLeaseForTask(t.ID, cmd.Time); err != nil {
+		tm.setState(t, ABORTED, cmd.Time)
+		t.CancelRequested = false
+	} else {
+		t.CancelRequested = true
+	}
+	t.UpdatedAt = cmd.Time.Format(time.RFC3339)
+
+	// Save the updated task to disk
+	if err := tm.saveTask(t); err != nil {
+		return nil, fmt.Errorf("failed to save updated task: %v", err)
+	}
+
+	return t, nil
+}
+
+// AcknowledgeCancel is called by the lease holder once it has stopped working on
+// a cancelled task. The task is aborted and the lease released.
+func (tm *TaskManager) AcknowledgeCancel(leaseID string, username string) (*task.Task, error) {
+	if err := tm.checkLease(leaseID); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_ACKNOWLEDGE_CANCEL, Time: time.Now(), LeaseID: leaseID, Username: username})
+	if err != nil {
+		return nil, err
+	}
+	return result.Task, nil
+}
+
+func (tm *TaskManager) applyAcknowledgeCancel(cmd *Command) (*task.Task, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	lease, err := tm.leaseManager.GetLease(cmd.LeaseID)
+	if err != nil {
+		return nil, err
+	}
+	if lease.CreatedBy != cmd.Username {
+		return nil, fmt.Errorf("lease was created by another user")
+	}
+
+	// Check if the task exists
+	t, exists := tm.tasks[lease.TaskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found")
+	}
+	if !t.CancelRequested {
+		return nil, fmt.Errorf("task cancellation was not requested")
+	}
+
+	tm.setState(t, ABORTED, cmd.Time)
+	t.CancelRequested = false
+	t.UpdatedAt = cmd.Time.Format(time.RFC3339)
+
+	// Save the updated task to disk
+	if err := tm.saveTask(t); err != nil {
+		return nil, fmt.Errorf("failed to save updated task: %v", err)
+	}
+
+	if err := tm.leaseManager.ReleaseLease(lease.ID); err != nil {
+		return nil, fmt.Errorf("failed to release lease: %v", err)
+	}
+
+	return t, nil
+}
+
+// FinalizeCancelledTasks aborts cancelled tasks that no longer have an active lease
+func (tm *TaskManager) FinalizeCancelledTasks() error {
+	if !tm.isLeader() {
+		return nil
+	}
+
+	now := time.Now()
+	tm.taskLock.Lock()
+	var cancelled []string
+	for _, t := range tm.tasks {
+		if t.CancelRequested && !isTerminal(t.State) {
+			cancelled = append(cancelled, t.ID)
+		}
+	}
+	tm.taskLock.Unlock()
+
+	for _, taskID := range cancelled {
+		if _, err := tm.propose(&Command{Op: OP_FINALIZE_CANCEL, Time: now, TaskID: taskID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tm *TaskManager) applyFinalizeCancel(cmd *Command) (*task.Task, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	t, exists := tm.tasks[cmd.TaskID]
+	if !exists || !t.CancelRequested {
+		return nil, nil
+	}
+	// The worker finished the task before acknowledging the cancellation
+	if isTerminal(t.State) {
+		return t, nil
+	}
+	if _, err := tm.leaseManager.GetActiveLeaseForTask(t.ID, cmd.Time); err == nil {
+		return t, nil
+	}
+	tm.setState(t, ABORTED, cmd.Time)
+	t.CancelRequested = false
+	t.UpdatedAt = cmd.Time.Format(time.RFC3339)
+	if err := tm.saveTask(t); err != nil {
+		return nil, fmt.Errorf("failed to save updated task: %v", err)
+	}
+	return t, nil
+}
+
+// PauseTask stops a task from being handed out. If the task is leased the
+// lease holder is signalled on its next heartbeat.
+func (tm *TaskManager) PauseTask(taskID string) (*task.Task, error) {
+	if err := tm.checkTask(taskID); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_PAUSE_TASK, Time: time.Now(), TaskID: taskID})
+	if err != nil {
+		return nil, err
+	}
+	return result.Task, nil
+}
+
+func (tm *TaskManager) applyPauseTask(cmd *Command) (*task.Task, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	// Check if the task exists
+	t, exists := tm.tasks[cmd.TaskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found")
+	}
+	if isTerminal(t.State) {
+		return nil, fmt.Errorf("task is already %s", t.State)
+	}
+	if t.State == PAUSED {
+		return nil, fmt.Errorf("task is already paused")
+	}
+
+	tm.setState(t, PAUSED, cmd.Time)
+	t.UpdatedAt = cmd.Time.Format(time.RFC3339)
+
+	// Save the updated task to disk
+	if err := tm.saveTask(t); err != nil {
+		return nil, fmt.Errorf("failed to save updated task: %v", err)
+	}
+
+	return t, nil
+}
+
+// ResumeTask makes a paused task available again
+func (tm *TaskManager) ResumeTask(taskID string) (*task.Task, error) {
+	if err := tm.checkTask(taskID); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_RESUME_TASK, Time: time.Now(), TaskID: taskID})
+	if err != nil {
+		return nil, err
+	}
+	return result.Task, nil
+}
+
+func (tm *TaskManager) applyResumeTask(cmd *Command) (*task.Task, error) {
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+
+	// Check if the task exists
+	t, exists := tm.tasks[cmd.TaskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found")
+	}
+	if t.State != PAUSED {
+		return nil, fmt.Errorf("task is not paused")
+	}
+
+	tm.setState(t, RESUMED, cmd.Time)
+	t.UpdatedAt = cmd.Time.Format(time.RFC3339)
+
+	// Save the updated task to disk
+	if err := tm.saveTask(t); err != nil {
+		return nil, fmt.Errorf("failed to save updated task: %v", err)
+	}
+
+	return t, nil
+}
+
+// PauseQueue stops tasks in a queue from being handed out
+func (tm *TaskManager) PauseQueue(queue string) (*queues.Queue, error) {
+	queue = tm.tenantQueue(queue)
+	if err := tm.checkQueue(queue); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_PAUSE_QUEUE, Time: time.Now(), Queue: queue})
+	if err != nil {
+		return nil, err
+	}
+	return result.Queue, nil
+}
+
+// ResumeQueue lets tasks in a paused queue be handed out again
+func (tm *TaskManager) ResumeQueue(queue string) (*queues.Queue, error) {
+	queue = tm.tenantQueue(queue)
+	if err := tm.checkQueue(queue); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_RESUME_QUEUE, Time: time.Now(), Queue: queue})
+	if err != nil {
+		return nil, err
+	}
+	return result.Queue, nil
+}
+
+// SetRetentionPolicy overrides the retention of tasks in a state for a queue
+func (tm *TaskManager) SetRetentionPolicy(queue string, state string, policy *queues.RetentionPolicy) (*queues.Queue, error) {
+	queue = tm.tenantQueue(queue)
+	if err := tm.checkQueue(queue); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_SET_RETENTION_POLICY, Time: time.Now(), Queue: queue, State: state, Retention: policy})
+	if err != nil {
+		return nil, err
+	}
+	return result.Queue, nil
+}
+
+// SetCompression sets the codec task payloads in a queue are stored with.
+// Tasks are recompressed the next time they are written.
+func (tm *TaskManager) SetCompression(queue string, compression string) (*queues.Queue, error) {
+	queue = tm.tenantQueue(queue)
+	if err := tm.checkQueue(queue); err != nil {
+		return nil, err
+	}
+	result, err := tm.propose(&Command{Op: OP_SET_COMPRESSION, Time: time.Now(), Queue: queue, Compression: compression})
+	if err != nil {
+		return nil, err
+	}
+	return result.Queue, nil
+}
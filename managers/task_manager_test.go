@@ -0,0 +1,325 @@
+package managers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/archive"
+	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// newTestTaskManager returns a task manager storing its records in a
+// temporary directory
+func newTestTaskManager(t *testing.T) *TaskManager {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tasks"), 0755); err != nil {
+		t.Fatalf("failed to create tasks directory: %v", err)
+	}
+	leaseManager, err := NewLeaseManager(filepath.Join(dir, "leases"))
+	if err != nil {
+		t.Fatalf("failed to create lease manager: %v", err)
+	}
+	queueManager, err := NewQueueManager(filepath.Join(dir, "metadata", "queues"))
+	if err != nil {
+		t.Fatalf("failed to create queue manager: %v", err)
+	}
+	taskArchive, err := archive.NewArchive(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	shardManager := NewShardManager(filepath.Join(dir, "metadata"))
+	return NewTaskManager(filepath.Join(dir, "tasks"), leaseManager, queueManager, shardManager, taskArchive)
+}
+
+func TestFinalizeCancelKeepsFinishedTasks(t *testing.T) {
+	tests := []struct {
+		name   string
+		finish func(tm *TaskManager, taskID string) error
+		state  string
+	}{
+		{
+			name: "completed",
+			finish: func(tm *TaskManager, taskID string) error {
+				_, err := tm.CompleteTask(taskID, []byte("result"))
+				return err
+			},
+			state: COMPLETED,
+		},
+		{
+			name: "failed",
+			finish: func(tm *TaskManager, taskID string) error {
+				_, err := tm.FailTask(taskID, &task.TaskError{Code: "boom", Message: "worker failed"})
+				return err
+			},
+			state: FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := newTestTaskManager(t)
+			created, err := tm.CreateTask("task", "", "default", []byte("input"), nil)
+			if err != nil {
+				t.Fatalf("CreateTask: %v", err)
+			}
+			lease, err := tm.LeaseTask(created.ID, "worker", time.Minute)
+			if err != nil {
+				t.Fatalf("LeaseTask: %v", err)
+			}
+			cancelled, err := tm.CancelTask(created.ID)
+			if err != nil {
+				t.Fatalf("CancelTask: %v", err)
+			}
+			if !cancelled.CancelRequested {
+				t.Fatalf("cancel of a leased task was not deferred")
+			}
+			if err := tt.finish(tm.WithLease(lease.ID, lease.FencingToken), created.ID); err != nil {
+				t.Fatalf("finishing the task: %v", err)
+			}
+
+			// The sweep runs after the lease has expired
+			result := tm.Apply(&Command{Op: OP_FINALIZE_CANCEL, Time: time.Now().Add(time.Hour), TaskID: created.ID})
+			if err := result.Err(); err != nil {
+				t.Fatalf("finalize: %v", err)
+			}
+			got, err := tm.GetTask(created.ID)
+			if err != nil {
+				t.Fatalf("GetTask: %v", err)
+			}
+			if got.State != tt.state {
+				t.Errorf("state = %s, want %s", got.State, tt.state)
+			}
+			if got.CancelRequested {
+				t.Errorf("cancel is still requested")
+			}
+			if tt.state == COMPLETED && string(got.Result) != "result" {
+				t.Errorf("result = %q, want %q", got.Result, "result")
+			}
+		})
+	}
+}
+
+func TestFinishedTasksCannotBeFinishedAgain(t *testing.T) {
+	tm := newTestTaskManager(t)
+	created, err := tm.CreateTask("task", "", "default", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if _, err := tm.CompleteTask(created.ID, []byte("first")); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if _, err := tm.CompleteTask(created.ID, []byte("second")); err == nil {
+		t.Errorf("completing a completed task succeeded")
+	}
+	if _, err := tm.FailTask(created.ID, &task.TaskError{Code: "late"}); err == nil {
+		t.Errorf("failing a completed task succeeded")
+	}
+	got, err := tm.GetTask(created.ID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if got.State != COMPLETED || string(got.Result) != "first" {
+		t.Errorf("task = %s %q, want %s %q", got.State, got.Result, COMPLETED, "first")
+	}
+}
+
+func TestUpdateTaskReplacesInput(t *testing.T) {
+	tm := newTestTaskManager(t)
+	created, err := tm.CreateTask("task", "", "default", []byte("first"), nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	updated, err := tm.UpdateTask(created.ID, RUNNING, []byte("second"))
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if string(updated.Input) != "second" || updated.Data != nil || updated.State != RUNNING {
+		t.Errorf("updated task = %s %q, data %q, want %s %q and no data", updated.State, updated.Input, updated.Data, RUNNING, "second")
+	}
+	// A state change alone keeps the input
+	updated, err = tm.UpdateTask(created.ID, PAUSED, nil)
+	if err != nil {
+		t.Fatalf("UpdateTask without input: %v", err)
+	}
+	if string(updated.Input) != "second" {
+		t.Errorf("input = %q after a state change, want %q", updated.Input, "second")
+	}
+	// Commands logged by older versions carry the input in Data
+	result := tm.Apply(&Command{Op: OP_UPDATE_TASK, Time: time.Now(), TaskID: created.ID, State: RUNNING, Data: []byte("legacy")})
+	if err := result.Err(); err != nil || string(result.Task.Input) != "legacy" || result.Task.Data != nil {
+		t.Errorf("legacy update = %+v, %v, want input %q", result.Task, err, "legacy")
+	}
+}
+
+func TestHeartbeatDoesNotExtendCancelledLease(t *testing.T) {
+	tm := newTestTaskManager(t)
+	created, err := tm.CreateTask("task", "", "default", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	lease, err := tm.LeaseTask(created.ID, "worker", time.Minute)
+	if err != nil {
+		t.Fatalf("LeaseTask: %v", err)
+	}
+	expiresAt := lease.ExpiresAt
+
+	heartbeat := func(at time.Time) *leases.Lease {
+		t.Helper()
+		result := tm.Apply(&Command{Op: OP_REPORT_PROGRESS, Time: at, LeaseID: lease.ID, Username: "worker", Progress: &task.Progress{Percent: 10}})
+		if err := result.Err(); err != nil {
+			t.Fatalf("ReportProgress: %v", err)
+		}
+		return result.Lease
+	}
+	if extended := heartbeat(time.Now().Add(30 * time.Second)); !extended.ExpiresAt.After(expiresAt) {
+		t.Fatalf("heartbeat did not extend the lease")
+	}
+	expiresAt = heartbeat(time.Now().Add(30 * time.Second)).ExpiresAt
+
+	if _, err := tm.CancelTask(created.ID); err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+	if got := heartbeat(time.Now().Add(50 * time.Second)); !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("heartbeat after cancel moved the expiry from %v to %v", expiresAt, got.ExpiresAt)
+	}
+
+	// Once the lease expires, the cancel sweep aborts the task
+	result := tm.Apply(&Command{Op: OP_FINALIZE_CANCEL, Time: expiresAt.Add(time.Second), TaskID: created.ID})
+	if err := result.Err(); err != nil {
+		t.Fatalf("finalize: %v", err)
+	}
+	if got, _ := tm.GetTask(created.ID); got.State != ABORTED {
+		t.Errorf("state = %s, want %s", got.State, ABORTED)
+	}
+}
+
+func TestLeaseTaskRejectsTasksThatCannotRun(t *testing.T) {
+	tm := newTestTaskManager(t)
+	create := func(queue string) string {
+		t.Helper()
+		created, err := tm.CreateTask("task", "", queue, nil, nil)
+		if err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+		return created.ID
+	}
+
+	paused := create("default")
+	if _, err := tm.PauseTask(paused); err != nil {
+		t.Fatalf("PauseTask: %v", err)
+	}
+	completed := create("default")
+	if _, err := tm.CompleteTask(completed, nil); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	inPausedQueue := create("held")
+	if _, err := tm.PauseQueue("held"); err != nil {
+		t.Fatalf("PauseQueue: %v", err)
+	}
+	cancelled := create("default")
+	lease, err := tm.LeaseTask(cancelled, "worker", time.Minute)
+	if err != nil {
+		t.Fatalf("LeaseTask: %v", err)
+	}
+	if _, err := tm.CancelTask(cancelled); err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+
+	for name, taskID := range map[string]string{"paused": paused, "completed": completed, "paused queue": inPausedQueue} {
+		if _, err := tm.LeaseTask(taskID, "worker", time.Minute); err == nil {
+			t.Errorf("leasing a %s task succeeded", name)
+		}
+	}
+	// The lease of the cancelled task has expired, but the task is not
+	// handed out again before the cancel sweep aborts it
+	result := tm.Apply(&Command{Op: OP_LEASE_TASK, Time: lease.ExpiresAt.Add(time.Second), TaskID: cancelled, LeaseID: "l2", Username: "worker"})
+	if result.Err() == nil {
+		t.Errorf("leasing a cancelled task succeeded")
+	}
+
+	if _, err := tm.GetUnLeasedTask("held"); err == nil {
+		t.Errorf("GetUnLeasedTask returned a task from a paused queue")
+	}
+	if _, err := tm.ResumeQueue("held"); err != nil {
+		t.Fatalf("ResumeQueue: %v", err)
+	}
+	if got, err := tm.GetUnLeasedTask("held"); err != nil || got.ID != inPausedQueue {
+		t.Errorf("GetUnLeasedTask(\"held\") = %v, %v, want %s", got, err, inPausedQueue)
+	}
+}
+
+func TestGetTaskRejectsPathIDs(t *testing.T) {
+	tm := newTestTaskManager(t)
+	for _, taskID := range []string{"", "..", "../tasks", "a/b"} {
+		if _, err := tm.GetTask(taskID); err == nil || err.Error() != "task not found" {
+			t.Errorf("GetTask(%q) error = %v, want task not found", taskID, err)
+		}
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	tm := newTestTaskManager(t)
+	create := func(queue string) string {
+		t.Helper()
+		created, err := tm.CreateTask("task", "", queue, []byte("input"), nil)
+		if err != nil {
+			t.Fatalf("CreateTask: %v", err)
+		}
+		return created.ID
+	}
+	if _, err := tm.SetRetentionPolicy("kept", COMPLETED, &queues.RetentionPolicy{MaxAgeSeconds: 0}); err != nil {
+		t.Fatalf("SetRetentionPolicy: %v", err)
+	}
+	if _, err := tm.SetRetentionPolicy("archived", FAILED, &queues.RetentionPolicy{MaxAgeSeconds: 60, Archive: true}); err != nil {
+		t.Fatalf("SetRetentionPolicy: %v", err)
+	}
+
+	completed := create("default")
+	if _, err := tm.CompleteTask(completed, []byte("result")); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	forever := create("kept")
+	if _, err := tm.CompleteTask(forever, nil); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	failed := create("archived")
+	if _, err := tm.FailTask(failed, &task.TaskError{Code: "boom"}); err != nil {
+		t.Fatalf("FailTask: %v", err)
+	}
+	pending := create("default")
+
+	// Within the retention periods nothing is removed
+	if err := tm.ApplyRetention(time.Now().Add(30 * time.Second)); err != nil {
+		t.Fatalf("ApplyRetention: %v", err)
+	}
+	if _, err := tm.GetTask(failed); err != nil {
+		t.Errorf("failed task removed within its retention period: %v", err)
+	}
+
+	if err := tm.ApplyRetention(time.Now().Add(365 * 24 * time.Hour)); err != nil {
+		t.Fatalf("ApplyRetention: %v", err)
+	}
+	for name, taskID := range map[string]string{"completed": completed, "failed": failed} {
+		if _, err := tm.GetTask(taskID); err == nil {
+			t.Errorf("expired %s task was kept", name)
+		}
+	}
+	for name, taskID := range map[string]string{"pending": pending, "kept forever": forever} {
+		if _, err := tm.GetTask(taskID); err != nil {
+			t.Errorf("%s task was removed: %v", name, err)
+		}
+	}
+
+	// Only the queue asking for it archives its tasks
+	archived, err := tm.SearchArchive(archive.Filter{})
+	if err != nil {
+		t.Fatalf("SearchArchive: %v", err)
+	}
+	if len(archived) != 1 || archived[0].ID != failed || string(archived[0].Input) != "input" {
+		t.Errorf("archive = %v, want the failed task with its input", archived)
+	}
+}
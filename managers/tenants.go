@@ -0,0 +1,222 @@
+package managers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/task"
+	"github.com/indkumar8999/ps-tasks/tenants"
+)
+
+// Resources a tenant quota limits
+const (
+	QUOTA_PENDING_TASKS = "pending tasks"
+	QUOTA_PAYLOAD_BYTES = "payload bytes"
+	QUOTA_CREATE_RATE   = "create rate"
+)
+
+// QuotaError is returned when creating a task would exceed a tenant's quota
+type QuotaError struct {
+	Tenant   string
+	Resource string
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("tenant %s exceeded its %s quota", e.Tenant, e.Resource)
+}
+
+// ForTenant returns a view of the task manager that only sees and changes
+// the tasks, leases and queues of a tenant, and holds the tenant to its
+// quota. The view shares all state with tm. An empty tenant returns tm.
+func (tm *TaskManager) ForTenant(tenant string, quota tenants.Quota) *TaskManager {
+	if tenant == "" {
+		return tm
+	}
+	view := *tm
+	view.tenant = tenant
+	view.quota = quota
+	return &view
+}
+
+// visible reports whether the view can see a task
+func (tm *TaskManager) visible(t *task.Task) bool {
+	return tm.tenant == "" || t.Tenant == tm.tenant
+}
+
+// tenantQueue returns the queue a task goes to. A tenant's tasks without a
+// queue go to the queue named after the tenant, so tenants do not share the
+// default queue.
+func (tm *TaskManager) tenantQueue(queue string) string {
+	if queue == "" && tm.tenant != "" {
+		return tm.tenant
+	}
+	return queue
+}
+
+// QueueName returns the name of the queue a possibly empty queue name refers to
+func (tm *TaskManager) QueueName(queue string) string {
+	return queues.Normalize(tm.tenantQueue(queue))
+}
+
+// checkTask fails for tasks the view cannot see. They are reported as
+// missing so tenants cannot probe each other's task IDs.
+func (tm *TaskManager) checkTask(taskID string) error {
+	if tm.tenant == "" {
+		return nil
+	}
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+	if t, exists := tm.tasks[taskID]; !exists || !tm.visible(t) {
+		return fmt.Errorf("task not found")
+	}
+	return nil
+}
+
+// checkLease fails for leases on tasks the view cannot see
+func (tm *TaskManager) checkLease(leaseID string) error {
+	if tm.tenant == "" {
+		return nil
+	}
+	if lease, err := tm.leaseManager.GetLease(leaseID); err != nil || lease.Tenant != tm.tenant {
+		return fmt.Errorf("lease not found")
+	}
+	return nil
+}
+
+// checkQueue fails for queues the view's tenant does not own
+func (tm *TaskManager) checkQueue(queue string) error {
+	if tm.tenant == "" {
+		return nil
+	}
+	if tm.queueManager.GetQueue(queue).Tenant != tm.tenant {
+		return fmt.Errorf("queue %s belongs to another tenant", queue)
+	}
+	return nil
+}
+
+// checkQuota fails if the view's tenant cannot create a task with a payload
+// of the given size
+func (tm *TaskManager) checkQuota(payloadBytes int64, now time.Time) error {
+	if tm.tenant == "" {
+		return nil
+	}
+	if !tm.createLimits.Allow(tm.tenant, tm.quota.CreateRate, tm.quota.CreateBurst, now) {
+		return &QuotaError{Tenant: tm.tenant, Resource: QUOTA_CREATE_RATE}
+	}
+	if tm.quota.MaxPendingTasks <= 0 && tm.quota.MaxPayloadBytes <= 0 {
+		return nil
+	}
+
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+	pending := 0
+	for _, t := range tm.tasks {
+		if t.Tenant != tm.tenant {
+			continue
+		}
+		if !isTerminal(t.State) {
+			pending++
+		}
+		payloadBytes += payloadSize(t)
+	}
+	if tm.quota.MaxPendingTasks > 0 && pending >= tm.quota.MaxPendingTasks {
+		return &QuotaError{Tenant: tm.tenant, Resource: QUOTA_PENDING_TASKS}
+	}
+	if tm.quota.MaxPayloadBytes > 0 && payloadBytes > tm.quota.MaxPayloadBytes {
+		return &QuotaError{Tenant: tm.tenant, Resource: QUOTA_PAYLOAD_BYTES}
+	}
+	return nil
+}
+
+// payloadSize is the number of payload bytes a task stores
+func payloadSize(t *task.Task) int64 {
+	size := int64(len(t.Data) + len(t.Input) + len(t.Result))
+	for _, ref := range []*task.BlobRef{t.InputBlob, t.ResultBlob} {
+		if ref != nil {
+			size += ref.Size
+		}
+	}
+	return size
+}
+
+// blobUploads records the blobs each tenant uploaded, so tenants can only
+// reference payloads they sent or can already read
+type blobUploads struct {
+	// uploads maps tenants to the digests they uploaded and when
+	uploads     map[string]map[string]time.Time
+	uploadsLock *sync.Mutex
+}
+
+func newBlobUploads() *blobUploads {
+	return &blobUploads{uploads: make(map[string]map[string]time.Time), uploadsLock: &sync.Mutex{}}
+}
+
+func (b *blobUploads) add(tenant string, digest string, now time.Time) {
+	b.uploadsLock.Lock()
+	defer b.uploadsLock.Unlock()
+	if b.uploads[tenant] == nil {
+		b.uploads[tenant] = make(map[string]time.Time)
+	}
+	b.uploads[tenant][digest] = now
+}
+
+func (b *blobUploads) has(tenant string, digest string) bool {
+	b.uploadsLock.Lock()
+	defer b.uploadsLock.Unlock()
+	_, exists := b.uploads[tenant][digest]
+	return exists
+}
+
+// prune forgets uploads made before cutoff
+func (b *blobUploads) prune(cutoff time.Time) {
+	b.uploadsLock.Lock()
+	defer b.uploadsLock.Unlock()
+	for tenant, digests := range b.uploads {
+		for digest, uploaded := range digests {
+			if uploaded.Before(cutoff) {
+				delete(digests, digest)
+			}
+		}
+		if len(digests) == 0 {
+			delete(b.uploads, tenant)
+		}
+	}
+}
+
+// RecordUpload notes that the view's tenant uploaded a blob, so it may
+// reference it in its tasks
+func (tm *TaskManager) RecordUpload(digest string) {
+	if tm.tenant == "" {
+		return
+	}
+	tm.uploads.add(tm.tenant, digest, time.Now())
+}
+
+// canUseBlob reports whether the view may reference a blob in a task:
+// tenants can only use blobs they uploaded or can read
+func (tm *TaskManager) canUseBlob(digest string) bool {
+	return tm.tenant == "" || tm.uploads.has(tm.tenant, digest) || tm.CanReadBlob(digest)
+}
+
+// CanReadBlob reports whether the view can download a blob: tenants can
+// only read the payloads of their own tasks
+func (tm *TaskManager) CanReadBlob(digest string) bool {
+	if tm.tenant == "" {
+		return true
+	}
+	tm.taskLock.Lock()
+	defer tm.taskLock.Unlock()
+	for _, t := range tm.tasks {
+		if t.Tenant != tm.tenant {
+			continue
+		}
+		for _, ref := range []*task.BlobRef{t.InputBlob, t.ResultBlob} {
+			if ref != nil && ref.Digest == digest {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package managers
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/tenants"
+)
+
+func TestTenantBlobReferences(t *testing.T) {
+	tm := newTestTaskManager(t)
+	blobStore, err := blobs.NewStore(filepath.Join(t.TempDir(), "blobs"), nil)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	tm.SetBlobStore(blobStore, 1024)
+	alice := tm.ForTenant("alice", tenants.Quota{})
+	bob := tm.ForTenant("bob", tenants.Quota{})
+
+	meta, err := blobStore.Put(bytes.NewReader([]byte("alice's input")))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	alice.RecordUpload(meta.Digest)
+
+	if _, err := bob.CreateTaskWithInputBlob("", "task", "", "", meta.Digest, nil); err == nil {
+		t.Errorf("bob created a task with a blob alice uploaded")
+	}
+	created, err := alice.CreateTaskWithInputBlob("", "task", "", "", meta.Digest, nil)
+	if err != nil {
+		t.Fatalf("CreateTaskWithInputBlob: %v", err)
+	}
+	if _, err := bob.CreateTaskWithInputBlob("", "task", "", "", meta.Digest, nil); err == nil {
+		t.Errorf("bob created a task with the input of alice's task")
+	}
+	if _, err := bob.GetTask(created.ID); err == nil {
+		t.Errorf("bob can see alice's task")
+	}
+
+	// Uploads are forgotten once they could have been collected, but tasks
+	// keep their blobs readable
+	tm.uploads.prune(time.Now().Add(time.Second))
+	if _, err := alice.CreateTaskWithInputBlob("", "task", "", "", meta.Digest, nil); err != nil {
+		t.Errorf("CreateTaskWithInputBlob with the input of an own task: %v", err)
+	}
+}
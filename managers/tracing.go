@@ -0,0 +1,91 @@
+package managers
+
+import (
+	"context"
+
+	"github.com/indkumar8999/ps-tasks/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithContext returns a view of the task manager whose commands are traced
+// as part of ctx. The view shares all state with tm.
+func (tm *TaskManager) WithContext(ctx context.Context) *TaskManager {
+	view := *tm
+	view.ctx = ctx
+	return &view
+}
+
+func (tm *TaskManager) context() context.Context {
+	if tm.ctx == nil {
+		return context.Background()
+	}
+	return tm.ctx
+}
+
+// propose applies a command in a span. Created tasks keep the trace context
+// of the span, and commands on a task created in another trace link to it,
+// so a task's creation, lease and completion can be followed even when the
+// worker does not continue the producer's trace.
+func (tm *TaskManager) propose(cmd *Command) (*CommandResult, error) {
+	ctx, span := tracing.Tracer().Start(tm.context(), "TaskManager."+cmd.Op,
+		trace.WithAttributes(tm.spanAttributes(cmd)...),
+		trace.WithLinks(tm.spanLinks(cmd)...))
+	defer span.End()
+
+	if cmd.Op == OP_CREATE_TASK {
+		cmd.TraceContext = tracing.Inject(ctx)
+	}
+	result, err := tm.replicate(cmd)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
+
+func (tm *TaskManager) spanAttributes(cmd *Command) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{attribute.String("op", cmd.Op)}
+	if cmd.TaskID != "" {
+		attributes = append(attributes, attribute.String("task.id", cmd.TaskID))
+	}
+	if cmd.LeaseID != "" {
+		attributes = append(attributes, attribute.String("lease.id", cmd.LeaseID))
+	}
+	if cmd.Queue != "" {
+		attributes = append(attributes, attribute.String("queue", cmd.Queue))
+	}
+	if tm.tenant != "" {
+		attributes = append(attributes, attribute.String("tenant", tm.tenant))
+	}
+	return attributes
+}
+
+// spanLinks links to the trace the command's task was created in, unless
+// the command is already part of it
+func (tm *TaskManager) spanLinks(cmd *Command) []trace.Link {
+	taskID := cmd.TaskID
+	if taskID == "" && cmd.LeaseID != "" {
+		if lease, err := tm.leaseManager.GetLease(cmd.LeaseID); err == nil {
+			taskID = lease.TaskID
+		}
+	}
+	if taskID == "" {
+		return nil
+	}
+
+	tm.taskLock.Lock()
+	t, exists := tm.tasks[taskID]
+	var traceContext map[string]string
+	if exists {
+		traceContext = t.TraceContext
+	}
+	tm.taskLock.Unlock()
+
+	link, ok := tracing.Link(traceContext)
+	if !ok || link.SpanContext.TraceID() == trace.SpanContextFromContext(tm.context()).TraceID() {
+		return nil
+	}
+	return []trace.Link{link}
+}
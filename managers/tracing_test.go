@@ -0,0 +1,64 @@
+package managers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/tracing"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTaskLifecycleIsOneTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := tracing.Setup(tracing.Config{SpanExporter: exporter, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	t.Cleanup(func() { provider.Shutdown(time.Second) })
+	tm := newTestTaskManager(t)
+
+	// The producer enqueues in its own trace
+	ctx, span := tracing.Tracer().Start(context.Background(), "enqueue")
+	created, err := tm.WithContext(ctx).CreateTask("task", "", "default", []byte("input"), nil)
+	span.End()
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if len(created.TraceContext) == 0 {
+		t.Fatalf("created task has no trace context")
+	}
+
+	// The worker continues the trace stored on the task
+	ctx = tracing.Extract(context.Background(), created.TraceContext)
+	worker := tm.WithContext(ctx)
+	lease, err := worker.LeaseTask(created.ID, "worker", time.Minute)
+	if err != nil {
+		t.Fatalf("LeaseTask: %v", err)
+	}
+	if _, err := worker.WithLease(lease.ID, lease.FencingToken).CompleteTask(created.ID, []byte("result")); err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+	spans := map[string]tracetest.SpanStub{}
+	for _, stub := range exporter.GetSpans() {
+		spans[stub.Name] = stub
+	}
+	traceID := span.SpanContext().TraceID()
+	for _, name := range []string{"TaskManager." + OP_CREATE_TASK, "TaskManager." + OP_LEASE_TASK, "TaskManager." + OP_COMPLETE_TASK} {
+		stub, exists := spans[name]
+		if !exists {
+			t.Errorf("no %s span among %d spans", name, len(spans))
+			continue
+		}
+		if stub.SpanContext.TraceID() != traceID {
+			t.Errorf("%s span is in trace %s, want %s", name, stub.SpanContext.TraceID(), traceID)
+		}
+	}
+	if lease := spans["TaskManager."+OP_LEASE_TASK]; lease.Parent.SpanID() != spans["TaskManager."+OP_CREATE_TASK].SpanContext.SpanID() {
+		t.Errorf("lease span does not continue the span the task was created in")
+	}
+}
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// NAMESPACE prefixes every metric name
+const NAMESPACE = "pstasks"
+
+// DEFAULT_LEASE_SWEEP_INTERVAL is how often expired leases are counted
+const DEFAULT_LEASE_SWEEP_INTERVAL = 5 * time.Second
+
+// durationBuckets cover 100ms to about 4 hours, for task ages and the time
+// tasks spend in a state
+var durationBuckets = prometheus.ExponentialBuckets(0.1, 2, 18)
+
+// Registry holds every metric of the server
+var Registry = prometheus.NewRegistry()
+
+// Metrics recorded by the task and lease managers, the gRPC server and the
+// stores
+var (
+	TasksCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "tasks_created_total",
+		Help:      "Tasks created, by queue.",
+	}, []string{"queue"})
+	TasksClaimed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "tasks_claimed_total",
+		Help:      "Tasks leased by a worker, by queue.",
+	}, []string{"queue"})
+	TasksFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "tasks_finished_total",
+		Help:      "Tasks that completed, failed or were aborted, by queue and final state.",
+	}, []string{"queue", "state"})
+	TaskAge = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "task_age_seconds",
+		Help:      "Time from creating a task to it completing, failing or being aborted, by queue and final state.",
+		Buckets:   durationBuckets,
+	}, []string{"queue", "state"})
+	TaskStateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "task_state_duration_seconds",
+		Help:      "Time tasks spent in a state before leaving it, by queue and state.",
+		Buckets:   durationBuckets,
+	}, []string{"queue", "state"})
+
+	LeasesGranted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "leases_granted_total",
+		Help:      "Leases granted to workers.",
+	})
+	LeasesExtended = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "leases_extended_total",
+		Help:      "Leases extended by a heartbeat.",
+	})
+	LeasesExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "leases_expired_total",
+		Help:      "Leases that expired before their task finished.",
+	})
+
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of gRPC calls, by method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	PersistenceWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "persistence_write_duration_seconds",
+		Help:      "Latency of durable record writes including fsync, by record kind.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+	}, []string{"kind"})
+	PersistenceFsyncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "persistence_fsyncs_total",
+		Help:      "Files synced to disk, by kind.",
+	}, []string{"kind"})
+)
+
+func init() {
+	Registry.MustRegister(
+		TasksCreated, TasksClaimed, TasksFinished, TaskAge, TaskStateDuration,
+		LeasesGranted, LeasesExtended, LeasesExpired,
+		RPCDuration,
+		PersistenceWriteDuration, PersistenceFsyncs,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// Depth is a queue and task state that tasks are counted by
+type Depth struct {
+	Queue string
+	State string
+}
+
+// depthCollector reports the number of tasks per queue and state when
+// scraped, so the gauge never drifts from the task manager's state
+type depthCollector struct {
+	desc   *prometheus.Desc
+	depths func() map[Depth]int
+}
+
+// RegisterQueueDepth reports the task counts returned by depths as the
+// queue depth gauge
+func RegisterQueueDepth(depths func() map[Depth]int) {
+	Registry.MustRegister(&depthCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, "", "queue_depth"),
+			"Tasks currently stored, by queue and state.", []string{"queue", "state"}, nil),
+		depths: depths,
+	})
+}
+
+func (c *depthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *depthCollector) Collect(ch chan<- prometheus.Metric) {
+	for depth, count := range c.depths() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), depth.Queue, depth.State)
+	}
+}
+
+// ObserveWrite records the latency of a durable write started at start
+func ObserveWrite(kind string, start time.Time) {
+	PersistenceWriteDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Serve serves the metrics on addr under /metrics. It only returns on error.
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return http.ListenAndServe(addr, mux)
+}
+
+// UnaryServerInterceptor records the latency of every call
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		RPCDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records how long every stream stays open
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		RPCDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
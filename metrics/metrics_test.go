@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	const method = "/task.TaskService/MetricsTest"
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	if _, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "task not found")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("interceptor error = %v, want the handler's", err)
+	}
+
+	// One series per method and code
+	if got := testutil.CollectAndCount(RPCDuration); got != 2 {
+		t.Errorf("calls recorded in %d series, want 2", got)
+	}
+}
+
+func TestHandlerServesQueueDepth(t *testing.T) {
+	RegisterQueueDepth(func() map[Depth]int {
+		return map[Depth]int{{Queue: "emails", State: "created"}: 3}
+	})
+	TasksCreated.WithLabelValues("emails").Inc()
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Result().Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	for _, want := range []string{
+		`pstasks_queue_depth{queue="emails",state="created"} 3`,
+		`pstasks_tasks_created_total{queue="emails"}`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not include %s", want)
+		}
+	}
+}
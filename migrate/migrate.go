@@ -0,0 +1,272 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// FORMAT_VERSION is the version of the database layout
+const FORMAT_VERSION = 1
+
+// SCHEMA_FILE is the name of the schema file in the metadata directory
+const SCHEMA_FILE = "schema.json"
+
+// Kinds of records, named after their directories
+const (
+	KIND_TASKS  = "tasks"
+	KIND_LEASES = "leases"
+	KIND_QUEUES = "queues"
+)
+
+// kindDirs gives the directory of each kind of record, relative to the database
+var kindDirs = map[string]string{
+	KIND_TASKS:  "tasks",
+	KIND_LEASES: "leases",
+	KIND_QUEUES: filepath.Join("metadata", "queues"),
+}
+
+// CurrentVersions holds the record version this server writes for each kind
+var CurrentVersions = map[string]int{
+	KIND_TASKS:  task.SCHEMA_VERSION,
+	KIND_LEASES: leases.SCHEMA_VERSION,
+	KIND_QUEUES: queues.SCHEMA_VERSION,
+}
+
+// Record is a record decoded as generic JSON, so migrations can work on
+// fields the current structs no longer have
+type Record map[string]interface{}
+
+// Migration upgrades one kind of record from version From to From+1
+type Migration struct {
+	Kind        string
+	From        int
+	Description string
+	Apply       func(record Record) error
+}
+
+var registry = map[string]map[int]Migration{}
+
+// Register adds a migration to the registry. It panics if a migration for
+// the same kind and version is already registered.
+func Register(m Migration) {
+	if registry[m.Kind] == nil {
+		registry[m.Kind] = make(map[int]Migration)
+	}
+	if _, exists := registry[m.Kind][m.From]; exists {
+		panic(fmt.Sprintf("migration for %s version %d registered twice", m.Kind, m.From))
+	}
+	registry[m.Kind][m.From] = m
+}
+
+// Schema records the versions a database was last migrated to
+type Schema struct {
+	FormatVersion int            `json:"format_version"`
+	Records       map[string]int `json:"records"`
+	UpdatedAt     string         `json:"updated_at"`
+}
+
+// LoadSchema reads the schema file. A database without one is at version 0.
+func LoadSchema(dbPath string) (*Schema, error) {
+	data, err := os.ReadFile(filepath.Join(dbPath, "metadata", SCHEMA_FILE))
+	if os.IsNotExist(err) {
+		return &Schema{Records: map[string]int{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to read schema: %v", err)
+	}
+	return &schema, nil
+}
+
+// Options controls a migration run
+type Options struct {
+	// DryRun reports the changes without writing anything
+	DryRun bool
+	// Backup copies every record before it is rewritten
+	Backup bool
+}
+
+// Change is a record that needs migrating
+type Change struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// Report describes a migration run
+type Report struct {
+	DryRun    bool     `json:"dry_run"`
+	Changes   []Change `json:"changes"`
+	BackupDir string   `json:"backup_dir,omitempty"`
+}
+
+type pendingRecord struct {
+	change Change
+	dir    string
+	record Record
+}
+
+// Run migrates every record in the database to the current versions and
+// updates the schema file. Records that cannot be read are left alone; the
+// startup loader quarantines them.
+func Run(dbPath string, opts Options) (*Report, error) {
+	schema, err := LoadSchema(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if schema.FormatVersion > FORMAT_VERSION {
+		return nil, fmt.Errorf("database format %d is newer than this server supports (%d)", schema.FormatVersion, FORMAT_VERSION)
+	}
+
+	report := &Report{DryRun: opts.DryRun}
+	var pending []pendingRecord
+	kinds := make([]string, 0, len(kindDirs))
+	for kind := range kindDirs {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		dir := filepath.Join(dbPath, kindDirs[kind])
+		ids, err := store.List(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			record, version, err := loadRecord(dir, id)
+			if err != nil {
+				continue
+			}
+			current := CurrentVersions[kind]
+			if version > current {
+				return nil, fmt.Errorf("%s %s has version %d, newer than this server supports (%d)", kind, id, version, current)
+			}
+			if version == current {
+				continue
+			}
+			if err := upgrade(kind, record, version, current); err != nil {
+				return nil, fmt.Errorf("failed to migrate %s %s: %v", kind, id, err)
+			}
+			change := Change{Kind: kind, ID: id, From: version, To: current}
+			report.Changes = append(report.Changes, change)
+			pending = append(pending, pendingRecord{change: change, dir: dir, record: record})
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	if opts.Backup && len(pending) > 0 {
+		report.BackupDir = filepath.Join(dbPath, "backups", "migrate-"+time.Now().UTC().Format("20060102T150405Z"))
+		for _, p := range pending {
+			if err := copyFile(store.Path(p.dir, p.change.ID), filepath.Join(report.BackupDir, kindDirs[p.change.Kind], store.FileName(p.change.ID))); err != nil {
+				return nil, fmt.Errorf("failed to back up %s %s: %v", p.change.Kind, p.change.ID, err)
+			}
+		}
+		schemaFile := filepath.Join(dbPath, "metadata", SCHEMA_FILE)
+		if _, err := os.Stat(schemaFile); err == nil {
+			if err := copyFile(schemaFile, filepath.Join(report.BackupDir, "metadata", SCHEMA_FILE)); err != nil {
+				return nil, fmt.Errorf("failed to back up schema: %v", err)
+			}
+		}
+	}
+
+	for _, p := range pending {
+		if err := store.Save(p.dir, p.change.ID, p.record); err != nil {
+			return nil, fmt.Errorf("failed to save %s %s: %v", p.change.Kind, p.change.ID, err)
+		}
+	}
+
+	schema.FormatVersion = FORMAT_VERSION
+	schema.Records = CurrentVersions
+	schema.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := os.MkdirAll(filepath.Join(dbPath, "metadata"), 0755); err != nil {
+		return nil, err
+	}
+	if err := store.Save(filepath.Join(dbPath, "metadata"), SCHEMA_FILE, schema); err != nil {
+		return nil, fmt.Errorf("failed to save schema: %v", err)
+	}
+	return report, nil
+}
+
+// loadRecord reads a record as generic JSON, keeping numbers exact
+func loadRecord(dir string, id string) (Record, int, error) {
+	data, err := os.ReadFile(store.Path(dir, id))
+	if err != nil {
+		return nil, 0, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var record Record
+	if err := decoder.Decode(&record); err != nil {
+		return nil, 0, err
+	}
+
+	version := 0
+	if raw, ok := record["schema_version"].(json.Number); ok {
+		v, err := raw.Int64()
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid schema_version: %v", err)
+		}
+		version = int(v)
+	}
+	return record, version, nil
+}
+
+// upgrade applies the registered migrations from version to current
+func upgrade(kind string, record Record, version int, current int) error {
+	for v := version; v < current; v++ {
+		m, ok := registry[kind][v]
+		if !ok {
+			return fmt.Errorf("no migration from version %d", v)
+		}
+		if err := m.Apply(record); err != nil {
+			return fmt.Errorf("migration from version %d: %v", v, err)
+		}
+	}
+	record["schema_version"] = current
+	return nil
+}
+
+func copyFile(from string, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
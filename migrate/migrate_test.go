@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// V0_TASK is a task written before input and result were split and before
+// tasks had a queue
+const V0_TASK = `{"id":"old","name":"old","state":"created","data":"aW5wdXQ="}`
+
+// newV0Database returns a database holding one version 0 task and one
+// version 0 lease, without a schema file
+func newV0Database(t *testing.T) string {
+	t.Helper()
+	dbPath := t.TempDir()
+	files := map[string]string{
+		"tasks/old.json":  V0_TASK,
+		"tasks/junk.json": `{"id":`,
+		"leases/l1.json":  `{"id":"l1","task_id":"old"}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dbPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	return dbPath
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	return string(data)
+}
+
+func TestRunMigratesV0Tasks(t *testing.T) {
+	dbPath := newV0Database(t)
+	report, err := Run(dbPath, Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// The unreadable task is left for the startup loader
+	if len(report.Changes) != 2 {
+		t.Errorf("changes = %+v, want the task and the lease", report.Changes)
+	}
+
+	var migrated task.Task
+	if err := store.Load(filepath.Join(dbPath, "tasks"), "old", &migrated); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if string(migrated.Input) != "input" {
+		t.Errorf("input = %q, want the old data %q", migrated.Input, "input")
+	}
+	if migrated.Queue != queues.DEFAULT_QUEUE {
+		t.Errorf("queue = %q, want %q", migrated.Queue, queues.DEFAULT_QUEUE)
+	}
+	if migrated.SchemaVersion != task.SCHEMA_VERSION {
+		t.Errorf("schema version = %d, want %d", migrated.SchemaVersion, task.SCHEMA_VERSION)
+	}
+	if got := readFile(t, filepath.Join(dbPath, "tasks", "junk.json")); got != `{"id":` {
+		t.Errorf("unreadable task was rewritten to %s", got)
+	}
+
+	schema, err := LoadSchema(dbPath)
+	if err != nil {
+		t.Fatalf("LoadSchema: %v", err)
+	}
+	if schema.FormatVersion != FORMAT_VERSION || schema.Records[KIND_TASKS] != task.SCHEMA_VERSION {
+		t.Errorf("schema = %+v, want format %d and tasks at %d", schema, FORMAT_VERSION, task.SCHEMA_VERSION)
+	}
+}
+
+func TestDryRunLeavesFilesUnchanged(t *testing.T) {
+	dbPath := newV0Database(t)
+	report, err := Run(dbPath, Options{DryRun: true, Backup: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !report.DryRun || len(report.Changes) != 2 || report.BackupDir != "" {
+		t.Errorf("report = %+v, want 2 changes and no backup", report)
+	}
+	if got := readFile(t, filepath.Join(dbPath, "tasks", "old.json")); got != V0_TASK {
+		t.Errorf("dry run rewrote the task to %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(dbPath, "metadata", SCHEMA_FILE)); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote the schema file")
+	}
+	if _, err := os.Stat(filepath.Join(dbPath, "backups")); !os.IsNotExist(err) {
+		t.Errorf("dry run made a backup")
+	}
+}
+
+func TestBackup(t *testing.T) {
+	dbPath := newV0Database(t)
+	report, err := Run(dbPath, Options{Backup: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.BackupDir == "" {
+		t.Fatalf("no backup was made")
+	}
+	if got := readFile(t, filepath.Join(report.BackupDir, "tasks", "old.json")); got != V0_TASK {
+		t.Errorf("backed up task = %s, want the original", got)
+	}
+	if _, err := os.Stat(filepath.Join(report.BackupDir, "leases", "l1.json")); err != nil {
+		t.Errorf("lease was not backed up: %v", err)
+	}
+}
+
+func TestRunAtCurrentVersion(t *testing.T) {
+	dbPath := newV0Database(t)
+	if _, err := Run(dbPath, Options{}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	migrated := readFile(t, filepath.Join(dbPath, "tasks", "old.json"))
+
+	report, err := Run(dbPath, Options{Backup: true})
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if len(report.Changes) != 0 || report.BackupDir != "" {
+		t.Errorf("second run = %+v, want no changes and no backup", report)
+	}
+	if got := readFile(t, filepath.Join(dbPath, "tasks", "old.json")); got != migrated {
+		t.Errorf("second run rewrote the task")
+	}
+
+	// A database written by a newer server is refused
+	if err := os.WriteFile(filepath.Join(dbPath, "metadata", SCHEMA_FILE), []byte(`{"format_version":99}`), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := Run(dbPath, Options{}); err == nil {
+		t.Errorf("migrating a newer database succeeded")
+	}
+}
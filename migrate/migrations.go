@@ -0,0 +1,36 @@
+package migrate
+
+import "github.com/indkumar8999/ps-tasks/queues"
+
+// The built in migrations. New record versions add their migration here and
+// bump SCHEMA_VERSION in the record's package.
+func init() {
+	Register(Migration{
+		Kind:        KIND_TASKS,
+		From:        0,
+		Description: "copy data into input for tasks written before input and result were split, and name their queue",
+		Apply: func(record Record) error {
+			if input, ok := record["input"]; !ok || input == nil {
+				if data, ok := record["data"]; ok && data != nil {
+					record["input"] = data
+				}
+			}
+			if queue, ok := record["queue"].(string); !ok || queue == "" {
+				record["queue"] = queues.DEFAULT_QUEUE
+			}
+			return nil
+		},
+	})
+	Register(Migration{
+		Kind:        KIND_LEASES,
+		From:        0,
+		Description: "add the schema version",
+		Apply:       func(record Record) error { return nil },
+	})
+	Register(Migration{
+		Kind:        KIND_QUEUES,
+		From:        0,
+		Description: "add the schema version",
+		Apply:       func(record Record) error { return nil },
+	})
+}
@@ -0,0 +1,83 @@
+package queues
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/store"
+)
+
+// DEFAULT_QUEUE is the queue used for tasks created without one
+const DEFAULT_QUEUE = "default"
+
+// RetentionPolicy decides how long tasks in a given state are kept.
+// A MaxAgeSeconds of zero keeps the tasks forever.
+type RetentionPolicy struct {
+	MaxAgeSeconds int64 `json:"max_age_seconds"`
+	Archive       bool  `json:"archive"`
+}
+
+// MaxAge returns the retention period as a duration
+func (p *RetentionPolicy) MaxAge() time.Duration {
+	return time.Duration(p.MaxAgeSeconds) * time.Second
+}
+
+// SCHEMA_VERSION is the version of the queue record written by Save
+const SCHEMA_VERSION = 1
+
+// Queue holds the settings of a named task queue
+type Queue struct {
+	SchemaVersion int                         `json:"schema_version"`
+	Name          string                      `json:"name"`
+	Paused        bool                        `json:"paused"`
+	Retention     map[string]*RetentionPolicy `json:"retention,omitempty"`
+	// Compression is the codec task payloads in the queue are stored with
+	Compression string `json:"compression,omitempty"`
+	// Tenant owns the queue once one of its members creates a task in it.
+	// Other tenants cannot use the queue.
+	Tenant    string    `json:"tenant,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewQueue creates a new queue with default settings
+func NewQueue(name string) *Queue {
+	return &Queue{
+		Name:      name,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Normalize returns the queue name to use for a possibly empty name
+func Normalize(name string) string {
+	if name == "" {
+		return DEFAULT_QUEUE
+	}
+	return name
+}
+
+// ValidateName checks that a queue name can be used as a file name
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("invalid queue name")
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid queue name: %q", name)
+	}
+	return nil
+}
+
+// Save saves the queue to <queuesDir>/<name>.json
+func (q *Queue) Save(queuesDir string) error {
+	q.SchemaVersion = SCHEMA_VERSION
+	return store.Save(queuesDir, q.Name, q)
+}
+
+// LoadQueue loads a queue from the queues directory
+func LoadQueue(queuesDir, name string) (*Queue, error) {
+	var queue Queue
+	if err := store.Load(queuesDir, name, &queue); err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
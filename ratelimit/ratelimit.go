@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket. It starts full, holds at most burst tokens and
+// refills at rate tokens per second.
+type Bucket struct {
+	rate       float64
+	burst      float64
+	tokens     float64
+	last       time.Time
+	bucketLock *sync.Mutex
+}
+
+// NewBucket creates a full bucket
+func NewBucket(rate float64, burst int, now time.Time) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{
+		rate:       rate,
+		burst:      float64(burst),
+		tokens:     float64(burst),
+		last:       now,
+		bucketLock: &sync.Mutex{},
+	}
+}
+
+// Allow takes a token if one is available
+func (b *Bucket) Allow(now time.Time) bool {
+	b.bucketLock.Lock()
+	defer b.bucketLock.Unlock()
+
+	if b.available(now) < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// available refills the bucket up to now and returns its tokens.
+// The caller must hold bucketLock.
+func (b *Bucket) available(now time.Time) float64 {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	return b.tokens
+}
+
+// full reports whether the bucket has refilled completely, so it behaves
+// like a new one
+func (b *Bucket) full(now time.Time) bool {
+	b.bucketLock.Lock()
+	defer b.bucketLock.Unlock()
+	return b.available(now) >= b.burst
+}
+
+// PRUNE_INTERVAL is how often Limiters drops the buckets that are full
+const PRUNE_INTERVAL = 1 * time.Minute
+
+// Limit is the rate and burst of the bucket of a key. A rate of zero or
+// less allows everything.
+type Limit struct {
+	Key   string
+	Rate  float64
+	Burst int
+}
+
+// Limiters keeps a bucket per key, such as a tenant or a caller. Buckets
+// that have refilled completely are dropped every PRUNE_INTERVAL, so keys
+// that stop calling do not use memory.
+type Limiters struct {
+	buckets      map[string]*Bucket
+	pruned       time.Time
+	limitersLock *sync.Mutex
+}
+
+// NewLimiters creates an empty set of buckets
+func NewLimiters() *Limiters {
+	return &Limiters{
+		buckets:      make(map[string]*Bucket),
+		limitersLock: &sync.Mutex{},
+	}
+}
+
+// Allow takes a token from the bucket of a key. The bucket is created with
+// rate and burst the first time the key is seen, and replaced when they
+// change. A rate of zero or less allows everything.
+func (l *Limiters) Allow(key string, rate float64, burst int, now time.Time) bool {
+	return l.AllowAll([]Limit{{Key: key, Rate: rate, Burst: burst}}, now) < 0
+}
+
+// AllowAll takes a token from the bucket of every limit, or from none of
+// them if any bucket is empty, so a rejected call uses up no quota. It
+// returns the index of the first limit whose bucket is empty, or -1.
+func (l *Limiters) AllowAll(limits []Limit, now time.Time) int {
+	l.limitersLock.Lock()
+	defer l.limitersLock.Unlock()
+
+	if now.Sub(l.pruned) >= PRUNE_INTERVAL {
+		l.prune(now)
+	}
+	buckets := make([]*Bucket, len(limits))
+	for i, limit := range limits {
+		if limit.Rate <= 0 {
+			continue
+		}
+		bucket, exists := l.buckets[limit.Key]
+		if !exists || bucket.rate != limit.Rate || bucket.burst != float64(max(limit.Burst, 1)) {
+			bucket = NewBucket(limit.Rate, limit.Burst, now)
+			l.buckets[limit.Key] = bucket
+		}
+		bucket.bucketLock.Lock()
+		available := bucket.available(now)
+		bucket.bucketLock.Unlock()
+		if available < 1 {
+			return i
+		}
+		buckets[i] = bucket
+	}
+	// Every bucket has a token, and they cannot be taken in the meantime
+	// because buckets are only used with limitersLock held
+	for _, bucket := range buckets {
+		if bucket != nil {
+			bucket.Allow(now)
+		}
+	}
+	return -1
+}
+
+// prune drops the buckets that are full. The caller must hold limitersLock.
+func (l *Limiters) prune(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+	l.pruned = now
+}
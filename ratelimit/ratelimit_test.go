@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowAllTakesNoTokenWhenRejected(t *testing.T) {
+	l := NewLimiters()
+	now := time.Now()
+	method := Limit{Key: "method", Rate: 1, Burst: 5}
+	caller := Limit{Key: "caller", Rate: 1, Burst: 1}
+
+	if rejected := l.AllowAll([]Limit{method, caller}, now); rejected != -1 {
+		t.Fatalf("first call rejected by limit %d", rejected)
+	}
+	// The caller's bucket is empty, so the method's keeps its tokens
+	for i := 0; i < 10; i++ {
+		if rejected := l.AllowAll([]Limit{method, caller}, now); rejected != 1 {
+			t.Fatalf("call %d rejected by limit %d, want 1", i, rejected)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if !l.Allow(method.Key, method.Rate, method.Burst, now) {
+			t.Fatalf("method bucket ran out after %d calls, want 4 tokens left", i)
+		}
+	}
+	if l.Allow(method.Key, method.Rate, method.Burst, now) {
+		t.Errorf("method bucket allowed more than its burst")
+	}
+	if !l.Allow("unlimited", 0, 0, now) {
+		t.Errorf("a rate of zero was limited")
+	}
+}
+
+func TestLimitersDropFullBuckets(t *testing.T) {
+	l := NewLimiters()
+	now := time.Now()
+	l.Allow("idle", 1, 2, now)
+	l.Allow("busy", 0.001, 2, now)
+
+	// After PRUNE_INTERVAL the idle bucket has refilled and is dropped,
+	// while the slow one still holds its state
+	later := now.Add(PRUNE_INTERVAL)
+	l.Allow("other", 1, 1, later)
+	if _, exists := l.buckets["idle"]; exists {
+		t.Errorf("full bucket was kept")
+	}
+	if _, exists := l.buckets["busy"]; !exists {
+		t.Errorf("bucket that has not refilled was dropped")
+	}
+}
@@ -17,7 +17,7 @@ func main() {
 	defer c.Close()
 
 	// Get unleased task
-	taskresp, err := c.GetUnLeasdTask()
+	taskresp, err := c.GetUnLeasdTask("")
 	if err != nil {
 		fmt.Printf("Error getting unleased task: %v\n", err)
 		return
@@ -33,8 +33,8 @@ func main() {
 	}
 	fmt.Printf("Leased task: %v\n", lease)
 
-	// Complete the task
-	completedTask, err := c.CompleteTask(task.Id)
+	// Complete the task through its lease, so it fails if the lease was lost
+	completedTask, err := c.CompleteLeasedTask(lease, []byte("task result"))
 	if err != nil {
 		fmt.Printf("Error completing task: %v\n", err)
 		return
@@ -43,7 +43,7 @@ func main() {
 	
 
 	// Create a new task
-	// task, err := c.CreateTask("name of task")
+	// task, err := c.CreateTask("name of task", "default", []byte("task data"))
 	// if err != nil {
 	// 	fmt.Printf("Error creating task: %v\n", err)
 	// 	return
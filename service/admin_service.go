@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/indkumar8999/ps-tasks/auth"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AdminService drains a node before it is taken out of rotation
+type AdminService struct {
+	taskpb.UnimplementedAdminServiceServer
+	taskManager   *managers.TaskManager
+	authenticator *auth.Authenticator
+}
+
+// NewAdminService creates a new AdminService
+func NewAdminService(taskManager *managers.TaskManager) *AdminService {
+	return &AdminService{taskManager: taskManager}
+}
+
+// SetAuthenticator limits draining to operators. The service does not rely
+// on the interceptors being configured to allow only operators.
+func (s *AdminService) SetAuthenticator(authenticator *auth.Authenticator) {
+	s.authenticator = authenticator
+}
+
+func (s *AdminService) Drain(ctx context.Context, req *taskpb.DrainRequest) (*taskpb.DrainStatus, error) {
+	if err := s.authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+	s.taskManager.WithContext(ctx).SetDraining(!req.Resume)
+	return s.status(), nil
+}
+
+func (s *AdminService) GetDrainStatus(ctx context.Context, req *taskpb.GetDrainStatusRequest) (*taskpb.DrainStatus, error) {
+	if err := s.authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+	return s.status(), nil
+}
+
+// authorizeOperator checks that the caller may drain the node
+func (s *AdminService) authorizeOperator(ctx context.Context) error {
+	if s.authenticator == nil {
+		return nil
+	}
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return status.Error(codes.Unauthenticated, "no credentials")
+	}
+	return s.authenticator.AuthorizeOperator(principal)
+}
+
+func (s *AdminService) status() *taskpb.DrainStatus {
+	return &taskpb.DrainStatus{
+		Draining:     s.taskManager.Draining(),
+		ActiveLeases: int32(s.taskManager.ActiveLeases()),
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/auth"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/tenants"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SetAuthenticator makes every call check the caller's role on the queue it
+// touches. The gRPC server must also run the authenticator's interceptors,
+// which identify the caller.
+func (s *TaskService) SetAuthenticator(authenticator *auth.Authenticator) {
+	s.authenticator = authenticator
+}
+
+// tasks returns the task manager as seen by the caller, tracing its
+// commands as part of the call. Members of a tenant only see the tenant's
+// tasks, leases and queues, within its quota.
+func (s *TaskService) tasks(ctx context.Context) *managers.TaskManager {
+	principal := auth.FromContext(ctx)
+	if s.authenticator == nil || principal == nil || principal.Tenant == "" {
+		return s.taskManager.WithContext(ctx)
+	}
+	var quota tenants.Quota
+	if tenant := s.authenticator.Tenant(principal.Name); tenant != nil {
+		quota = tenant.Quota
+	}
+	return s.taskManager.ForTenant(principal.Tenant, quota).WithContext(ctx)
+}
+
+// principal returns the caller, or nil when authentication is disabled
+func (s *TaskService) principal(ctx context.Context) (*auth.Principal, error) {
+	if s.authenticator == nil {
+		return nil, nil
+	}
+	principal := auth.FromContext(ctx)
+	if principal == nil {
+		return nil, status.Error(codes.Unauthenticated, "no credentials")
+	}
+	return principal, nil
+}
+
+// authorize checks that the caller holds a role on a queue
+func (s *TaskService) authorize(ctx context.Context, queue string, role string) error {
+	principal, err := s.principal(ctx)
+	if err != nil || principal == nil {
+		return err
+	}
+	return s.authenticator.Authorize(principal, queue, role)
+}
+
+// authorizeOperator checks that the caller may run operations that span
+// tenants
+func (s *TaskService) authorizeOperator(ctx context.Context) error {
+	principal, err := s.principal(ctx)
+	if err != nil || principal == nil {
+		return err
+	}
+	return s.authenticator.AuthorizeOperator(principal)
+}
+
+// authorizeRead checks that the caller holds any role on a queue
+func (s *TaskService) authorizeRead(ctx context.Context, queue string) error {
+	principal, err := s.principal(ctx)
+	if err != nil || principal == nil {
+		return err
+	}
+	return s.authenticator.AuthorizeRead(principal, queue)
+}
+
+// authorizeAny checks that the caller holds some role on some queue, for
+// calls that are not tied to a queue
+func (s *TaskService) authorizeAny(ctx context.Context) error {
+	principal, err := s.principal(ctx)
+	if err != nil || principal == nil {
+		return err
+	}
+	return s.authenticator.AuthorizeAny(principal)
+}
+
+// authorizeTask checks that the caller holds a role on the queue of a task.
+// Unknown tasks, including those of other tenants, are left to the call to
+// report.
+func (s *TaskService) authorizeTask(ctx context.Context, taskID string, role string) error {
+	if s.authenticator == nil {
+		return nil
+	}
+	t, err := s.tasks(ctx).GetTask(taskID)
+	if err != nil {
+		return s.authorizeAny(ctx)
+	}
+	if role == "" {
+		return s.authorizeRead(ctx, t.Queue)
+	}
+	return s.authorize(ctx, t.Queue, role)
+}
+
+// authorizeLease checks that the caller holds the worker role on the queue
+// of a leased task
+func (s *TaskService) authorizeLease(ctx context.Context, leaseID string) error {
+	if s.authenticator == nil {
+		return nil
+	}
+	lease, err := s.leaseManager.GetLease(leaseID)
+	if err != nil {
+		return s.authorizeAny(ctx)
+	}
+	return s.authorizeTask(ctx, lease.TaskID, auth.ROLE_WORKER)
+}
+
+// owner returns the lease owner to act as. With authentication it is the
+// caller, whatever owner the request names.
+func (s *TaskService) owner(ctx context.Context, requested string) (string, error) {
+	principal, err := s.principal(ctx)
+	if err != nil || principal == nil {
+		return requested, err
+	}
+	return principal.Name, nil
+}
+
+// canRead reports whether the caller may see tasks of a queue, for filtering
+// listings
+func (s *TaskService) canRead(ctx context.Context, queue string) bool {
+	return s.authorizeRead(ctx, queue) == nil
+}
+
+// authorizeWorker checks that the caller may finish a task: it needs the
+// worker role on the task's queue, and only admins may finish a task leased
+// by someone else
+func (s *TaskService) authorizeWorker(ctx context.Context, taskID string) error {
+	if err := s.authorizeTask(ctx, taskID, auth.ROLE_WORKER); err != nil {
+		return err
+	}
+	principal, err := s.principal(ctx)
+	if err != nil || principal == nil {
+		return err
+	}
+	lease, err := s.leaseManager.GetActiveLeaseForTask(taskID, time.Now())
+	if err != nil || lease.CreatedBy == principal.Name {
+		return nil
+	}
+	if s.authorizeTask(ctx, taskID, auth.ROLE_ADMIN) == nil {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "task %s is leased by %s", taskID, lease.CreatedBy)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/indkumar8999/ps-tasks/cluster"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+)
+
+// ClusterService accepts commands forwarded by followers and serves the
+// blobs stored on this node to the other nodes
+type ClusterService struct {
+	taskpb.UnimplementedClusterServiceServer
+	node        *cluster.Node
+	taskManager *managers.TaskManager
+}
+
+// NewClusterService creates a new ClusterService
+func NewClusterService(node *cluster.Node, taskManager *managers.TaskManager) *ClusterService {
+	return &ClusterService{node: node, taskManager: taskManager}
+}
+
+func (s *ClusterService) Propose(ctx context.Context, req *taskpb.ProposeRequest) (*taskpb.ProposeResponse, error) {
+	var cmd managers.Command
+	if err := json.Unmarshal(req.Command, &cmd); err != nil {
+		return nil, fmt.Errorf("failed to decode command: %v", err)
+	}
+	result, err := s.node.ProposeAsLeader(&cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to propose command: %v", err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %v", err)
+	}
+
+	return &taskpb.ProposeResponse{Result: data}, nil
+}
+
+func (s *ClusterService) FetchBlob(req *taskpb.FetchBlobRequest, stream taskpb.ClusterService_FetchBlobServer) error {
+	return fetchBlob(s.taskManager.Blobs(), req.Digest, stream.Send)
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/indkumar8999/ps-tasks/auth"
+	"github.com/indkumar8999/ps-tasks/logging"
+)
+
+// SetLogger makes the service log to logger instead of the default logger
+func (s *TaskService) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// log returns the logger annotated with the call's request and trace IDs
+// and caller
+func (s *TaskService) log(ctx context.Context) *slog.Logger {
+	logger := logging.With(ctx, s.logger)
+	if principal := auth.FromContext(ctx); principal != nil {
+		logger = logger.With("principal", principal.Name)
+	}
+	return logger
+}
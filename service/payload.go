@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/indkumar8999/ps-tasks/blobs"
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"github.com/indkumar8999/ps-tasks/task"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toPayloadRefProto converts a blob reference into its protobuf representation
+func toPayloadRefProto(ref *task.BlobRef) *taskpb.PayloadRef {
+	if ref == nil {
+		return nil
+	}
+	return &taskpb.PayloadRef{Digest: ref.Digest, Size: ref.Size}
+}
+
+func (s *TaskService) UploadPayload(stream taskpb.TaskService_UploadPayloadServer) error {
+	if err := s.authorizeAny(stream.Context()); err != nil {
+		return err
+	}
+	blobStore := s.taskManager.Blobs()
+	if blobStore == nil {
+		return fmt.Errorf("failed to upload payload: blob storage is not enabled")
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				writer.Close()
+				return
+			}
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if _, err := writer.Write(chunk.Data); err != nil {
+				return
+			}
+		}
+	}()
+
+	meta, err := blobStore.Put(reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to upload payload: %v", err)
+	}
+	s.tasks(stream.Context()).RecordUpload(meta.Digest)
+	return stream.SendAndClose(&taskpb.PayloadRef{Digest: meta.Digest, Size: meta.Size})
+}
+
+func (s *TaskService) DownloadPayload(req *taskpb.DownloadPayloadRequest, stream taskpb.TaskService_DownloadPayloadServer) error {
+	if err := s.authorizeAny(stream.Context()); err != nil {
+		return err
+	}
+	if s.taskManager.Blobs() == nil {
+		return fmt.Errorf("failed to download payload: blob storage is not enabled")
+	}
+	if !s.tasks(stream.Context()).CanReadBlob(req.Digest) {
+		return fmt.Errorf("failed to download payload: blob not found")
+	}
+	reader, _, err := s.taskManager.OpenBlob(req.Digest)
+	if err != nil {
+		return fmt.Errorf("failed to download payload: %v", err)
+	}
+	defer reader.Close()
+	if err := sendBlob(reader, stream.Send); err != nil {
+		return fmt.Errorf("failed to download payload: %v", err)
+	}
+	return nil
+}
+
+// sendBlob sends a blob's payload in chunks
+func sendBlob(reader io.Reader, send func(*taskpb.PayloadChunk) error) error {
+	buf := make([]byte, blobs.CHUNK_SIZE)
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			if err := send(&taskpb.PayloadChunk{Data: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// fetchBlob sends a blob stored on this node to another node. Blobs this
+// node does not hold are not fetched in turn.
+func fetchBlob(blobStore *blobs.Store, digest string, send func(*taskpb.PayloadChunk) error) error {
+	if blobStore == nil {
+		return fmt.Errorf("failed to fetch blob: blob storage is not enabled")
+	}
+	reader, _, err := blobStore.Open(digest)
+	if errors.Is(err, blobs.ErrNotFound) {
+		return status.Errorf(codes.NotFound, "failed to fetch blob: %v", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch blob: %v", err)
+	}
+	defer reader.Close()
+	if err := sendBlob(reader, send); err != nil {
+		return fmt.Errorf("failed to fetch blob: %v", err)
+	}
+	return nil
+}
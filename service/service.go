@@ -2,13 +2,20 @@ package service
 
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
+	"github.com/indkumar8999/ps-tasks/archive"
+	"github.com/indkumar8999/ps-tasks/auth"
 	"github.com/indkumar8999/ps-tasks/managers"
-	// "github.com/indkumar8999/ps-tasks/task"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/task"
 
 	"context"
 	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 
@@ -16,6 +23,9 @@ type TaskService struct {
 	taskpb.UnimplementedTaskServiceServer
 	leaseManager *managers.LeaseManager
 	taskManager *managers.TaskManager
+	// authenticator is nil when authentication is disabled
+	authenticator *auth.Authenticator
+	logger        *slog.Logger
 }
 
 // NewTaskService creates a new TaskService
@@ -23,89 +33,402 @@ func NewTaskService(leaseManager *managers.LeaseManager, taskManager *managers.T
 	return &TaskService{
 		leaseManager: leaseManager,
 		taskManager:  taskManager,
+		logger:       slog.Default(),
 	}
 }
 
+// toTaskProto converts a task into its protobuf representation
+func toTaskProto(t *task.Task) *taskpb.Task {
+	taskProto := &taskpb.Task{
+		Id:              t.ID,
+		TaskState:       t.State,
+		Queue:           t.Queue,
+		Data:            t.Data,
+		Input:           t.Input,
+		Result:          t.Result,
+		LastHeartbeat:   t.LastHeartbeat,
+		CancelRequested: t.CancelRequested,
+		InputBlob:       toPayloadRefProto(t.InputBlob),
+		ResultBlob:      toPayloadRefProto(t.ResultBlob),
+		TraceContext:    t.TraceContext,
+	}
+	if t.Progress != nil {
+		taskProto.Progress = &taskpb.Progress{
+			Percent:     t.Progress.Percent,
+			CurrentStep: t.Progress.CurrentStep,
+			TotalSteps:  t.Progress.TotalSteps,
+			Message:     t.Progress.Message,
+			UpdatedAt:   t.Progress.UpdatedAt,
+		}
+	}
+	if t.Error != nil {
+		taskProto.Error = &taskpb.TaskError{
+			Code:    t.Error.Code,
+			Message: t.Error.Message,
+			Details: t.Error.Details,
+		}
+	}
+	return taskProto
+}
+
+// toQueueProto converts a queue into its protobuf representation
+func toQueueProto(q *queues.Queue) *taskpb.Queue {
+	queueProto := &taskpb.Queue{
+		Name:        q.Name,
+		Paused:      q.Paused,
+		Compression: q.Compression,
+	}
+	for state, policy := range q.Retention {
+		queueProto.Retention = append(queueProto.Retention, &taskpb.RetentionPolicy{
+			State:         state,
+			MaxAgeSeconds: policy.MaxAgeSeconds,
+			Archive:       policy.Archive,
+		})
+	}
+	return queueProto
+}
+
 func (s *TaskService) CreateTask(ctx context.Context, req *taskpb.CreateTaskRequest) (*taskpb.TaskResponse, error) {
-	task1, err := s.taskManager.CreateTask("", "", req.Data, nil);
+	if err := s.authorize(ctx, s.tasks(ctx).QueueName(req.Queue), auth.ROLE_PRODUCER); err != nil {
+		return nil, err
+	}
+	var task1 *task.Task
+	var err error
+	if req.InputBlob != "" {
+		task1, err = s.tasks(ctx).CreateTaskWithInputBlob(req.Id, req.Name, req.Description, req.Queue, req.InputBlob, nil)
+	} else if req.Id != "" {
+		task1, err = s.tasks(ctx).CreateTaskWithID(req.Id, req.Name, req.Description, req.Queue, req.Data, nil)
+	} else {
+		task1, err = s.tasks(ctx).CreateTask(req.Name, req.Description, req.Queue, req.Data, nil)
+	}
+	var quotaErr *managers.QuotaError
+	if errors.As(err, &quotaErr) {
+		return nil, status.Errorf(codes.ResourceExhausted, "failed to create task: %v", err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create task: %v", err)
 	}
-	taskProto := &taskpb.Task{
-		Id:          task1.ID,
-		TaskState:   "CREATED",
-		Data:        req.Data,
-	}
+	s.log(ctx).Debug("task created", "task_id", task1.ID, "queue", task1.Queue)
+	taskProto := toTaskProto(task1)
 
 	return &taskpb.TaskResponse{Task: taskProto}, nil
 }
 
 func (s *TaskService) GetTask(ctx context.Context, req *taskpb.GetTaskRequest) (*taskpb.TaskResponse, error) {
-	task, err := s.taskManager.GetTask(req.Id)
+	if err := s.authorizeTask(ctx, req.Id, ""); err != nil {
+		return nil, err
+	}
+	task, err := s.tasks(ctx).GetTask(req.Id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task: %v", err)
 	}
-	taskProto := &taskpb.Task{
-		Id:          task.ID,
-		TaskState:   task.State,
-		Data:        task.Data,
-	}
+	taskProto := toTaskProto(task)
 
 	return &taskpb.TaskResponse{Task: taskProto}, nil
 }
 
 func (s *TaskService) CompleteTask(ctx context.Context, req *taskpb.CompleteTaskRequest) (*taskpb.TaskResponse, error) {
-	task, err := s.taskManager.CompleteTask(req.Id)
+	if err := s.authorizeWorker(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	var completed *task.Task
+	var err error
+	tasks := s.tasks(ctx).WithLease(req.LeaseId, req.FencingToken)
+	if req.ResultBlob != "" {
+		completed, err = tasks.CompleteTaskWithResultBlob(req.Id, req.ResultBlob)
+	} else {
+		completed, err = tasks.CompleteTask(req.Id, req.Result)
+	}
+	if errors.Is(err, managers.ErrLeaseLost) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to complete task: %v", err)
 	}
-	taskProto := &taskpb.Task{
-		Id:          task.ID,
-		TaskState:   task.State,
-		Data:        task.Data,
+	s.log(ctx).Debug("task completed", "task_id", completed.ID, "queue", completed.Queue)
+	taskProto := toTaskProto(completed)
+
+	return &taskpb.TaskResponse{Task: taskProto}, nil
+}
+
+func (s *TaskService) FailTask(ctx context.Context, req *taskpb.FailTaskRequest) (*taskpb.TaskResponse, error) {
+	if req.Error == nil {
+		return nil, fmt.Errorf("failed to fail task: error is required")
+	}
+	if err := s.authorizeWorker(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	taskErr := &task.TaskError{
+		Code:    req.Error.Code,
+		Message: req.Error.Message,
+		Details: req.Error.Details,
+	}
+	task, err := s.tasks(ctx).WithLease(req.LeaseId, req.FencingToken).FailTask(req.Id, taskErr)
+	if errors.Is(err, managers.ErrLeaseLost) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail task: %v", err)
+	}
+	s.log(ctx).Debug("task failed", "task_id", task.ID, "queue", task.Queue, "error_code", taskErr.Code)
+	taskProto := toTaskProto(task)
 
 	return &taskpb.TaskResponse{Task: taskProto}, nil
 }
 
 func (s *TaskService) UpdateTask(ctx context.Context, req *taskpb.UpdateTaskRequest) (*taskpb.TaskResponse, error) {
-	task, err := s.taskManager.UpdateTask(req.Id, req.TaskState, req.Data)
+	if err := s.authorizeWorker(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	input := req.Input
+	if input == nil {
+		input = req.Data
+	}
+	task, err := s.tasks(ctx).UpdateTask(req.Id, req.TaskState, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update task: %v", err)
 	}
-	taskProto := &taskpb.Task{
-		Id:          task.ID,
-		TaskState:   task.State,
-		Data:        task.Data,
-	}
+	taskProto := toTaskProto(task)
 
 	return &taskpb.TaskResponse{Task: taskProto}, nil
 }
 
 func (s *TaskService) LeaseTask(ctx context.Context, req *taskpb.LeaseTaskRequest) (*taskpb.LeaseTaskResponse, error) {
-	lease, err := s.taskManager.LeaseTask(req.TaskId, req.Owner)
+	if err := s.authorizeTask(ctx, req.TaskId, auth.ROLE_WORKER); err != nil {
+		return nil, err
+	}
+	owner, err := s.owner(ctx, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+	lease, err := s.tasks(ctx).LeaseTask(req.TaskId, owner, time.Duration(req.LeaseDurationSeconds)*time.Second)
+	if errors.Is(err, managers.ErrDraining) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to lease task: %v", err)
 	}
+	s.log(ctx).Debug("task leased", "task_id", lease.TaskID, "lease_id", lease.ID, "owner", lease.CreatedBy, "fencing_token", lease.FencingToken)
 	response := &taskpb.LeaseTaskResponse{
 		Id: lease.ID,
 		TaskId: lease.TaskID,
 		LeaseEndTime: lease.ExpiresAt.Format(time.RFC3339),
+		Term: lease.Term,
+		FencingToken: lease.FencingToken,
 	}
 
 	return response, nil
 }
 
 func (s *TaskService) GetUnLeasdTask(ctx context.Context, req *taskpb.UnLeasedTaskRequest) (*taskpb.TaskResponse, error) {
-	task, err := s.taskManager.GetUnLeasedTask()
+	// An empty queue means any queue
+	queue := req.Queue
+	if queue == "" {
+		queue = auth.ANY
+	}
+	if err := s.authorize(ctx, queue, auth.ROLE_WORKER); err != nil {
+		return nil, err
+	}
+	task, err := s.tasks(ctx).GetUnLeasedTask(req.Queue)
+	if errors.Is(err, managers.ErrDraining) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get unleased task: %v", err)
 	}
-	taskProto := &taskpb.Task{
-		Id:          task.ID,
-		TaskState:   task.State,
-		Data:        task.Data,
+	taskProto := toTaskProto(task)
+
+	return &taskpb.TaskResponse{Task: taskProto}, nil
+}
+
+func (s *TaskService) ReportProgress(ctx context.Context, req *taskpb.ReportProgressRequest) (*taskpb.ReportProgressResponse, error) {
+	if err := s.authorizeLease(ctx, req.LeaseId); err != nil {
+		return nil, err
+	}
+	owner, err := s.owner(ctx, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+	progress := &task.Progress{
+		Percent:     req.Percent,
+		CurrentStep: req.CurrentStep,
+		TotalSteps:  req.TotalSteps,
+		Message:     req.Message,
+	}
+	task, lease, err := s.tasks(ctx).WithLease(req.LeaseId, req.FencingToken).ReportProgress(req.LeaseId, owner, progress)
+	if errors.Is(err, managers.ErrLeaseLost) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to report progress: %v", err)
+	}
+	response := &taskpb.ReportProgressResponse{
+		Task: toTaskProto(task),
+		LeaseEndTime: lease.ExpiresAt.Format(time.RFC3339),
+		CancelRequested: task.CancelRequested,
+		PauseRequested: s.tasks(ctx).IsPauseRequested(task),
+	}
+
+	return response, nil
+}
+
+func (s *TaskService) CancelTask(ctx context.Context, req *taskpb.CancelTaskRequest) (*taskpb.TaskResponse, error) {
+	if err := s.authorizeTask(ctx, req.Id, auth.ROLE_PRODUCER); err != nil {
+		return nil, err
+	}
+	task, err := s.tasks(ctx).CancelTask(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel task: %v", err)
+	}
+	taskProto := toTaskProto(task)
+
+	return &taskpb.TaskResponse{Task: taskProto}, nil
+}
+
+func (s *TaskService) AcknowledgeCancel(ctx context.Context, req *taskpb.AcknowledgeCancelRequest) (*taskpb.TaskResponse, error) {
+	if err := s.authorizeLease(ctx, req.LeaseId); err != nil {
+		return nil, err
 	}
+	owner, err := s.owner(ctx, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.tasks(ctx).AcknowledgeCancel(req.LeaseId, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge cancel: %v", err)
+	}
+	taskProto := toTaskProto(task)
 
 	return &taskpb.TaskResponse{Task: taskProto}, nil
-}
\ No newline at end of file
+}
+
+func (s *TaskService) PauseTask(ctx context.Context, req *taskpb.PauseTaskRequest) (*taskpb.TaskResponse, error) {
+	if err := s.authorizeTask(ctx, req.Id, auth.ROLE_ADMIN); err != nil {
+		return nil, err
+	}
+	task, err := s.tasks(ctx).PauseTask(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pause task: %v", err)
+	}
+	taskProto := toTaskProto(task)
+
+	return &taskpb.TaskResponse{Task: taskProto}, nil
+}
+
+func (s *TaskService) ResumeTask(ctx context.Context, req *taskpb.ResumeTaskRequest) (*taskpb.TaskResponse, error) {
+	if err := s.authorizeTask(ctx, req.Id, auth.ROLE_ADMIN); err != nil {
+		return nil, err
+	}
+	task, err := s.tasks(ctx).ResumeTask(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume task: %v", err)
+	}
+	taskProto := toTaskProto(task)
+
+	return &taskpb.TaskResponse{Task: taskProto}, nil
+}
+
+func (s *TaskService) PauseQueue(ctx context.Context, req *taskpb.PauseQueueRequest) (*taskpb.QueueResponse, error) {
+	if err := s.authorize(ctx, s.tasks(ctx).QueueName(req.Queue), auth.ROLE_ADMIN); err != nil {
+		return nil, err
+	}
+	queue, err := s.tasks(ctx).PauseQueue(req.Queue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pause queue: %v", err)
+	}
+
+	return &taskpb.QueueResponse{Queue: toQueueProto(queue)}, nil
+}
+
+func (s *TaskService) ResumeQueue(ctx context.Context, req *taskpb.ResumeQueueRequest) (*taskpb.QueueResponse, error) {
+	if err := s.authorize(ctx, s.tasks(ctx).QueueName(req.Queue), auth.ROLE_ADMIN); err != nil {
+		return nil, err
+	}
+	queue, err := s.tasks(ctx).ResumeQueue(req.Queue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume queue: %v", err)
+	}
+
+	return &taskpb.QueueResponse{Queue: toQueueProto(queue)}, nil
+}
+
+func (s *TaskService) SetRetentionPolicy(ctx context.Context, req *taskpb.SetRetentionPolicyRequest) (*taskpb.QueueResponse, error) {
+	if err := s.authorize(ctx, s.tasks(ctx).QueueName(req.Queue), auth.ROLE_ADMIN); err != nil {
+		return nil, err
+	}
+	if req.Policy == nil {
+		return nil, fmt.Errorf("failed to set retention policy: policy is required")
+	}
+	policy := &queues.RetentionPolicy{
+		MaxAgeSeconds: req.Policy.MaxAgeSeconds,
+		Archive:       req.Policy.Archive,
+	}
+	queue, err := s.tasks(ctx).SetRetentionPolicy(req.Queue, req.Policy.State, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set retention policy: %v", err)
+	}
+
+	return &taskpb.QueueResponse{Queue: toQueueProto(queue)}, nil
+}
+
+func (s *TaskService) SetQueueCompression(ctx context.Context, req *taskpb.SetQueueCompressionRequest) (*taskpb.QueueResponse, error) {
+	if err := s.authorize(ctx, s.tasks(ctx).QueueName(req.Queue), auth.ROLE_ADMIN); err != nil {
+		return nil, err
+	}
+	queue, err := s.tasks(ctx).SetCompression(req.Queue, req.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set queue compression: %v", err)
+	}
+
+	return &taskpb.QueueResponse{Queue: toQueueProto(queue)}, nil
+}
+
+func (s *TaskService) SearchArchive(ctx context.Context, req *taskpb.SearchArchiveRequest) (*taskpb.SearchArchiveResponse, error) {
+	if err := s.authorizeAny(ctx); err != nil {
+		return nil, err
+	}
+	filter := archive.Filter{
+		TaskID: req.TaskId,
+		Queue:  req.Queue,
+		State:  req.TaskState,
+		Limit:  int(req.Limit),
+	}
+	var err error
+	if req.CreatedAfter != "" {
+		if filter.CreatedAfter, err = time.Parse(time.RFC3339, req.CreatedAfter); err != nil {
+			return nil, fmt.Errorf("invalid created_after: %v", err)
+		}
+	}
+	if req.CreatedBefore != "" {
+		if filter.CreatedBefore, err = time.Parse(time.RFC3339, req.CreatedBefore); err != nil {
+			return nil, fmt.Errorf("invalid created_before: %v", err)
+		}
+	}
+
+	tasks, err := s.tasks(ctx).SearchArchive(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search archive: %v", err)
+	}
+	response := &taskpb.SearchArchiveResponse{}
+	for _, t := range tasks {
+		if s.canRead(ctx, t.Queue) {
+			response.Tasks = append(response.Tasks, toTaskProto(t))
+		}
+	}
+
+	return response, nil
+}
+
+func (s *TaskService) ListTasks(ctx context.Context, req *taskpb.ListTasksRequest) (*taskpb.ListTasksResponse, error) {
+	if err := s.authorizeAny(ctx); err != nil {
+		return nil, err
+	}
+	response := &taskpb.ListTasksResponse{}
+	for _, t := range s.tasks(ctx).ListTasks(req.Queue, req.TaskState, int(req.Limit)) {
+		if s.canRead(ctx, t.Queue) {
+			response.Tasks = append(response.Tasks, toTaskProto(t))
+		}
+	}
+
+	return response, nil
+}
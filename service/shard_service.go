@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"github.com/indkumar8999/ps-tasks/shards"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+func (s *TaskService) GetShardMap(ctx context.Context, req *taskpb.GetShardMapRequest) (*taskpb.ShardMapResponse, error) {
+	if err := s.authorizeAny(ctx); err != nil {
+		return nil, err
+	}
+	shardMap, err := s.taskManager.GetShardMap()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard map: %v", err)
+	}
+
+	return &taskpb.ShardMapResponse{ShardMap: shardMap.ToProto()}, nil
+}
+
+func (s *TaskService) SetShardMap(ctx context.Context, req *taskpb.SetShardMapRequest) (*taskpb.ShardMapResponse, error) {
+	if err := s.authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+	if req.ShardMap == nil {
+		return nil, fmt.Errorf("failed to set shard map: shard map is required")
+	}
+	shardMap, err := s.taskManager.WithContext(ctx).SetShardMap(shards.FromProto(req.ShardMap))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set shard map: %v", err)
+	}
+
+	return &taskpb.ShardMapResponse{ShardMap: shardMap.ToProto()}, nil
+}
+
+func (s *TaskService) ExportSlot(ctx context.Context, req *taskpb.ExportSlotRequest) (*taskpb.ExportSlotResponse, error) {
+	if err := s.authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+	exported, err := s.taskManager.ExportSlot(int(req.Slot), int(req.Slots))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export slot: %v", err)
+	}
+
+	response := &taskpb.ExportSlotResponse{}
+	for _, e := range exported {
+		taskData, err := json.Marshal(e.Task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode task: %v", err)
+		}
+		exportedProto := &taskpb.ExportedTask{Task: taskData, Version: e.Version}
+		for _, lease := range e.Leases {
+			leaseData, err := json.Marshal(lease)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode lease: %v", err)
+			}
+			exportedProto.Leases = append(exportedProto.Leases, leaseData)
+		}
+		response.Tasks = append(response.Tasks, exportedProto)
+	}
+	return response, nil
+}
+
+func (s *TaskService) ImportTasks(ctx context.Context, req *taskpb.ImportTasksRequest) (*taskpb.ImportTasksResponse, error) {
+	if err := s.authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+	var imported int32
+	for _, exportedProto := range req.Tasks {
+		var t task.Task
+		if err := json.Unmarshal(exportedProto.Task, &t); err != nil {
+			return nil, fmt.Errorf("failed to decode task: %v", err)
+		}
+		var taskLeases []*leases.Lease
+		for _, leaseData := range exportedProto.Leases {
+			var lease leases.Lease
+			if err := json.Unmarshal(leaseData, &lease); err != nil {
+				return nil, fmt.Errorf("failed to decode lease: %v", err)
+			}
+			taskLeases = append(taskLeases, &lease)
+		}
+		applied, err := s.taskManager.WithContext(ctx).ImportTask(&t, taskLeases)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import task %s: %v", t.ID, err)
+		}
+		if applied {
+			imported++
+		}
+	}
+
+	return &taskpb.ImportTasksResponse{Imported: imported}, nil
+}
+
+func (s *TaskService) DropTasks(ctx context.Context, req *taskpb.DropTasksRequest) (*taskpb.DropTasksResponse, error) {
+	if err := s.authorizeOperator(ctx); err != nil {
+		return nil, err
+	}
+	response := &taskpb.DropTasksResponse{}
+	for _, taskVersion := range req.Tasks {
+		dropped, err := s.taskManager.WithContext(ctx).DropTask(taskVersion.Id, taskVersion.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to drop task %s: %v", taskVersion.Id, err)
+		}
+		if dropped {
+			response.Dropped = append(response.Dropped, taskVersion.Id)
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"github.com/indkumar8999/ps-tasks/snapshot"
+)
+
+// SnapshotService creates and restores snapshots of the database
+type SnapshotService struct {
+	taskpb.UnimplementedSnapshotServiceServer
+	snapshotManager *snapshot.Manager
+}
+
+// NewSnapshotService creates a new SnapshotService
+func NewSnapshotService(snapshotManager *snapshot.Manager) *SnapshotService {
+	return &SnapshotService{snapshotManager: snapshotManager}
+}
+
+// toSnapshotProto converts a snapshot manifest into its protobuf representation
+func toSnapshotProto(manifest *snapshot.Manifest) *taskpb.SnapshotInfo {
+	return &taskpb.SnapshotInfo{
+		Id:        manifest.ID,
+		CreatedAt: manifest.CreatedAt,
+		Tasks:     int32(manifest.Tasks),
+		Leases:    int32(manifest.Leases),
+		Queues:    int32(manifest.Queues),
+		Files:     int32(len(manifest.Files)),
+	}
+}
+
+func (s *SnapshotService) CreateSnapshot(ctx context.Context, req *taskpb.CreateSnapshotRequest) (*taskpb.SnapshotInfo, error) {
+	manifest, err := s.snapshotManager.Create()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %v", err)
+	}
+
+	return toSnapshotProto(manifest), nil
+}
+
+func (s *SnapshotService) ListSnapshots(ctx context.Context, req *taskpb.ListSnapshotsRequest) (*taskpb.ListSnapshotsResponse, error) {
+	manifests, err := s.snapshotManager.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %v", err)
+	}
+	response := &taskpb.ListSnapshotsResponse{}
+	for _, manifest := range manifests {
+		response.Snapshots = append(response.Snapshots, toSnapshotProto(manifest))
+	}
+
+	return response, nil
+}
+
+func (s *SnapshotService) RestoreSnapshot(ctx context.Context, req *taskpb.RestoreSnapshotRequest) (*taskpb.SnapshotInfo, error) {
+	manifest, err := s.snapshotManager.Restore(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot: %v", err)
+	}
+
+	return toSnapshotProto(manifest), nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/indkumar8999/ps-tasks/logship"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/service/taskpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StandbyService ships the mutation log and blobs from a primary to its
+// standbys
+type StandbyService struct {
+	taskpb.UnimplementedStandbyServiceServer
+	node        *logship.Node
+	taskManager *managers.TaskManager
+}
+
+// NewStandbyService creates a new StandbyService
+func NewStandbyService(node *logship.Node, taskManager *managers.TaskManager) *StandbyService {
+	return &StandbyService{node: node, taskManager: taskManager}
+}
+
+func (s *StandbyService) StreamLog(req *taskpb.StreamLogRequest, stream taskpb.StandbyService_StreamLogServer) error {
+	err := s.node.Stream(stream.Context(), req.AfterSeq, req.Epoch, func(entry *logship.Entry, state []byte) error {
+		return stream.Send(&taskpb.LogEntry{Seq: entry.Seq, Epoch: entry.Epoch, Command: entry.Command, State: state})
+	})
+	if errors.Is(err, logship.ErrStreamsClosed) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil && stream.Context().Err() == nil {
+		return fmt.Errorf("failed to stream log: %v", err)
+	}
+	return nil
+}
+
+func (s *StandbyService) FetchBlob(req *taskpb.FetchBlobRequest, stream taskpb.StandbyService_FetchBlobServer) error {
+	return fetchBlob(s.taskManager.Blobs(), req.Digest, stream.Send)
+}
+
+func (s *StandbyService) Promote(ctx context.Context, req *taskpb.PromoteRequest) (*taskpb.ReplicationStatus, error) {
+	if err := s.node.Promote(); err != nil {
+		return nil, fmt.Errorf("failed to promote: %v", err)
+	}
+	return s.status(), nil
+}
+
+func (s *StandbyService) Fence(ctx context.Context, req *taskpb.FenceRequest) (*taskpb.ReplicationStatus, error) {
+	if err := s.node.Fence(req.Epoch); err != nil {
+		return nil, fmt.Errorf("failed to fence: %v", err)
+	}
+	return s.status(), nil
+}
+
+func (s *StandbyService) GetReplicationStatus(ctx context.Context, req *taskpb.GetReplicationStatusRequest) (*taskpb.ReplicationStatus, error) {
+	return s.status(), nil
+}
+
+func (s *StandbyService) status() *taskpb.ReplicationStatus {
+	role, epoch, seq := s.node.Status()
+	status := &taskpb.ReplicationStatus{Role: role, Epoch: epoch, AppliedSeq: seq}
+	if role == logship.ROLE_STANDBY {
+		status.Primary = s.node.PrimaryAddr()
+	}
+	return status
+}
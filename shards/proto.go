@@ -0,0 +1,43 @@
+package shards
+
+import "github.com/indkumar8999/ps-tasks/service/taskpb"
+
+// ToProto converts a shard map into its protobuf representation
+func (m *ShardMap) ToProto() *taskpb.ShardMap {
+	shardMapProto := &taskpb.ShardMap{
+		Version:     m.Version,
+		Slots:       int32(m.Slots),
+		Assignments: m.Assignments,
+	}
+	for _, shard := range m.Shards {
+		shardMapProto.Shards = append(shardMapProto.Shards, &taskpb.Shard{Id: shard.ID, Addr: shard.Addr})
+	}
+	for _, migration := range m.Migrations {
+		shardMapProto.Migrations = append(shardMapProto.Migrations, &taskpb.SlotMigration{
+			Slot: int32(migration.Slot),
+			From: migration.From,
+			To:   migration.To,
+		})
+	}
+	return shardMapProto
+}
+
+// FromProto converts a protobuf shard map into a ShardMap
+func FromProto(shardMapProto *taskpb.ShardMap) *ShardMap {
+	m := &ShardMap{
+		Version:     shardMapProto.Version,
+		Slots:       int(shardMapProto.Slots),
+		Assignments: shardMapProto.Assignments,
+	}
+	for _, shard := range shardMapProto.Shards {
+		m.Shards = append(m.Shards, Shard{ID: shard.Id, Addr: shard.Addr})
+	}
+	for _, migration := range shardMapProto.Migrations {
+		m.Migrations = append(m.Migrations, Migration{
+			Slot: int(migration.Slot),
+			From: migration.From,
+			To:   migration.To,
+		})
+	}
+	return m
+}
@@ -0,0 +1,229 @@
+package shards
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DEFAULT_SLOTS is the number of hash slots tasks are spread over
+const DEFAULT_SLOTS = 256
+
+// SHARD_MAP_FILE is the name of the shard map file in the metadata directory
+const SHARD_MAP_FILE = "shard_map.json"
+
+// Shard is a TaskService instance owning part of the task ID space
+type Shard struct {
+	ID   string `json:"id"`
+	Addr string `json:"addr"`
+}
+
+// Migration records a slot being moved between shards
+type Migration struct {
+	Slot int    `json:"slot"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ShardMap assigns every hash slot to a shard. Task IDs are hashed to a slot,
+// so a task lives on the shard owning its slot.
+type ShardMap struct {
+	Version int64   `json:"version"`
+	Slots   int     `json:"slots"`
+	Shards  []Shard `json:"shards"`
+	// Assignments holds the owning shard ID of every slot
+	Assignments []string `json:"assignments"`
+	// Migrations lists slots currently being moved. Their Assignments entry
+	// already names the new owner.
+	Migrations []Migration `json:"migrations,omitempty"`
+}
+
+// NewShardMap spreads the slots evenly over the given shards
+func NewShardMap(shards []Shard, slots int) (*ShardMap, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("at least one shard is required")
+	}
+	if slots <= 0 {
+		slots = DEFAULT_SLOTS
+	}
+	m := &ShardMap{
+		Version:     1,
+		Slots:       slots,
+		Shards:      shards,
+		Assignments: make([]string, slots),
+	}
+	for slot := range m.Assignments {
+		m.Assignments[slot] = shards[slot%len(shards)].ID
+	}
+	return m, m.Validate()
+}
+
+// SlotFor returns the hash slot of a key
+func SlotFor(key string, slots int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(slots))
+}
+
+// Validate checks that every slot is assigned to a known shard
+func (m *ShardMap) Validate() error {
+	if m.Slots <= 0 || len(m.Assignments) != m.Slots {
+		return fmt.Errorf("shard map must assign all %d slots", m.Slots)
+	}
+	known := make(map[string]bool)
+	for _, shard := range m.Shards {
+		if shard.ID == "" || shard.Addr == "" {
+			return fmt.Errorf("shard needs an ID and an address")
+		}
+		if known[shard.ID] {
+			return fmt.Errorf("duplicate shard %s", shard.ID)
+		}
+		known[shard.ID] = true
+	}
+	for slot, shardID := range m.Assignments {
+		if !known[shardID] {
+			return fmt.Errorf("slot %d is assigned to unknown shard %s", slot, shardID)
+		}
+	}
+	for _, migration := range m.Migrations {
+		if !known[migration.From] || !known[migration.To] {
+			return fmt.Errorf("slot %d migrates between unknown shards", migration.Slot)
+		}
+	}
+	return nil
+}
+
+// Shard returns a shard by ID
+func (m *ShardMap) Shard(id string) (Shard, error) {
+	for _, shard := range m.Shards {
+		if shard.ID == id {
+			return shard, nil
+		}
+	}
+	return Shard{}, fmt.Errorf("shard %s not found", id)
+}
+
+// Owner returns the shard owning a key
+func (m *ShardMap) Owner(key string) (Shard, error) {
+	return m.Shard(m.Assignments[SlotFor(key, m.Slots)])
+}
+
+// PreviousOwner returns the shard a key's slot is migrating from, if any
+func (m *ShardMap) PreviousOwner(key string) (Shard, bool) {
+	slot := SlotFor(key, m.Slots)
+	for _, migration := range m.Migrations {
+		if migration.Slot == slot {
+			shard, err := m.Shard(migration.From)
+			return shard, err == nil
+		}
+	}
+	return Shard{}, false
+}
+
+// Rebalance returns a copy of the map spread evenly over the target shards,
+// along with the slot moves needed to get there. Slots already on a shard
+// that keeps its fair share are left in place.
+func (m *ShardMap) Rebalance(target []Shard) (*ShardMap, []Migration, error) {
+	if len(target) == 0 {
+		return nil, nil, fmt.Errorf("at least one shard is required")
+	}
+	next := &ShardMap{
+		Version:     m.Version + 1,
+		Slots:       m.Slots,
+		Shards:      target,
+		Assignments: make([]string, m.Slots),
+	}
+
+	// Every shard gets base slots, the first extra shards one more
+	base, extra := m.Slots/len(target), m.Slots%len(target)
+	quota := make(map[string]int)
+	ids := make([]string, 0, len(target))
+	for i, shard := range target {
+		quota[shard.ID] = base
+		if i < extra {
+			quota[shard.ID]++
+		}
+		ids = append(ids, shard.ID)
+	}
+
+	// Keep slots where they are while their owner is within quota
+	var unassigned []int
+	for slot, owner := range m.Assignments {
+		if quota[owner] > 0 {
+			next.Assignments[slot] = owner
+			quota[owner]--
+		} else {
+			unassigned = append(unassigned, slot)
+		}
+	}
+
+	// Hand the remaining slots to shards with room left
+	sort.Strings(ids)
+	var moves []Migration
+	for _, slot := range unassigned {
+		for _, id := range ids {
+			if quota[id] > 0 {
+				next.Assignments[slot] = id
+				quota[id]--
+				break
+			}
+		}
+		moves = append(moves, Migration{Slot: slot, From: m.Assignments[slot], To: next.Assignments[slot]})
+	}
+
+	return next, moves, next.Validate()
+}
+
+// Migrating returns a copy of the target map that also knows the shards of
+// the previous map, with the given moves recorded as in progress. Clients use
+// it to fall back to the old owner of a slot until its tasks have been copied.
+func (m *ShardMap) Migrating(previous *ShardMap, moves []Migration) *ShardMap {
+	migrating := &ShardMap{
+		Version:     m.Version,
+		Slots:       m.Slots,
+		Shards:      append([]Shard{}, m.Shards...),
+		Assignments: append([]string{}, m.Assignments...),
+		Migrations:  append([]Migration{}, moves...),
+	}
+	for _, shard := range previous.Shards {
+		if _, err := migrating.Shard(shard.ID); err != nil {
+			migrating.Shards = append(migrating.Shards, shard)
+		}
+	}
+	return migrating
+}
+
+// Save saves the shard map to the metadata directory
+func (m *ShardMap) Save(metadataDir string) error {
+	tmpFile := filepath.Join(metadataDir, SHARD_MAP_FILE+".tmp")
+	file, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(m); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filepath.Join(metadataDir, SHARD_MAP_FILE))
+}
+
+// LoadShardMap loads the shard map from the metadata directory
+func LoadShardMap(metadataDir string) (*ShardMap, error) {
+	file, err := os.Open(filepath.Join(metadataDir, SHARD_MAP_FILE))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var m ShardMap
+	if err := json.NewDecoder(file).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,181 @@
+package shards
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testShards(ids ...string) []Shard {
+	shards := make([]Shard, 0, len(ids))
+	for _, id := range ids {
+		shards = append(shards, Shard{ID: id, Addr: id + ":8080"})
+	}
+	return shards
+}
+
+// slotCounts counts the slots each shard owns
+func slotCounts(m *ShardMap) map[string]int {
+	counts := make(map[string]int)
+	for _, owner := range m.Assignments {
+		counts[owner]++
+	}
+	return counts
+}
+
+func TestNewShardMap(t *testing.T) {
+	m, err := NewShardMap(testShards("a", "b", "c"), 0)
+	if err != nil {
+		t.Fatalf("NewShardMap: %v", err)
+	}
+	if m.Slots != DEFAULT_SLOTS || len(m.Assignments) != DEFAULT_SLOTS {
+		t.Errorf("slots = %d with %d assignments, want %d", m.Slots, len(m.Assignments), DEFAULT_SLOTS)
+	}
+	for id, count := range slotCounts(m) {
+		if count < DEFAULT_SLOTS/3 || count > DEFAULT_SLOTS/3+1 {
+			t.Errorf("shard %s owns %d slots, want an even share", id, count)
+		}
+	}
+	owner, err := m.Owner("task-1")
+	if err != nil || owner.ID != m.Assignments[SlotFor("task-1", m.Slots)] {
+		t.Errorf("Owner = %v, %v, want the owner of the key's slot", owner, err)
+	}
+	if _, err := NewShardMap(nil, 16); err == nil {
+		t.Errorf("a shard map without shards was created")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *ShardMap)
+	}{
+		{name: "missing assignments", change: func(m *ShardMap) { m.Assignments = m.Assignments[1:] }},
+		{name: "no slots", change: func(m *ShardMap) { m.Slots, m.Assignments = 0, nil }},
+		{name: "shard without an address", change: func(m *ShardMap) { m.Shards[0].Addr = "" }},
+		{name: "duplicate shard", change: func(m *ShardMap) { m.Shards = append(m.Shards, m.Shards[0]) }},
+		{name: "unknown owner", change: func(m *ShardMap) { m.Assignments[3] = "z" }},
+		{name: "migration from an unknown shard", change: func(m *ShardMap) {
+			m.Migrations = []Migration{{Slot: 1, From: "z", To: "a"}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewShardMap(testShards("a", "b"), 8)
+			if err != nil {
+				t.Fatalf("NewShardMap: %v", err)
+			}
+			tt.change(m)
+			if err := m.Validate(); err == nil {
+				t.Errorf("Validate accepted a map with a %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   []string
+		to     []string
+		slots  int
+		counts map[string]int
+	}{
+		{name: "add a shard", from: []string{"a", "b"}, to: []string{"a", "b", "c"}, slots: 12, counts: map[string]int{"a": 4, "b": 4, "c": 4}},
+		{name: "remove a shard", from: []string{"a", "b", "c"}, to: []string{"a", "c"}, slots: 12, counts: map[string]int{"a": 6, "c": 6}},
+		{name: "uneven split", from: []string{"a"}, to: []string{"a", "b", "c"}, slots: 10, counts: map[string]int{"a": 4, "b": 3, "c": 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewShardMap(testShards(tt.from...), tt.slots)
+			if err != nil {
+				t.Fatalf("NewShardMap: %v", err)
+			}
+			next, moves, err := m.Rebalance(testShards(tt.to...))
+			if err != nil {
+				t.Fatalf("Rebalance: %v", err)
+			}
+			if next.Version != m.Version+1 {
+				t.Errorf("version = %d, want %d", next.Version, m.Version+1)
+			}
+			if got := slotCounts(next); fmt.Sprint(got) != fmt.Sprint(tt.counts) {
+				t.Errorf("slot counts = %v, want %v", got, tt.counts)
+			}
+
+			// Only the slots that changed owner move, and each move
+			// matches the old and new maps
+			moved := 0
+			for slot := range m.Assignments {
+				if m.Assignments[slot] != next.Assignments[slot] {
+					moved++
+				}
+			}
+			if len(moves) != moved {
+				t.Errorf("%d moves for %d changed slots", len(moves), moved)
+			}
+			for _, move := range moves {
+				if move.From != m.Assignments[move.Slot] || move.To != next.Assignments[move.Slot] {
+					t.Errorf("move %+v does not match the maps", move)
+				}
+			}
+
+			// The migrating map routes to the new owner and remembers the old one
+			migrating := next.Migrating(m, moves)
+			if err := migrating.Validate(); err != nil {
+				t.Errorf("migrating map is invalid: %v", err)
+			}
+			for _, move := range moves {
+				if _, err := migrating.Shard(move.From); err != nil {
+					t.Errorf("migrating map lost shard %s", move.From)
+				}
+			}
+		})
+	}
+
+	m, err := NewShardMap(testShards("a"), 4)
+	if err != nil {
+		t.Fatalf("NewShardMap: %v", err)
+	}
+	if _, _, err := m.Rebalance(nil); err == nil {
+		t.Errorf("rebalancing onto no shards succeeded")
+	}
+}
+
+func TestPreviousOwner(t *testing.T) {
+	m, err := NewShardMap(testShards("a"), 4)
+	if err != nil {
+		t.Fatalf("NewShardMap: %v", err)
+	}
+	if _, ok := m.PreviousOwner("task"); ok {
+		t.Errorf("a key has a previous owner without a migration")
+	}
+	next, moves, err := m.Rebalance(testShards("b"))
+	if err != nil {
+		t.Fatalf("Rebalance: %v", err)
+	}
+	migrating := next.Migrating(m, moves)
+	previous, ok := migrating.PreviousOwner("task")
+	if !ok || previous.ID != "a" {
+		t.Errorf("PreviousOwner = %v, %v, want a", previous, ok)
+	}
+	if owner, err := migrating.Owner("task"); err != nil || owner.ID != "b" {
+		t.Errorf("Owner = %v, %v, want b", owner, err)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewShardMap(testShards("a", "b"), 8)
+	if err != nil {
+		t.Fatalf("NewShardMap: %v", err)
+	}
+	if err := m.Save(dir); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := LoadShardMap(dir)
+	if err != nil {
+		t.Fatalf("LoadShardMap: %v", err)
+	}
+	if fmt.Sprint(loaded) != fmt.Sprint(m) {
+		t.Errorf("loaded map = %+v, want %+v", loaded, m)
+	}
+}
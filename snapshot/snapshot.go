@@ -0,0 +1,421 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/codec"
+	"github.com/indkumar8999/ps-tasks/keyring"
+	"github.com/indkumar8999/ps-tasks/leases"
+	"github.com/indkumar8999/ps-tasks/managers"
+	"github.com/indkumar8999/ps-tasks/metrics"
+	"github.com/indkumar8999/ps-tasks/queues"
+	"github.com/indkumar8999/ps-tasks/shards"
+	"github.com/indkumar8999/ps-tasks/store"
+	"github.com/indkumar8999/ps-tasks/task"
+)
+
+// MANIFEST_FILE is the name of the manifest inside a snapshot. It is the last
+// entry of the archive, so a snapshot cut short has no manifest.
+const MANIFEST_FILE = "manifest.json"
+
+// FORMAT_VERSION is the version of the snapshot layout
+const FORMAT_VERSION = 1
+
+// Prefixes of snapshot IDs. Only scheduled snapshots are rotated.
+const (
+	MANUAL_PREFIX    = "snapshot-"
+	SCHEDULED_PREFIX = "scheduled-"
+)
+
+// DEFAULT_KEEP is how many scheduled snapshots are kept
+const DEFAULT_KEEP = 7
+
+// FileEntry describes one record file in a snapshot
+type FileEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes the content of a snapshot
+type Manifest struct {
+	ID            string      `json:"id"`
+	FormatVersion int         `json:"format_version"`
+	CreatedAt     string      `json:"created_at"`
+	Tasks         int         `json:"tasks"`
+	Leases        int         `json:"leases"`
+	Queues        int         `json:"queues"`
+	Files         []FileEntry `json:"files"`
+}
+
+// Manager creates, lists and restores snapshots of a TaskManager. A snapshot
+// is a gzipped tar of the task, lease and metadata records laid out like the
+// database directory, plus a manifest with a checksum of every file.
+type Manager struct {
+	snapshotsDir string
+	taskManager  *managers.TaskManager
+	keep         int
+	snapshotLock *sync.Mutex
+	// onCreate is called after every snapshot is written
+	onCreate func(*Manifest)
+	stop     chan struct{}
+	stopOnce *sync.Once
+}
+
+// NewManager creates a new snapshot Manager
+func NewManager(snapshotsDir string, taskManager *managers.TaskManager, keep int) (*Manager, error) {
+	if err := os.MkdirAll(snapshotsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshots directory: %v", err)
+	}
+	if keep <= 0 {
+		keep = DEFAULT_KEEP
+	}
+	return &Manager{
+		snapshotsDir: snapshotsDir,
+		taskManager:  taskManager,
+		keep:         keep,
+		snapshotLock: &sync.Mutex{},
+		stop:         make(chan struct{}),
+		stopOnce:     &sync.Once{},
+	}, nil
+}
+
+// SetOnCreate calls fn after every snapshot is written, such as to compact
+// logs the snapshot makes unnecessary
+func (m *Manager) SetOnCreate(fn func(*Manifest)) {
+	m.snapshotLock.Lock()
+	defer m.snapshotLock.Unlock()
+	m.onCreate = fn
+}
+
+func (m *Manager) path(id string) string {
+	return filepath.Join(m.snapshotsDir, id+".tar.gz")
+}
+
+// Create takes a snapshot. The state is copied in memory while the managers'
+// locks are held, and written out after they are released.
+func (m *Manager) Create() (*Manifest, error) {
+	return m.create(MANUAL_PREFIX)
+}
+
+func (m *Manager) create(prefix string) (*Manifest, error) {
+	m.snapshotLock.Lock()
+	defer m.snapshotLock.Unlock()
+
+	data, err := m.taskManager.ExportState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to export state: %v", err)
+	}
+	var state managers.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode state: %v", err)
+	}
+
+	// IDs sort in the order snapshots were taken, which rotation relies on.
+	// The random suffix keeps snapshots taken at the same instant apart.
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	manifest := &Manifest{
+		ID:            prefix + now.Format("20060102T150405.000000000Z") + "-" + hex.EncodeToString(suffix),
+		FormatVersion: FORMAT_VERSION,
+		CreatedAt:     now.Format(time.RFC3339),
+		Tasks:         len(state.Tasks),
+		Leases:        len(state.Leases),
+		Queues:        len(state.Queues),
+	}
+
+	tmpFile := m.path(manifest.ID) + ".tmp"
+	file, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeArchive(file, &state, manifest, m.taskManager.Keyring()); err != nil {
+		file.Close()
+		os.Remove(tmpFile)
+		return nil, err
+	}
+	metrics.PersistenceFsyncs.WithLabelValues("snapshots").Inc()
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpFile)
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpFile)
+		return nil, err
+	}
+	if err := os.Rename(tmpFile, m.path(manifest.ID)); err != nil {
+		return nil, err
+	}
+	if m.onCreate != nil {
+		m.onCreate(manifest)
+	}
+	return manifest, nil
+}
+
+// writeArchive writes the state as a tar.gz. Task payloads are sealed when
+// the server encrypts tasks at rest.
+func writeArchive(w io.Writer, state *managers.State, manifest *Manifest, keys *keyring.Keyring) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	add := func(name string, v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+		if name != MANIFEST_FILE {
+			sum := sha256.Sum256(data)
+			manifest.Files = append(manifest.Files, FileEntry{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+		}
+		return nil
+	}
+
+	for _, t := range state.Tasks {
+		t, err := t.Pack(keys, codec.NONE)
+		if err != nil {
+			return err
+		}
+		if err := add("tasks/"+store.FileName(t.ID), t); err != nil {
+			return err
+		}
+	}
+	for _, lease := range state.Leases {
+		if err := add("leases/"+store.FileName(lease.ID), lease); err != nil {
+			return err
+		}
+	}
+	for _, queue := range state.Queues {
+		if err := add("metadata/queues/"+store.FileName(queue.Name), queue); err != nil {
+			return err
+		}
+	}
+	if state.ShardMap != nil {
+		if err := add("metadata/"+shards.SHARD_MAP_FILE, state.ShardMap); err != nil {
+			return err
+		}
+	}
+	if err := add(MANIFEST_FILE, manifest); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// List returns the manifests of all snapshots, oldest first
+func (m *Manager) List() ([]*Manifest, error) {
+	ids, err := m.ids("")
+	if err != nil {
+		return nil, err
+	}
+	var manifests []*Manifest
+	for _, id := range ids {
+		manifest, _, err := m.read(id)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %v", id, err)
+		}
+		manifests = append(manifests, manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].CreatedAt < manifests[j].CreatedAt })
+	return manifests, nil
+}
+
+// ids returns the IDs of the snapshots with the given prefix, sorted by name
+func (m *Manager) ids(prefix string) ([]string, error) {
+	files, err := os.ReadDir(m.snapshotsDir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".tar.gz") || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".tar.gz"))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Verify reads a snapshot and checks every file against the manifest
+func (m *Manager) Verify(id string) (*Manifest, error) {
+	manifest, _, err := m.read(id)
+	return manifest, err
+}
+
+// Restore verifies a snapshot and replaces the current state with it
+func (m *Manager) Restore(id string) (*Manifest, error) {
+	m.snapshotLock.Lock()
+	defer m.snapshotLock.Unlock()
+
+	manifest, state, err := m.read(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.taskManager.ReplaceState(state); err != nil {
+		return nil, fmt.Errorf("failed to restore state: %v", err)
+	}
+	return manifest, nil
+}
+
+// read loads a snapshot, verifying its checksums
+func (m *Manager) read(id string) (*Manifest, *managers.State, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, nil, fmt.Errorf("invalid snapshot ID")
+	}
+	file, err := os.Open(m.path(id))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	contents := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read snapshot: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %v", header.Name, err)
+		}
+		contents[header.Name] = data
+	}
+
+	manifestData, ok := contents[MANIFEST_FILE]
+	if !ok {
+		return nil, nil, fmt.Errorf("snapshot has no manifest")
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+	if manifest.FormatVersion > FORMAT_VERSION {
+		return nil, nil, fmt.Errorf("unsupported snapshot format %d", manifest.FormatVersion)
+	}
+
+	state := &managers.State{}
+	for _, entry := range manifest.Files {
+		data, ok := contents[entry.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s is missing", entry.Name)
+		}
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != entry.Size || hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return nil, nil, fmt.Errorf("checksum mismatch for %s", entry.Name)
+		}
+
+		var err error
+		switch {
+		case strings.HasPrefix(entry.Name, "tasks/"):
+			var t task.Task
+			if err = json.Unmarshal(data, &t); err == nil {
+				err = t.Unpack(m.taskManager.Keyring())
+			}
+			state.Tasks = append(state.Tasks, &t)
+		case strings.HasPrefix(entry.Name, "leases/"):
+			var lease leases.Lease
+			err = json.Unmarshal(data, &lease)
+			state.Leases = append(state.Leases, &lease)
+		case strings.HasPrefix(entry.Name, "metadata/queues/"):
+			var queue queues.Queue
+			err = json.Unmarshal(data, &queue)
+			state.Queues = append(state.Queues, &queue)
+		case entry.Name == "metadata/"+shards.SHARD_MAP_FILE:
+			state.ShardMap = &shards.ShardMap{}
+			err = json.Unmarshal(data, state.ShardMap)
+		default:
+			err = fmt.Errorf("unexpected file")
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %v", entry.Name, err)
+		}
+	}
+	if len(state.Tasks) != manifest.Tasks || len(state.Leases) != manifest.Leases || len(state.Queues) != manifest.Queues {
+		return nil, nil, fmt.Errorf("snapshot does not match its manifest counts")
+	}
+	return &manifest, state, nil
+}
+
+// rotate deletes the oldest scheduled snapshots beyond the number to keep
+func (m *Manager) rotate() error {
+	ids, err := m.ids(SCHEDULED_PREFIX)
+	if err != nil {
+		return err
+	}
+	for len(ids) > m.keep {
+		if err := os.Remove(m.path(ids[0])); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// PeriodicallyCreateSnapshots takes a scheduled snapshot every interval and
+// keeps only the newest ones
+func (m *Manager) PeriodicallyCreateSnapshots(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+		manifest, err := m.create(SCHEDULED_PREFIX)
+		if err != nil {
+			slog.Error("failed to create scheduled snapshot", "error", err)
+			continue
+		}
+		slog.Info("created snapshot", "snapshot", manifest.ID, "tasks", manifest.Tasks)
+		if err := m.rotate(); err != nil {
+			slog.Error("failed to rotate snapshots", "error", err)
+		}
+	}
+}
+
+// Stop ends scheduled snapshots and waits for a snapshot or restore in
+// progress to finish
+func (m *Manager) Stop() {
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.snapshotLock.Lock()
+	defer m.snapshotLock.Unlock()
+}
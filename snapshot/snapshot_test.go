@@ -0,0 +1,205 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/indkumar8999/ps-tasks/archive"
+	"github.com/indkumar8999/ps-tasks/managers"
+)
+
+func newTestTaskManager(t *testing.T) *managers.TaskManager {
+	t.Helper()
+	dir := t.TempDir()
+	for _, sub := range []string{"tasks", "leases", "metadata/queues"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", sub, err)
+		}
+	}
+	leaseManager, err := managers.NewLeaseManager(filepath.Join(dir, "leases"))
+	if err != nil {
+		t.Fatalf("failed to create lease manager: %v", err)
+	}
+	queueManager, err := managers.NewQueueManager(filepath.Join(dir, "metadata", "queues"))
+	if err != nil {
+		t.Fatalf("failed to create queue manager: %v", err)
+	}
+	taskArchive, err := archive.NewArchive(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	shardManager := managers.NewShardManager(filepath.Join(dir, "metadata"))
+	return managers.NewTaskManager(filepath.Join(dir, "tasks"), leaseManager, queueManager, shardManager, taskArchive)
+}
+
+func newTestManager(t *testing.T, taskManager *managers.TaskManager, keep int) *Manager {
+	t.Helper()
+	m, err := NewManager(t.TempDir(), taskManager, keep)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m
+}
+
+func TestCreateAndVerify(t *testing.T) {
+	taskManager := newTestTaskManager(t)
+	if _, err := taskManager.CreateTask("task", "", "default", []byte("input"), nil); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	m := newTestManager(t, taskManager, 0)
+
+	manifest, err := m.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(manifest.ID, MANUAL_PREFIX) || manifest.Tasks != 1 || len(manifest.Files) != 1 {
+		t.Errorf("manifest = %+v, want one task file", manifest)
+	}
+	if _, err := m.Verify(manifest.ID); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+
+	// Snapshots taken back to back get their own IDs
+	second, err := m.Create()
+	if err != nil {
+		t.Fatalf("second Create: %v", err)
+	}
+	if second.ID == manifest.ID {
+		t.Errorf("two snapshots share the ID %s", manifest.ID)
+	}
+	if manifests, err := m.List(); err != nil || len(manifests) != 2 {
+		t.Errorf("List = %d snapshots, %v, want 2", len(manifests), err)
+	}
+}
+
+func TestVerifyDetectsChecksumMismatch(t *testing.T) {
+	taskManager := newTestTaskManager(t)
+	if _, err := taskManager.CreateTask("task", "", "default", []byte("input"), nil); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	m := newTestManager(t, taskManager, 0)
+	manifest, err := m.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Rewrite the archive with a changed task record and the original manifest
+	path := m.path(manifest.ID)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(original))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var rewritten bytes.Buffer
+	gzw := gzip.NewWriter(&rewritten)
+	tw := tar.NewWriter(gzw)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		if strings.HasPrefix(header.Name, "tasks/") {
+			data = bytes.Replace(data, []byte(`"task"`), []byte(`"tampered"`), 1)
+			header.Size = int64(len(data))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+	}
+	tw.Close()
+	gzw.Close()
+	if err := os.WriteFile(path, rewritten.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if _, err := m.Verify(manifest.ID); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Verify error = %v, want a checksum mismatch", err)
+	}
+	if _, err := m.Restore(manifest.ID); err == nil {
+		t.Errorf("restoring a corrupted snapshot succeeded")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	taskManager := newTestTaskManager(t)
+	kept, err := taskManager.CreateTask("kept", "", "default", []byte("input"), nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	m := newTestManager(t, taskManager, 0)
+	manifest, err := m.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	later, err := taskManager.CreateTask("later", "", "default", nil, nil)
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	if _, err := m.Restore(manifest.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	restored, err := taskManager.GetTask(kept.ID)
+	if err != nil || string(restored.Input) != "input" {
+		t.Errorf("restored task = %+v, %v, want the snapshotted task", restored, err)
+	}
+	if _, err := taskManager.GetTask(later.ID); err == nil {
+		t.Errorf("task created after the snapshot survived the restore")
+	}
+	if _, err := m.Restore("../" + manifest.ID); err == nil {
+		t.Errorf("restoring a path outside the snapshots directory succeeded")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	m := newTestManager(t, newTestTaskManager(t), 2)
+	manual, err := m.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	var scheduled []string
+	for i := 0; i < 4; i++ {
+		manifest, err := m.create(SCHEDULED_PREFIX)
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		scheduled = append(scheduled, manifest.ID)
+	}
+
+	if err := m.rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	ids, err := m.ids("")
+	if err != nil {
+		t.Fatalf("ids: %v", err)
+	}
+	want := map[string]bool{manual.ID: true, scheduled[2]: true, scheduled[3]: true}
+	if len(ids) != len(want) {
+		t.Fatalf("snapshots after rotation = %v, want the manual one and the 2 newest scheduled ones", ids)
+	}
+	for _, id := range ids {
+		if !want[id] {
+			t.Errorf("snapshot %s was kept", id)
+		}
+	}
+}
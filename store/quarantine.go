@@ -0,0 +1,87 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// QuarantinedFile is a file moved out of the way because it could not be read
+type QuarantinedFile struct {
+	Kind          string `json:"kind"`
+	File          string `json:"file"`
+	QuarantinedTo string `json:"quarantined_to"`
+	Error         string `json:"error"`
+}
+
+// QuarantineReport lists the files quarantined during one run
+type QuarantineReport struct {
+	CreatedAt string            `json:"created_at"`
+	Files     []QuarantinedFile `json:"files"`
+}
+
+// Quarantine moves unreadable records into <quarantineDir>/<kind>/ so the
+// server can start without them, and keeps a report of what was moved
+type Quarantine struct {
+	quarantineDir  string
+	files          []QuarantinedFile
+	quarantineLock *sync.Mutex
+}
+
+// NewQuarantine creates a new Quarantine
+func NewQuarantine(quarantineDir string) *Quarantine {
+	return &Quarantine{
+		quarantineDir:  quarantineDir,
+		quarantineLock: &sync.Mutex{},
+	}
+}
+
+// Add moves a file into the quarantine
+func (q *Quarantine) Add(kind string, path string, cause error) error {
+	q.quarantineLock.Lock()
+	defer q.quarantineLock.Unlock()
+
+	dir := filepath.Join(q.quarantineDir, kind)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %v", err)
+	}
+	target := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Stat(target); err == nil {
+		target = fmt.Sprintf("%s.%d", target, time.Now().UnixNano())
+	}
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Errorf("failed to quarantine %s: %v", path, err)
+	}
+
+	q.files = append(q.files, QuarantinedFile{Kind: kind, File: path, QuarantinedTo: target, Error: cause.Error()})
+	return nil
+}
+
+// Files returns the files quarantined so far
+func (q *Quarantine) Files() []QuarantinedFile {
+	q.quarantineLock.Lock()
+	defer q.quarantineLock.Unlock()
+	return append([]QuarantinedFile{}, q.files...)
+}
+
+// WriteReport saves the list of quarantined files as
+// <quarantineDir>/report-<time>.json and returns its path. Nothing is
+// written if no file was quarantined.
+func (q *Quarantine) WriteReport() (string, error) {
+	files := q.Files()
+	if len(files) == 0 {
+		return "", nil
+	}
+	now := time.Now().UTC()
+	if err := os.MkdirAll(q.quarantineDir, 0755); err != nil {
+		return "", err
+	}
+	id := "report-" + now.Format("20060102T150405Z")
+	report := QuarantineReport{CreatedAt: now.Format(time.RFC3339), Files: files}
+	if err := Save(q.quarantineDir, id, report); err != nil {
+		return "", err
+	}
+	return Path(q.quarantineDir, id), nil
+}
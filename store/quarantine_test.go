@@ -0,0 +1,49 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteReport(t *testing.T) {
+	dir := t.TempDir()
+	quarantine := NewQuarantine(filepath.Join(dir, "quarantine"))
+
+	path, err := quarantine.WriteReport()
+	if err != nil || path != "" {
+		t.Fatalf("WriteReport with nothing quarantined = %q, %v, want no report", path, err)
+	}
+
+	// Files with the same name are kept apart
+	for _, sub := range []string{"one", "two"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, sub), "a.json", "{")
+		if err := quarantine.Add("tasks", filepath.Join(dir, sub, "a.json"), os.ErrInvalid); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	files := quarantine.Files()
+	if len(files) != 2 || files[0].QuarantinedTo == files[1].QuarantinedTo {
+		t.Fatalf("quarantined files = %+v, want two separate files", files)
+	}
+
+	path, err = quarantine.WriteReport()
+	if err != nil {
+		t.Fatalf("WriteReport: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the report: %v", err)
+	}
+	var report QuarantineReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decoding the report: %v", err)
+	}
+	if report.CreatedAt == "" || len(report.Files) != 2 || report.Files[0].Error != os.ErrInvalid.Error() {
+		t.Errorf("report = %+v, want two files and the cause", report)
+	}
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/indkumar8999/ps-tasks/metrics"
+)
+
+// RECORD_EXT is the extension of every record file
+const RECORD_EXT = ".json"
+
+// TMP_EXT marks a record that is still being written
+const TMP_EXT = ".tmp"
+
+// FileName returns the name of the file a record is stored in. IDs that
+// already end in the record extension are accepted, so callers that pass a
+// file name instead of an ID still find the right file.
+func FileName(id string) string {
+	return strings.TrimSuffix(id, RECORD_EXT) + RECORD_EXT
+}
+
+// ValidID reports whether id can name a record file. IDs that are empty,
+// contain a path separator or name a directory such as ".." are rejected,
+// so an ID from a request cannot reach files outside the directory.
+func ValidID(id string) bool {
+	name := strings.TrimSuffix(id, RECORD_EXT)
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+	return !strings.ContainsAny(id, "/\\\x00")
+}
+
+// invalidID is returned for IDs ValidID rejects. Reads and removes report
+// them as missing records.
+func invalidID(op string, id string) error {
+	return &os.PathError{Op: op, Path: id, Err: os.ErrNotExist}
+}
+
+// Path returns the path of a record in a directory
+func Path(dir string, id string) string {
+	return filepath.Join(dir, FileName(id))
+}
+
+// RecordID returns the ID stored in a file name. It reports false for files
+// that are not records, such as unfinished writes.
+func RecordID(fileName string) (string, bool) {
+	if !strings.HasSuffix(fileName, RECORD_EXT) {
+		return "", false
+	}
+	// Strip every extension, so files saved as <id>.json.json are found too
+	id := fileName
+	for strings.HasSuffix(id, RECORD_EXT) {
+		id = strings.TrimSuffix(id, RECORD_EXT)
+	}
+	if id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// Save writes a record. The record is written to a temporary file, synced
+// and renamed over the old one, so a crash never leaves a half written record.
+// The directory is synced too, so the rename itself survives a crash.
+// Writes are measured by the name of the directory, such as "tasks".
+func Save(dir string, id string, v interface{}) error {
+	if id == "" {
+		return fmt.Errorf("record has no ID")
+	}
+	if !ValidID(id) {
+		return fmt.Errorf("invalid record ID %q", id)
+	}
+	kind := filepath.Base(dir)
+	defer metrics.ObserveWrite(kind, time.Now())
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	path := Path(dir, id)
+	tmpFile := path + TMP_EXT
+	file, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	metrics.PersistenceFsyncs.WithLabelValues(kind).Inc()
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := os.Rename(tmpFile, path); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	metrics.PersistenceFsyncs.WithLabelValues(kind).Inc()
+	return SyncDir(dir)
+}
+
+// SyncDir syncs a directory, so files renamed into it survive a crash
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Load reads a record
+func Load(dir string, id string, v interface{}) error {
+	if !ValidID(id) {
+		return invalidID("open", id)
+	}
+	data, err := os.ReadFile(Path(dir, id))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Remove deletes a record
+func Remove(dir string, id string) error {
+	if !ValidID(id) {
+		return invalidID("remove", id)
+	}
+	return os.Remove(Path(dir, id))
+}
+
+// List returns the IDs of the records in a directory
+func List(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if id, ok := RecordID(file.Name()); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// LoadAll reads every record in a directory. Leftover temporary files from
+// interrupted writes are removed. Records stored under a name that does not
+// match their ID are moved to the right name. Records that cannot be read
+// are moved into the quarantine, or fail the load if quarantine is nil.
+func LoadAll[T any](dir string, kind string, quarantine *Quarantine, recordID func(*T) string) ([]*T, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*T
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		if strings.HasSuffix(file.Name(), TMP_EXT) {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		fileID, ok := RecordID(file.Name())
+		if !ok {
+			continue
+		}
+
+		record := new(T)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, record)
+		}
+		if err == nil && recordID(record) == "" {
+			err = fmt.Errorf("record has no ID")
+		}
+		if err != nil {
+			if quarantine == nil {
+				return nil, fmt.Errorf("failed to load %s: %v", path, err)
+			}
+			if err := quarantine.Add(kind, path, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		id := recordID(record)
+		if fileID != id || file.Name() != FileName(id) {
+			if _, err := os.Stat(Path(dir, id)); err == nil {
+				// The record is already stored under the right name
+				if quarantine == nil {
+					return nil, fmt.Errorf("%s duplicates %s", path, FileName(id))
+				}
+				if err := quarantine.Add(kind, path, fmt.Errorf("duplicate of %s", FileName(id))); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := os.Rename(path, Path(dir, id)); err != nil {
+				return nil, err
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
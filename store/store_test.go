@@ -0,0 +1,291 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+}
+
+func recordID(r *record) string {
+	return r.ID
+}
+
+func writeFile(t *testing.T, dir string, name string, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fileNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{id: "abc", want: "abc.json"},
+		{id: "abc.json", want: "abc.json"},
+		{id: "abc.json.json", want: "abc.json.json"},
+		{id: "abc.tmp", want: "abc.tmp.json"},
+		{id: "", want: ".json"},
+	}
+	for _, tt := range tests {
+		if got := FileName(tt.id); got != tt.want {
+			t.Errorf("FileName(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestRecordID(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     string
+		ok       bool
+	}{
+		{fileName: "abc.json", want: "abc", ok: true},
+		{fileName: "abc.json.json", want: "abc", ok: true},
+		{fileName: "abc.json.tmp", ok: false},
+		{fileName: "abc", ok: false},
+		{fileName: ".json", ok: false},
+		{fileName: ".json.json", ok: false},
+		{fileName: "", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := RecordID(tt.fileName)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("RecordID(%q) = %q, %v, want %q, %v", tt.fileName, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "abc", want: true},
+		{id: "abc.json", want: true},
+		{id: "a..b", want: true},
+		{id: "", want: false},
+		{id: ".json", want: false},
+		{id: ".", want: false},
+		{id: "..", want: false},
+		{id: "../abc", want: false},
+		{id: "a/b", want: false},
+		{id: `a\b`, want: false},
+		{id: "a\x00b", want: false},
+	}
+	for _, tt := range tests {
+		if got := ValidID(tt.id); got != tt.want {
+			t.Errorf("ValidID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidIDsDoNotReachTheFilesystem(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "records")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, parent, "secret.json", `{"id":"secret","value":"outside"}`)
+
+	var loaded record
+	if err := Load(dir, "../secret", &loaded); !os.IsNotExist(err) {
+		t.Errorf("Load outside the directory error = %v, want not found", err)
+	}
+	if err := Save(dir, "../secret", &record{ID: "secret", Value: "overwritten"}); err == nil {
+		t.Errorf("Save outside the directory succeeded")
+	}
+	if err := Remove(dir, "../secret"); !os.IsNotExist(err) {
+		t.Errorf("Remove outside the directory error = %v, want not found", err)
+	}
+	if names := fileNames(t, parent); len(names) != 1 || names[0] != "secret.json" {
+		t.Errorf("files outside the directory = %v, want [secret.json]", names)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := Save(dir, "abc", &record{ID: "abc", Value: "first"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := Save(dir, "abc.json", &record{ID: "abc", Value: "second"}); err != nil {
+		t.Fatalf("Save with the extension: %v", err)
+	}
+	if err := Save(dir, "", &record{}); err == nil {
+		t.Errorf("Save without an ID succeeded")
+	}
+
+	var loaded record
+	if err := Load(dir, "abc", &loaded); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Value != "second" {
+		t.Errorf("Value = %q, want %q", loaded.Value, "second")
+	}
+	if names := fileNames(t, dir); len(names) != 1 || names[0] != "abc.json" {
+		t.Errorf("files = %v, want [abc.json]", names)
+	}
+	ids, err := List(dir)
+	if err != nil || len(ids) != 1 || ids[0] != "abc" {
+		t.Errorf("List = %v, %v, want [abc]", ids, err)
+	}
+	if err := Remove(dir, "abc"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if names := fileNames(t, dir); len(names) != 0 {
+		t.Errorf("files after Remove = %v, want none", names)
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are written to the directory before loading
+		files map[string]string
+		// quarantined says whether a quarantine is passed
+		quarantined bool
+		wantIDs     []string
+		wantFiles   []string
+		// wantQuarantined are the names of the files quarantined
+		wantQuarantined []string
+		wantErr         bool
+	}{
+		{
+			name:      "records",
+			files:     map[string]string{"a.json": `{"id":"a"}`, "b.json": `{"id":"b"}`},
+			wantIDs:   []string{"a", "b"},
+			wantFiles: []string{"a.json", "b.json"},
+		},
+		{
+			name:      "leftover temporary files are removed",
+			files:     map[string]string{"a.json": `{"id":"a"}`, "b.json.tmp": `{"id":`},
+			wantIDs:   []string{"a"},
+			wantFiles: []string{"a.json"},
+		},
+		{
+			name:      "files that are not records are ignored",
+			files:     map[string]string{"a.json": `{"id":"a"}`, "README": "notes"},
+			wantIDs:   []string{"a"},
+			wantFiles: []string{"README", "a.json"},
+		},
+		{
+			name:      "double extension is renamed",
+			files:     map[string]string{"a.json.json": `{"id":"a"}`},
+			wantIDs:   []string{"a"},
+			wantFiles: []string{"a.json"},
+		},
+		{
+			name:      "misnamed record is renamed to its ID",
+			files:     map[string]string{"old.json": `{"id":"a"}`},
+			wantIDs:   []string{"a"},
+			wantFiles: []string{"a.json"},
+		},
+		{
+			name:            "duplicate is quarantined",
+			files:           map[string]string{"a.json": `{"id":"a","value":"kept"}`, "a.json.json": `{"id":"a","value":"duplicate"}`},
+			quarantined:     true,
+			wantIDs:         []string{"a"},
+			wantFiles:       []string{"a.json"},
+			wantQuarantined: []string{"a.json.json"},
+		},
+		{
+			name:    "duplicate fails without a quarantine",
+			files:   map[string]string{"a.json": `{"id":"a"}`, "a.json.json": `{"id":"a"}`},
+			wantErr: true,
+		},
+		{
+			name:            "unreadable records are quarantined",
+			files:           map[string]string{"a.json": `{"id":"a"}`, "b.json": `{"id":`, "c.json": `{"value":"no id"}`},
+			quarantined:     true,
+			wantIDs:         []string{"a"},
+			wantFiles:       []string{"a.json"},
+			wantQuarantined: []string{"b.json", "c.json"},
+		},
+		{
+			name:    "unreadable record fails without a quarantine",
+			files:   map[string]string{"b.json": `{"id":`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "records")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, data := range tt.files {
+				writeFile(t, dir, name, data)
+			}
+			var quarantine *Quarantine
+			if tt.quarantined {
+				quarantine = NewQuarantine(filepath.Join(t.TempDir(), "quarantine"))
+			}
+
+			records, err := LoadAll(dir, "records", quarantine, recordID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadAll succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAll: %v", err)
+			}
+			var ids []string
+			for _, r := range records {
+				ids = append(ids, r.ID)
+				if r.Value == "duplicate" {
+					t.Errorf("loaded the duplicate instead of the record under its own name")
+				}
+			}
+			sort.Strings(ids)
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("IDs = %v, want %v", ids, tt.wantIDs)
+			}
+			if names := fileNames(t, dir); strings.Join(names, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("files = %v, want %v", names, tt.wantFiles)
+			}
+			if quarantine == nil {
+				return
+			}
+			var quarantined []string
+			for _, file := range quarantine.Files() {
+				quarantined = append(quarantined, filepath.Base(file.File))
+				if file.Kind != "records" || file.Error == "" {
+					t.Errorf("quarantined file = %+v, want kind records and an error", file)
+				}
+				if _, err := os.Stat(file.QuarantinedTo); err != nil {
+					t.Errorf("quarantined file is missing: %v", err)
+				}
+			}
+			sort.Strings(quarantined)
+			if strings.Join(quarantined, ",") != strings.Join(tt.wantQuarantined, ",") {
+				t.Errorf("quarantined = %v, want %v", quarantined, tt.wantQuarantined)
+			}
+		})
+	}
+}
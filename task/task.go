@@ -3,24 +3,83 @@ package task
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"github.com/indkumar8999/ps-tasks/codec"
+	"github.com/indkumar8999/ps-tasks/keyring"
+	"github.com/indkumar8999/ps-tasks/store"
 )
 
+// TaskError describes why a task failed
+type TaskError struct {
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Progress is the latest progress reported by the worker holding the task
+type Progress struct {
+	Percent     int32  `json:"percent"`
+	CurrentStep int64  `json:"current_step"`
+	TotalSteps  int64  `json:"total_steps"`
+	Message     string `json:"message"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// BlobRef points at a payload kept in the blob store instead of the record
+type BlobRef struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// SCHEMA_VERSION is the version of the task record written by Save.
+// Older records are upgraded by the migrations in the migrate package.
+const SCHEMA_VERSION = 1
+
 type Task struct {
+	SchemaVersion int `json:"schema_version"`
 	ID string `json:"id"`
 	Name string `json:"name"`
 	Description string `json:"description"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 	State string `json:"state"`
+	// StateChangedAt is when the task entered its state; it is empty for
+	// tasks stored before it was recorded
+	StateChangedAt string `json:"state_changed_at,omitempty"`
+	Queue string `json:"queue"`
+	// Tenant owns the task; it is empty for tasks created outside tenants
+	Tenant string `json:"tenant,omitempty"`
 	Data []byte `json:"data"`
+	Input []byte `json:"input"`
+	Result []byte `json:"result,omitempty"`
+	InputBlob *BlobRef `json:"input_blob,omitempty"`
+	ResultBlob *BlobRef `json:"result_blob,omitempty"`
+	Error *TaskError `json:"error,omitempty"`
+	Progress *Progress `json:"progress,omitempty"`
+	LastHeartbeat string `json:"last_heartbeat,omitempty"`
+	CancelRequested bool `json:"cancel_requested,omitempty"`
+	Metadata map[string]string `json:"metadata"`
+	// TraceContext is the W3C trace context the task was created in
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+	// Compression is the codec the stored payload is compressed with
+	Compression string `json:"compression,omitempty"`
+	// Compressed holds Data, Input, Result and Metadata when the task is
+	// stored compressed, and Sealed when it is stored encrypted. They are
+	// only set on disk; loaded tasks are unpacked.
+	Compressed []byte `json:"compressed,omitempty"`
+	Sealed *keyring.Envelope `json:"sealed,omitempty"`
+}
+
+// payload is the part of a task that is compressed and encrypted at rest
+type payload struct {
+	Data []byte `json:"data"`
+	Input []byte `json:"input"`
+	Result []byte `json:"result,omitempty"`
 	Metadata map[string]string `json:"metadata"`
 }
 
 
 func NewTask(id string, name string, description string,
-	createdAt string, updatedAt string, state string, data []byte,
+	createdAt string, updatedAt string, state string, input []byte,
 	metadata map[string]string) *Task {
 	return &Task{
 		ID: id,
@@ -29,7 +88,7 @@ func NewTask(id string, name string, description string,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
 		State: state,
-		Data: data,
+		Input: input,
 		Metadata: metadata,
 	}
 }
@@ -58,42 +117,148 @@ func (t *Task) GetState() string {
 	return t.State
 }
 
+func (t *Task) GetQueue() string {
+	return t.Queue
+}
+
 func (t *Task) GetData() []byte {
 	return t.Data
 }
 
+func (t *Task) GetInput() []byte {
+	return t.Input
+}
+
+func (t *Task) GetResult() []byte {
+	return t.Result
+}
+
+func (t *Task) GetError() *TaskError {
+	return t.Error
+}
+
+func (t *Task) GetProgress() *Progress {
+	return t.Progress
+}
+
+func (t *Task) GetLastHeartbeat() string {
+	return t.LastHeartbeat
+}
+
+func (t *Task) IsCancelRequested() bool {
+	return t.CancelRequested
+}
+
 func (t *Task) GetMetadata() map[string]string {
 	return t.Metadata
 }
+// isPacked reports whether the payload is compressed or sealed
+func (t *Task) isPacked() bool {
+	return t.Sealed != nil || t.Compressed != nil
+}
 
-func (t *Task) Save(taskDir string) error {
-	// Implement the logic to save the task to a file
-	
-	// For example, you can use JSON encoding to save the task to a file
-	taskFile := filepath.Join(taskDir, fmt.Sprintf("%s.json", t.ID))
-	file, err := os.Create(taskFile)
+// Pack returns a copy of the task as it is stored: the payload is
+// compressed with the given codec, then encrypted with the keyring's primary
+// key if keys is set. The task ID is bound to the ciphertext, so a sealed
+// payload cannot be moved to another task. Without either the task itself
+// is returned.
+func (t *Task) Pack(keys *keyring.Keyring, compression string) (*Task, error) {
+	if t.isPacked() || (keys == nil && compression == codec.NONE) {
+		return t, nil
+	}
+	packed, err := json.Marshal(&payload{Data: t.Data, Input: t.Input, Result: t.Result, Metadata: t.Metadata})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer file.Close()
-	// Serialize the task to JSON and write to the file
-	if err := json.NewEncoder(file).Encode(t); err != nil {
-		return err
+	if packed, err = codec.Compress(compression, packed); err != nil {
+		return nil, fmt.Errorf("failed to compress task %s: %v", t.ID, err)
+	}
+
+	stored := *t
+	stored.Data = nil
+	stored.Input = nil
+	stored.Result = nil
+	stored.Metadata = nil
+	stored.Compression = compression
+	if keys == nil {
+		stored.Compressed = packed
+		return &stored, nil
+	}
+	if stored.Sealed, err = keys.Seal(packed, []byte(t.ID)); err != nil {
+		return nil, fmt.Errorf("failed to seal task %s: %v", t.ID, err)
+	}
+	return &stored, nil
+}
+
+// Unpack decrypts and decompresses a stored task in place. Tasks stored as
+// they are are left untouched.
+func (t *Task) Unpack(keys *keyring.Keyring) error {
+	if !t.isPacked() {
+		return nil
+	}
+	packed := t.Compressed
+	if t.Sealed != nil {
+		if keys == nil {
+			return fmt.Errorf("task %s is encrypted with key %s but no keyring is configured", t.ID, t.Sealed.KeyID)
+		}
+		var err error
+		if packed, err = keys.Open(t.Sealed, []byte(t.ID)); err != nil {
+			return fmt.Errorf("failed to open task %s: %v", t.ID, err)
+		}
+	}
+	plaintext, err := codec.Decompress(t.Compression, packed)
+	if err != nil {
+		return fmt.Errorf("failed to decompress task %s: %v", t.ID, err)
 	}
+	var p payload
+	if err := json.Unmarshal(plaintext, &p); err != nil {
+		return fmt.Errorf("failed to decode task %s: %v", t.ID, err)
+	}
+	t.Data = p.Data
+	t.Input = p.Input
+	t.Result = p.Result
+	t.Metadata = p.Metadata
+	t.Compression = codec.NONE
+	t.Compressed = nil
+	t.Sealed = nil
 	return nil
 }
 
-func LoadTask(taskDir string, taskID string) (*Task, error) {
-	// Implement the logic to load the task from a file
-	taskFile := filepath.Join(taskDir, fmt.Sprintf("%s", taskID))
-	file, err := os.Open(taskFile)
+// Save saves the task to <taskDir>/<id>.json, packed with the given keyring
+// and compression codec
+func (t *Task) Save(taskDir string, keys *keyring.Keyring, compression string) error {
+	t.SchemaVersion = SCHEMA_VERSION
+	record, err := t.Pack(keys, compression)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer file.Close()
+	return store.Save(taskDir, t.ID, record)
+}
+
+// LoadTask loads a task from the tasks directory and unpacks it. The ID may be
+// given with or without the .json extension.
+func LoadTask(taskDir string, taskID string, keys *keyring.Keyring) (*Task, error) {
 	var task Task
-	if err := json.NewDecoder(file).Decode(&task); err != nil {
+	if err := store.Load(taskDir, taskID, &task); err != nil {
+		return nil, err
+	}
+	if err := task.Unpack(keys); err != nil {
 		return nil, err
 	}
 	return &task, nil
-}
\ No newline at end of file
+}
+
+// StoredKeyID returns the ID of the key a stored task is sealed with, or ""
+// if it is stored in plain text
+func StoredKeyID(taskDir string, taskID string) (string, error) {
+	var stored struct {
+		Sealed *keyring.Envelope `json:"sealed"`
+	}
+	if err := store.Load(taskDir, taskID, &stored); err != nil {
+		return "", err
+	}
+	if stored.Sealed == nil {
+		return "", nil
+	}
+	return stored.Sealed.KeyID, nil
+}
@@ -0,0 +1,84 @@
+package task
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/indkumar8999/ps-tasks/codec"
+	"github.com/indkumar8999/ps-tasks/keyring"
+)
+
+func TestSaveAndLoadTask(t *testing.T) {
+	keysPath := filepath.Join(t.TempDir(), "keyring.json")
+	if _, err := keyring.AddKey(keysPath, "k1"); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	keys, err := keyring.Load(keysPath)
+	if err != nil {
+		t.Fatalf("Load keyring: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		keys        *keyring.Keyring
+		compression string
+		// loadID is the ID LoadTask is called with
+		loadID    string
+		wantKeyID string
+	}{
+		{name: "plain", compression: codec.NONE, loadID: "t1"},
+		{name: "plain loaded by file name", compression: codec.NONE, loadID: "t1.json"},
+		{name: "compressed", compression: codec.GZIP, loadID: "t1"},
+		{name: "sealed", keys: keys, compression: codec.NONE, loadID: "t1", wantKeyID: "k1"},
+		{name: "compressed and sealed", keys: keys, compression: codec.ZSTD, loadID: "t1.json", wantKeyID: "k1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			original := &Task{
+				ID:       "t1",
+				Name:     "task",
+				Queue:    "default",
+				Data:     []byte("data"),
+				Input:    []byte("secret input"),
+				Result:   []byte("result"),
+				Metadata: map[string]string{"key": "value"},
+			}
+			if err := original.Save(dir, tt.keys, tt.compression); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if original.SchemaVersion != SCHEMA_VERSION {
+				t.Errorf("SchemaVersion = %d, want %d", original.SchemaVersion, SCHEMA_VERSION)
+			}
+
+			stored, err := os.ReadFile(filepath.Join(dir, "t1.json"))
+			if err != nil {
+				t.Fatalf("reading the record: %v", err)
+			}
+			packed := tt.keys != nil || tt.compression != codec.NONE
+			if packed && bytes.Contains(stored, []byte("value")) {
+				t.Errorf("packed record holds its metadata as is: %s", stored)
+			}
+			keyID, err := StoredKeyID(dir, "t1")
+			if err != nil || keyID != tt.wantKeyID {
+				t.Errorf("StoredKeyID = %q, %v, want %q", keyID, err, tt.wantKeyID)
+			}
+
+			loaded, err := LoadTask(dir, tt.loadID, tt.keys)
+			if err != nil {
+				t.Fatalf("LoadTask: %v", err)
+			}
+			if loaded.ID != "t1" || string(loaded.Input) != "secret input" || string(loaded.Result) != "result" ||
+				string(loaded.Data) != "data" || loaded.Metadata["key"] != "value" {
+				t.Errorf("loaded task = %+v, want the saved one", loaded)
+			}
+			if tt.keys != nil {
+				if _, err := LoadTask(dir, "t1", nil); err == nil {
+					t.Errorf("loading a sealed task without the keyring succeeded")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package tenants
+
+import (
+	"fmt"
+
+	"github.com/indkumar8999/ps-tasks/queues"
+)
+
+// Quota limits what a tenant can store and how fast it can create tasks.
+// Zero values are unlimited.
+type Quota struct {
+	// MaxPendingTasks bounds the tasks that are not completed, failed or aborted
+	MaxPendingTasks int `json:"max_pending_tasks,omitempty"`
+	// MaxPayloadBytes bounds the data, input and result bytes of all tasks,
+	// including their blobs
+	MaxPayloadBytes int64 `json:"max_payload_bytes,omitempty"`
+	// CreateRate is how many tasks can be created per second on average,
+	// with bursts of up to CreateBurst
+	CreateRate  float64 `json:"create_rate,omitempty"`
+	CreateBurst int     `json:"create_burst,omitempty"`
+}
+
+// Tenant is a group of principals whose tasks, leases and queues are kept
+// apart from other tenants
+type Tenant struct {
+	Name       string   `json:"name"`
+	Principals []string `json:"principals"`
+	Quota      Quota    `json:"quota"`
+}
+
+// Validate checks that tenants have usable names and that no principal is in
+// two of them. A tenant's name is also the queue its tasks go to when they
+// do not name one.
+func Validate(list []Tenant) error {
+	names := make(map[string]bool)
+	members := make(map[string]string)
+	for _, tenant := range list {
+		if err := queues.ValidateName(tenant.Name); err != nil {
+			return fmt.Errorf("invalid tenant name %q", tenant.Name)
+		}
+		if names[tenant.Name] {
+			return fmt.Errorf("tenant %s is listed twice", tenant.Name)
+		}
+		names[tenant.Name] = true
+		for _, principal := range tenant.Principals {
+			if other, exists := members[principal]; exists {
+				return fmt.Errorf("%s is a member of tenants %s and %s", principal, other, tenant.Name)
+			}
+			members[principal] = tenant.Name
+		}
+	}
+	return nil
+}
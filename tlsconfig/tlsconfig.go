@@ -0,0 +1,253 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// DEFAULT_RELOAD_INTERVAL is how often certificate files are checked for changes
+const DEFAULT_RELOAD_INTERVAL = 30 * time.Second
+
+// ServerOptions configures TLS for the gRPC server
+type ServerOptions struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile is a PEM bundle of the CAs client certificates must be
+	// signed by. Setting it enables mutual TLS.
+	ClientCAFile string
+	// ClientCertOptional accepts clients without a certificate, while still
+	// verifying the certificates that are presented
+	ClientCertOptional bool
+	// ReloadInterval defaults to DEFAULT_RELOAD_INTERVAL
+	ReloadInterval time.Duration
+}
+
+// ClientOptions configures TLS for connections to a server
+type ClientOptions struct {
+	// CAFile is a PEM bundle of the CAs the server certificate must be
+	// signed by. The system roots are used when it is empty.
+	CAFile string
+	// CertFile and KeyFile are the client certificate for mutual TLS
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name checked against the server certificate
+	ServerName string
+	// ReloadInterval defaults to DEFAULT_RELOAD_INTERVAL
+	ReloadInterval time.Duration
+}
+
+// Reloader holds a certificate and a CA pool loaded from files, and loads
+// them again when any of the files changes. A change that fails to load is
+// reported and the previous files stay in use.
+type Reloader struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	cert       *tls.Certificate
+	pool       *x509.CertPool
+	modTimes   map[string]time.Time
+	reloadLock *sync.RWMutex
+	stop       chan struct{}
+	stopOnce   *sync.Once
+}
+
+// NewReloader loads the given files. Empty file names are skipped.
+func NewReloader(certFile string, keyFile string, caFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("a certificate and its key must be given together")
+	}
+	r := &Reloader{
+		certFile:   certFile,
+		keyFile:    keyFile,
+		caFile:     caFile,
+		modTimes:   make(map[string]time.Time),
+		reloadLock: &sync.RWMutex{},
+		stop:       make(chan struct{}),
+		stopOnce:   &sync.Once{},
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) files() []string {
+	var files []string
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Reload loads the files again if any of them changed since the last load,
+// and reports whether they did
+func (r *Reloader) Reload() (bool, error) {
+	r.reloadLock.RLock()
+	previous := r.modTimes
+	r.reloadLock.RUnlock()
+
+	modTimes := make(map[string]time.Time)
+	changed := false
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		modTimes[file] = info.ModTime()
+		if !info.ModTime().Equal(previous[file]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	// Files that fail to load are reported once and tried again when they
+	// next change
+	r.reloadLock.Lock()
+	r.modTimes = modTimes
+	r.reloadLock.Unlock()
+	if err := r.load(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// load reads the files and replaces the certificate and CA pool
+func (r *Reloader) load() error {
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		loaded, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %v", err)
+		}
+		cert = &loaded
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		data, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in %s", r.caFile)
+		}
+	}
+
+	r.reloadLock.Lock()
+	defer r.reloadLock.Unlock()
+	r.cert = cert
+	r.pool = pool
+	return nil
+}
+
+// Certificate returns the current certificate
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.reloadLock.RLock()
+	defer r.reloadLock.RUnlock()
+	return r.cert
+}
+
+// CAPool returns the current CA pool
+func (r *Reloader) CAPool() *x509.CertPool {
+	r.reloadLock.RLock()
+	defer r.reloadLock.RUnlock()
+	return r.pool
+}
+
+// Run checks the files every interval until Stop is called
+func (r *Reloader) Run(interval time.Duration) {
+	if interval <= 0 {
+		interval = DEFAULT_RELOAD_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			changed, err := r.Reload()
+			if err != nil {
+				slog.Error("failed to reload certificates", "cert", r.certFile, "error", err)
+				continue
+			}
+			if changed {
+				slog.Info("reloaded certificates", "cert", r.certFile)
+			}
+		}
+	}
+}
+
+// Stop ends Run
+func (r *Reloader) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// ServerConfig returns a TLS configuration for a server whose certificate
+// and client CAs are reloaded in the background. New connections use the
+// files as of their handshake.
+func ServerConfig(opts ServerOptions) (*tls.Config, *Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, nil, fmt.Errorf("a server certificate and key are required")
+	}
+	r, err := NewReloader(opts.CertFile, opts.KeyFile, opts.ClientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// gRPC clients require the h2 protocol to be negotiated, and the config
+	// returned per handshake replaces the one gRPC adds it to
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2"},
+				Certificates: []tls.Certificate{*r.Certificate()},
+			}
+			if opts.ClientCAFile != "" {
+				config.ClientCAs = r.CAPool()
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+				if opts.ClientCertOptional {
+					config.ClientAuth = tls.VerifyClientCertIfGiven
+				}
+			}
+			return config, nil
+		},
+	}
+	go r.Run(opts.ReloadInterval)
+	return config, r, nil
+}
+
+// ClientConfig returns a TLS configuration for connecting to a server. The
+// client certificate is reloaded in the background; the CA bundle is read
+// once.
+func ClientConfig(opts ClientOptions) (*tls.Config, *Reloader, error) {
+	r, err := NewReloader(opts.CertFile, opts.KeyFile, opts.CAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+		RootCAs:    r.CAPool(),
+	}
+	if opts.CertFile != "" {
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.Certificate(), nil
+		}
+	}
+	go r.Run(opts.ReloadInterval)
+	return config, r, nil
+}
@@ -0,0 +1,256 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	writePEM(t, ca.path("ca.pem"), "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+// issue writes a certificate for commonName and its key as name.pem and
+// name-key.pem
+func (ca *testCA) issue(t *testing.T, name string, commonName string, serial int64) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+	certFile, keyFile := ca.path(name+".pem"), ca.path(name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+// handshake connects a client to a server over loopback and returns the
+// certificate each side verified, or the first error either side saw
+func handshake(server *tls.Config, client *tls.Config) (*x509.Certificate, *x509.Certificate, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer listener.Close()
+
+	type result struct {
+		clientCert *x509.Certificate
+		err        error
+	}
+	serverDone := make(chan result, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverDone <- result{err: err}
+			return
+		}
+		defer conn.Close()
+		serverTLS := tls.Server(conn, server)
+		if err := serverTLS.Handshake(); err != nil {
+			serverDone <- result{err: err}
+			return
+		}
+		var clientCert *x509.Certificate
+		if certs := serverTLS.ConnectionState().PeerCertificates; len(certs) > 0 {
+			clientCert = certs[0]
+		}
+		serverDone <- result{clientCert: clientCert}
+	}()
+
+	conn, err := net.DialTimeout("tcp", listener.Addr().String(), 5*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	clientTLS := tls.Client(conn, client)
+	err = clientTLS.Handshake()
+	if err != nil {
+		conn.Close()
+	}
+	// With TLS 1.3 the server checks the client certificate after the
+	// client has finished its side of the handshake
+	accepted := <-serverDone
+	if err == nil {
+		err = accepted.err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientTLS.ConnectionState().PeerCertificates[0], accepted.clientCert, nil
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "server", "localhost", 2)
+	clientCert, clientKey := ca.issue(t, "client", "worker", 3)
+
+	server, serverReloader, err := ServerConfig(ServerOptions{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: ca.path("ca.pem")})
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	defer serverReloader.Stop()
+	client, clientReloader, err := ClientConfig(ClientOptions{CAFile: ca.path("ca.pem"), CertFile: clientCert, KeyFile: clientKey, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("ClientConfig: %v", err)
+	}
+	defer clientReloader.Stop()
+
+	seenServer, seenClient, err := handshake(server, client)
+	if err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if seenServer.Subject.CommonName != "localhost" || seenClient == nil || seenClient.Subject.CommonName != "worker" {
+		t.Errorf("handshake saw %v and %v, want localhost and worker", seenServer.Subject, seenClient)
+	}
+
+	// Without a client certificate the server refuses the connection
+	anonymous, anonymousReloader, err := ClientConfig(ClientOptions{CAFile: ca.path("ca.pem"), ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("ClientConfig: %v", err)
+	}
+	defer anonymousReloader.Stop()
+	if _, _, err := handshake(server, anonymous); err == nil {
+		t.Errorf("handshake without a client certificate succeeded")
+	}
+
+	// Unless client certificates are optional
+	optional, optionalReloader, err := ServerConfig(ServerOptions{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: ca.path("ca.pem"), ClientCertOptional: true})
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	defer optionalReloader.Stop()
+	if _, seenClient, err := handshake(optional, anonymous); err != nil || seenClient != nil {
+		t.Errorf("handshake with an optional client certificate = %v, %v, want no client certificate", seenClient, err)
+	}
+
+	// A client trusting another CA refuses the server
+	other := newTestCA(t)
+	untrusting, untrustingReloader, err := ClientConfig(ClientOptions{CAFile: other.path("ca.pem"), CertFile: clientCert, KeyFile: clientKey, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("ClientConfig: %v", err)
+	}
+	defer untrustingReloader.Stop()
+	if _, _, err := handshake(server, untrusting); err == nil {
+		t.Errorf("handshake with a server signed by an untrusted CA succeeded")
+	}
+}
+
+func TestReload(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "server", "localhost", 2)
+	r, err := NewReloader(certFile, keyFile, ca.path("ca.pem"))
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	if changed, err := r.Reload(); err != nil || changed {
+		t.Errorf("Reload of unchanged files = %v, %v, want no change", changed, err)
+	}
+
+	// Issue a new certificate over the old one
+	ca.issue(t, "server", "localhost", 4)
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+	if changed, err := r.Reload(); err != nil || !changed {
+		t.Fatalf("Reload of a new certificate = %v, %v, want a change", changed, err)
+	}
+	leaf, err := x509.ParseCertificate(r.Certificate().Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	if leaf.SerialNumber.Int64() != 4 {
+		t.Errorf("serial = %d after reload, want 4", leaf.SerialNumber.Int64())
+	}
+
+	// A broken file is reported and the previous certificate kept
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	evenLater := later.Add(time.Minute)
+	if err := os.Chtimes(certFile, evenLater, evenLater); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	if _, err := r.Reload(); err == nil {
+		t.Errorf("Reload of a broken certificate succeeded")
+	}
+	if leaf, _ := x509.ParseCertificate(r.Certificate().Certificate[0]); leaf.SerialNumber.Int64() != 4 {
+		t.Errorf("certificate changed after a failed reload")
+	}
+
+	if _, err := NewReloader(certFile, "", ""); err == nil {
+		t.Errorf("NewReloader accepted a certificate without a key")
+	}
+}
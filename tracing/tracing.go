@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters spans can be sent to
+const (
+	EXPORTER_NONE   = ""
+	EXPORTER_OTLP   = "otlp"
+	EXPORTER_STDOUT = "stdout"
+)
+
+// SERVICE_NAME is the service spans are reported for
+const SERVICE_NAME = "ps-tasks"
+
+// TRACER_NAME names the tracer of the task manager and service spans
+const TRACER_NAME = "github.com/indkumar8999/ps-tasks"
+
+// Propagator carries W3C trace context and baggage between producers, the
+// server and workers, and on stored tasks
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Config selects where spans are exported
+type Config struct {
+	// Exporter is EXPORTER_NONE, EXPORTER_OTLP or EXPORTER_STDOUT
+	Exporter string
+	// Endpoint is the host:port of the OTLP gRPC collector; it defaults to
+	// the OTEL_EXPORTER_OTLP_ENDPOINT environment variable or localhost:4317
+	Endpoint string
+	// Insecure sends OTLP without TLS
+	Insecure bool
+	// SampleRatio is the fraction of new traces that are recorded. Traces
+	// started by a sampled caller are always recorded.
+	SampleRatio float64
+	// SpanExporter overrides Exporter, for example with an in-memory
+	// exporter in tests
+	SpanExporter sdktrace.SpanExporter
+	// Attributes are added to every span, such as the node ID
+	Attributes []attribute.KeyValue
+}
+
+// Provider exports the spans of the process
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Setup installs the propagator and, unless no exporter is configured, a
+// tracer provider as the global defaults. Trace context is propagated and
+// stored on tasks even when spans are not exported.
+func Setup(config Config) (*Provider, error) {
+	otel.SetTextMapPropagator(Propagator)
+
+	exporter := config.SpanExporter
+	if exporter == nil {
+		var err error
+		switch config.Exporter {
+		case EXPORTER_NONE:
+			return &Provider{}, nil
+		case EXPORTER_OTLP:
+			opts := []otlptracegrpc.Option{}
+			if config.Endpoint != "" {
+				opts = append(opts, otlptracegrpc.WithEndpoint(config.Endpoint))
+			}
+			if config.Insecure {
+				opts = append(opts, otlptracegrpc.WithInsecure())
+			}
+			exporter, err = otlptracegrpc.New(context.Background(), opts...)
+		case EXPORTER_STDOUT:
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		default:
+			return nil, fmt.Errorf("unknown trace exporter %q", config.Exporter)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s trace exporter: %v", config.Exporter, err)
+		}
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		append([]attribute.KeyValue{attribute.String("service.name", SERVICE_NAME)}, config.Attributes...)...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return &Provider{provider: provider}, nil
+}
+
+// ForceFlush exports every ended span now
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	return p.provider.ForceFlush(ctx)
+}
+
+// Shutdown exports the remaining spans and stops exporting, waiting at most
+// timeout
+func (p *Provider) Shutdown(timeout time.Duration) error {
+	if p.provider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return p.provider.Shutdown(ctx)
+}
+
+// Tracer returns the tracer of the task manager and service spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(TRACER_NAME)
+}
+
+// Inject returns the trace context of ctx in a form that can be stored on a
+// task, or nil if ctx carries no trace
+func Inject(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	Propagator.Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns ctx carrying the trace context stored by Inject, so new
+// spans continue that trace
+func Extract(ctx context.Context, traceContext map[string]string) context.Context {
+	if len(traceContext) == 0 {
+		return ctx
+	}
+	return Propagator.Extract(ctx, propagation.MapCarrier(traceContext))
+}
+
+// Link returns a link to the span stored by Inject, and false if there is
+// none
+func Link(traceContext map[string]string) (trace.Link, bool) {
+	spanContext := trace.SpanContextFromContext(Extract(context.Background(), traceContext))
+	if !spanContext.IsValid() {
+		return trace.Link{}, false
+	}
+	return trace.Link{SpanContext: spanContext}, true
+}